@@ -9,16 +9,12 @@ import (
 )
 
 type Flubber struct {
-	Variant  string  `json:"variant"`
-	Size     int     `json:"size"`
-	Strength float64 `json:"strength"`
+	Variant  string  `json:"variant" vebben:",,required,limit=4,name=The 4-letter variant"`
+	Size     int     `json:"size" vebben:",,required,limit=1-4,name=The size (1-4)"`
+	Strength float64 `json:"strength" vebben:",,name=Flubber strength"`
 }
 
-var FlubberSpecs = []*vebben.FormSpec{
-	vebben.RequiredFormSpec("variant", "string", "4", "The 4-letter variant"),
-	vebben.RequiredFormSpec("size", "int", "1-4", "The size (1-4)"),
-	vebben.OptionalFormSpec("strength", "float", "", "Flubber strength"),
-}
+var FlubberSpecs = vebben.MustFormSpecsFor(Flubber{})
 
 func main() {
 
@@ -26,7 +22,8 @@ func main() {
 
 		if r.Method == "POST" {
 			f := &Flubber{}
-			err := vebben.DecodeForm(r, FlubberSpecs, f)
+			err := vebben.DecodeForm(r, FlubberSpecs, f,
+				vebben.AcceptLanguage(r.Header.Get("Accept-Language")))
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
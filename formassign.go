@@ -0,0 +1,451 @@
+// formassign.go -- assignment of decoded form values to target structs.
+// -------------
+
+package vebben
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// formPlan maps form keys to the fields of a struct type.  Keys are the
+// FormTag key and the json name (or field name), with the latter also
+// matched case-insensitively as with encoding/json; fields of embedded
+// structs are included unless shadowed.
+type formPlan struct {
+	fields map[string][]int // exact key -> field index
+	folded map[string][]int // lowercased key -> field index, as fallback
+}
+
+var formPlanCache sync.Map // reflect.Type -> *formPlan
+
+// formPlanFor returns the (cached) formPlan for struct type t.
+func formPlanFor(t reflect.Type) *formPlan {
+
+	if plan, ok := formPlanCache.Load(t); ok {
+		return plan.(*formPlan)
+	}
+	plan := &formPlan{
+		fields: map[string][]int{},
+		folded: map[string][]int{},
+	}
+	plan.add(t, nil)
+	cached, _ := formPlanCache.LoadOrStore(t, plan)
+	return cached.(*formPlan)
+}
+
+// add adds the fields of t at index path prefix, with embedded structs
+// added after the fields of t itself so that the shallower fields win.
+// Pointers to unexported struct types are skipped, as by encoding/json,
+// since they can not be allocated.
+func (p *formPlan) add(t reflect.Type, prefix []int) {
+
+	embedded := [][]int{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int{}, prefix...), i)
+		tag := field.Tag.Get(FormTag)
+		_, jsonTagged := field.Tag.Lookup("json")
+		if field.Anonymous && tag == "" && !jsonTagged {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				if !field.IsExported() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, index)
+				continue
+			}
+		}
+		key, _, _ := strings.Cut(tag, ",")
+		key = strings.TrimSpace(key)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || tag == "-" || (name == "-" && key == "") {
+			continue
+		}
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		for _, k := range []string{key, name} {
+			if _, have := p.fields[k]; k != "" && !have {
+				p.fields[k] = index
+			}
+		}
+		if _, have := p.folded[strings.ToLower(name)]; !have {
+			p.folded[strings.ToLower(name)] = index
+		}
+	}
+	for _, index := range embedded {
+		ft := t.Field(index[len(index)-1]).Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		p.add(ft, index)
+	}
+}
+
+// lookup returns the field index for key, or nil if there is none.
+func (p *formPlan) lookup(key string) []int {
+	if index, ok := p.fields[key]; ok {
+		return index
+	}
+	return p.folded[strings.ToLower(key)]
+}
+
+// checkFormTarget returns an error unless target is a non-nil pointer to a
+// struct or to a map with string keys.
+func checkFormTarget(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, not %T", target)
+	}
+	switch rv.Elem().Kind() {
+	case reflect.Struct:
+		return nil
+	case reflect.Map:
+		if rv.Elem().Type().Key().Kind() == reflect.String {
+			return nil
+		}
+	}
+	return fmt.Errorf("target must point to a struct or map, not %T", target)
+}
+
+// formKeySeg is a segment of a nested form key: either a name, which may be
+// a struct field or map key, or an index into a slice or array.
+type formKeySeg struct {
+	name  string
+	index int // -1 for names
+}
+
+// parseFormKey splits a form key into segments, e.g. "items[2].qty" into
+// "items", 2 and "qty"; and "meta[color]" into "meta" and "color".  A final
+// "[]" is ignored, so "tags[]" is the same as "tags".
+func parseFormKey(key string) ([]formKeySeg, error) {
+
+	if !strings.ContainsAny(key, ".[]") {
+		return []formKeySeg{{key, -1}}, nil
+	}
+	segs := []formKeySeg{}
+	rest := key
+	for rest != "" {
+		var name string
+		switch {
+		case len(segs) > 0 && rest == "[]":
+			// PHP-style multi-value key, e.g. "tags[]".
+			rest = ""
+			continue
+		case len(segs) > 0 && rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in %q", key)
+			}
+			name, rest = rest[1:end], rest[end+1:]
+			if name == "" || strings.ContainsAny(name, "[.") {
+				return nil, fmt.Errorf("bad brackets in %q", key)
+			}
+			if n, err := strconv.Atoi(name); err == nil && n >= 0 {
+				segs = append(segs, formKeySeg{index: n})
+				continue
+			}
+		default:
+			if len(segs) > 0 {
+				if rest[0] != '.' {
+					return nil, fmt.Errorf("missing dot in %q", key)
+				}
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[]")
+			if end < 0 {
+				end = len(rest)
+			}
+			name, rest = rest[:end], rest[end:]
+			if name == "" {
+				return nil, fmt.Errorf("empty name in %q", key)
+			}
+		}
+		segs = append(segs, formKeySeg{name, -1})
+	}
+	return segs, nil
+}
+
+// assignFormValue assigns val to the field (or map entry) of target
+// identified by key, which may be nested, e.g. "items[2].qty".  Missing
+// struct fields are silently ignored.  The target must have passed
+// checkFormTarget.
+func assignFormValue(target interface{}, key string, val interface{}) error {
+
+	path, err := parseFormKey(key)
+	if err == nil {
+		err = setFormPath(reflect.ValueOf(target).Elem(), path, val)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", key, err.Error())
+	}
+	return nil
+}
+
+var anyMapType = reflect.TypeOf(map[string]interface{}{})
+var anySliceType = reflect.TypeOf([]interface{}{})
+
+// setFormPath sets the value at path within v to val, creating any maps,
+// slice items and pointers needed on the way.
+func setFormPath(v reflect.Value, path []formKeySeg, val interface{}) error {
+
+	if len(path) == 0 {
+		return setFormValue(v, val)
+	}
+	seg := path[0]
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setFormPath(v.Elem(), path, val)
+
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			break
+		}
+		// Work on a copy of the current value if it's useful, or else a
+		// new map or slice.
+		var cp reflect.Value
+		inner := v.Elem()
+		switch {
+		case inner.IsValid() &&
+			(inner.Kind() == reflect.Map || inner.Kind() == reflect.Slice):
+			cp = reflect.New(inner.Type()).Elem()
+			cp.Set(inner)
+		case seg.index >= 0:
+			cp = reflect.New(anySliceType).Elem()
+		default:
+			cp = reflect.MakeMap(anyMapType)
+		}
+		if err := setFormPath(cp, path, val); err != nil {
+			return err
+		}
+		v.Set(cp)
+		return nil
+
+	case reflect.Struct:
+		if seg.index >= 0 {
+			break
+		}
+		index := formPlanFor(v.Type()).lookup(seg.name)
+		if index == nil {
+			return nil
+		}
+		for _, i := range index {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+			v = v.Field(i)
+		}
+		return setFormPath(v, path[1:], val)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		name := seg.name
+		if seg.index >= 0 {
+			name = strconv.Itoa(seg.index)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		mk := reflect.ValueOf(name).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(mk); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := setFormPath(elem, path[1:], val); err != nil {
+			return err
+		}
+		v.SetMapIndex(mk, elem)
+		return nil
+
+	case reflect.Slice:
+		if seg.index < 0 {
+			break
+		}
+		if n := seg.index + 1 - v.Len(); n > 0 {
+			v.Set(reflect.AppendSlice(v, reflect.MakeSlice(v.Type(), n, n)))
+		}
+		return setFormPath(v.Index(seg.index), path[1:], val)
+
+	case reflect.Array:
+		if seg.index < 0 || seg.index >= v.Len() {
+			break
+		}
+		return setFormPath(v.Index(seg.index), path[1:], val)
+	}
+
+	if seg.index >= 0 {
+		return fmt.Errorf("can not index %s with %d", v.Type(), seg.index)
+	}
+	return fmt.Errorf("can not set %q in %s", seg.name, v.Type())
+}
+
+var textUnmarshalerType = reflect.TypeOf(
+	(*encoding.TextUnmarshaler)(nil)).Elem()
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// setFormValue sets dst to val, converting as necessary: numbers, strings
+// and bools convert to other types of the same kind if they fit; Decimals
+// and Money convert to strings, and their amounts to integers as Units and
+// to floats; pointers are allocated; slices are converted item by item;
+// strings are passed to encoding.TextUnmarshaler implementations; and as a
+// last resort, values are passed to json.Unmarshaler implementations as
+// JSON.
+func setFormValue(dst reflect.Value, val interface{}) error {
+
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(val)
+	dt := dst.Type()
+
+	if src.Type().AssignableTo(dt) {
+		dst.Set(src)
+		return nil
+	}
+	if dt.Kind() == reflect.Ptr {
+		ptr := reflect.New(dt.Elem())
+		if err := setFormValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+	s, isString := val.(string)
+	if isString && reflect.PtrTo(dt).Implements(textUnmarshalerType) {
+		ptr := reflect.New(dt)
+		tu := ptr.Interface().(encoding.TextUnmarshaler)
+		if err := tu.UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		dst.Set(ptr.Elem())
+		return nil
+	}
+	if reflect.PtrTo(dt).Implements(jsonUnmarshalerType) {
+		b, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		ptr := reflect.New(dt)
+		ju := ptr.Interface().(json.Unmarshaler)
+		if err := ju.UnmarshalJSON(b); err != nil {
+			return err
+		}
+		dst.Set(ptr.Elem())
+		return nil
+	}
+
+	bad := fmt.Errorf("can not assign %T to %s", val, dt)
+	d, isDecimal := decimalAmount(val)
+	overflow := fmt.Errorf("%v overflows %s", val, dt)
+	switch dk := dt.Kind(); {
+	case isIntKind(dk):
+		var i int64
+		switch {
+		case isIntKind(src.Kind()):
+			i = src.Int()
+		case isUintKind(src.Kind()) && src.Uint() < 1<<63:
+			i = int64(src.Uint())
+		case isUintKind(src.Kind()):
+			return overflow
+		case isDecimal:
+			i = d.Units
+		default:
+			return bad
+		}
+		if dst.OverflowInt(i) {
+			return overflow
+		}
+		dst.SetInt(i)
+	case isUintKind(dk):
+		var u uint64
+		switch {
+		case isUintKind(src.Kind()):
+			u = src.Uint()
+		case isIntKind(src.Kind()) && src.Int() >= 0:
+			u = uint64(src.Int())
+		case isIntKind(src.Kind()):
+			return overflow
+		default:
+			return bad
+		}
+		if dst.OverflowUint(u) {
+			return overflow
+		}
+		dst.SetUint(u)
+	case dk == reflect.Float32 || dk == reflect.Float64:
+		var f float64
+		switch src.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = src.Float()
+		default:
+			if !isDecimal {
+				return bad
+			}
+			f = d.Float64()
+		}
+		if dst.OverflowFloat(f) {
+			return overflow
+		}
+		dst.SetFloat(f)
+	case dk == reflect.String:
+		if src.Kind() != reflect.String {
+			s, ok := netValueString(val)
+			if isDecimal {
+				s, ok = fmt.Sprint(val), true
+			}
+			if !ok {
+				return bad
+			}
+			dst.SetString(s)
+			return nil
+		}
+		dst.SetString(src.String())
+	case dk == reflect.Bool:
+		if src.Kind() != reflect.Bool {
+			return bad
+		}
+		dst.SetBool(src.Bool())
+	case dk == reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			return bad
+		}
+		res := reflect.MakeSlice(dt, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			item := src.Index(i).Interface()
+			if err := setFormValue(res.Index(i), item); err != nil {
+				return err
+			}
+		}
+		dst.Set(res)
+	default:
+		return bad
+	}
+	return nil
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
@@ -0,0 +1,236 @@
+// formassign_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+type AssignLevel string
+
+type AssignCode struct {
+	Code string
+}
+
+func (c *AssignCode) UnmarshalText(b []byte) error {
+	if len(b) == 0 || b[0] != 'C' {
+		return errors.New("not a code")
+	}
+	c.Code = strings.ToLower(string(b))
+	return nil
+}
+
+type AssignPoint struct {
+	X, Y int
+}
+
+type AssignEmbedded struct {
+	Note  string
+	Shade string `json:"shade"`
+}
+
+type AssignType struct {
+	*AssignEmbedded
+	Small   int32       `json:"small"`
+	Level   AssignLevel `json:"level"`
+	Count   *int        `json:"count"`
+	When    *time.Time  `json:"when"`
+	Sizes   []int64     `json:"sizes"`
+	Code    AssignCode  `json:"code"`
+	Point   AssignPoint `json:"point"`
+	Renamed string      `json:"renamed" vebben:"other"`
+	Shade   string      `json:"shade"`
+	Hidden  string      `json:"-"`
+}
+
+func init() {
+	vebben.AddFormSpecType("assignpoint",
+		func(s string) (interface{}, bool) {
+			var p AssignPoint
+			if _, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y); err != nil {
+				return nil, false
+			}
+			return p, true
+		}, nil)
+}
+
+func Test_DecodeForm_Assignment(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"small":  []string{"123"},
+		"level":  []string{"high"},
+		"count":  []string{"7"},
+		"when":   []string{"2017.02.25"},
+		"sizes":  []string{"1", "2"},
+		"code":   []string{"CODE"},
+		"point":  []string{"3,4"},
+		"other":  []string{"by tag"},
+		"note":   []string{"embedded"},
+		"shade":  []string{"shallow"},
+		"Hidden": []string{"nope"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("small", "int"),
+		vebben.OptionalFormSpec("level", "string"),
+		vebben.OptionalFormSpec("count", "int"),
+		vebben.OptionalFormSpec("when", "date"),
+		vebben.OptionalFormSpec("sizes", "[]int"),
+		vebben.OptionalFormSpec("code", "string"),
+		vebben.OptionalFormSpec("point", "assignpoint"),
+		vebben.OptionalFormSpec("other", "string"),
+		vebben.OptionalFormSpec("note", "string"),
+		vebben.OptionalFormSpec("shade", "string"),
+		vebben.OptionalFormSpec("Hidden", "string"),
+	}
+
+	target := &AssignType{}
+	err := vebben.DecodeForm(f, specs, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(int32(123), target.Small)
+	assert.Equal(AssignLevel("high"), target.Level)
+	if assert.NotNil(target.Count) {
+		assert.Equal(7, *target.Count)
+	}
+	if assert.NotNil(target.When) {
+		assert.Equal("2017-02-25", target.When.Format("2006-01-02"))
+	}
+	assert.Equal([]int64{1, 2}, target.Sizes)
+	assert.Equal("code", target.Code.Code)
+	assert.Equal(AssignPoint{3, 4}, target.Point)
+	assert.Equal("by tag", target.Renamed)
+	if assert.NotNil(target.AssignEmbedded) {
+		assert.Equal("embedded", target.Note)
+		assert.Equal("", target.AssignEmbedded.Shade)
+	}
+	assert.Equal("shallow", target.Shade)
+	assert.Equal("", target.Hidden)
+
+}
+
+func Test_DecodeForm_AssignmentErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"small": []string{"2147483647"},
+		"level": []string{"1"},
+		"code":  []string{"nocode"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("small", "int64"),
+		vebben.OptionalFormSpec("level", "int"),
+		vebben.OptionalFormSpec("code", "string"),
+	}
+
+	type Target struct {
+		Small int16      `json:"small"`
+		Level string     `json:"level"`
+		Code  AssignCode `json:"code"`
+	}
+	err := vebben.DecodeForm(f, specs, &Target{})
+	if assert.Error(err) {
+		assert.IsType(&vebben.MultiError{}, err)
+		assert.Equal("small: 2147483647 overflows int16\n"+
+			"level: can not assign int to string\n"+
+			"code: not a code", err.Error())
+	}
+
+}
+
+type assignHidden struct {
+	X int `json:"x"`
+}
+
+func Test_DecodeForm_UnexportedEmbedded(t *testing.T) {
+
+	assert := assert.New(t)
+
+	type PointerOuter struct {
+		*assignHidden
+		Y int `json:"y"`
+	}
+	type ValueOuter struct {
+		assignHidden
+		Y int `json:"y"`
+	}
+	f := vebben.URLValues{"x": {"3"}, "y": {"4"}}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("x", "int"),
+		vebben.OptionalFormSpec("y", "int"),
+	}
+
+	// Pointers to unexported structs are skipped, as by encoding/json.
+	ptr := &PointerOuter{}
+	if assert.Nil(vebben.DecodeForm(f, specs, ptr)) {
+		assert.Nil(ptr.assignHidden)
+		assert.Equal(4, ptr.Y)
+	}
+
+	val := &ValueOuter{}
+	if assert.Nil(vebben.DecodeForm(f, specs, val)) {
+		assert.Equal(3, val.X)
+		assert.Equal(4, val.Y)
+	}
+}
+
+func Test_DecodeForm_MapTarget(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"foo": []string{"bar"},
+		"num": []string{"12"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("foo", "string"),
+		vebben.OptionalFormSpec("num", "int"),
+	}
+
+	var target map[string]interface{}
+	err := vebben.DecodeForm(f, specs, &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(map[string]interface{}{"foo": "bar", "num": 12}, target)
+
+}
+
+func Test_DecodeForm_BadTarget(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{}
+	specs := []*vebben.FormSpec{}
+
+	err := vebben.DecodeForm(f, specs, SimpleType{})
+	if assert.Error(err) {
+		assert.Equal("target must be a non-nil pointer, not "+
+			"vebben_test.SimpleType", err.Error())
+	}
+	var nilTarget *SimpleType
+	err = vebben.DecodeForm(f, specs, nilTarget)
+	assert.Error(err)
+	n := 1
+	err = vebben.DecodeForm(f, specs, &n)
+	if assert.Error(err) {
+		assert.Equal("target must point to a struct or map, not *int",
+			err.Error())
+	}
+
+}
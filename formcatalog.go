@@ -0,0 +1,230 @@
+// formcatalog.go -- localizable validation messages.
+// --------------
+
+package vebben
+
+import (
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// Catalog holds validation messages by language and error code, for use in
+// formatting FieldErrors.  Messages may contain placeholders in braces,
+// which are replaced with the Name, Key or Input of the FieldError for
+// {name}, {key} and {input}, or else with the corresponding entry in its
+// Params, e.g. {min} and {max} for CodeTooLow and CodeTooHigh.
+//
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[language.Tag]map[string]string
+	tags     []language.Tag
+	matched  []language.Tag // tags in matcher order
+	matcher  language.Matcher
+}
+
+// DefaultCatalog is used unless a different Catalog is given to DecodeForm.
+// It contains English and Hungarian messages for the standard error codes;
+// messages for custom codes may be added with Set.
+var DefaultCatalog = newDefaultCatalog()
+
+// NewCatalog returns an empty Catalog, to which at least English messages
+// should be added, as English is the final fallback language.
+func NewCatalog() *Catalog {
+	return &Catalog{messages: map[language.Tag]map[string]string{}}
+}
+
+// Set sets the message for code in language tag.
+func (c *Catalog) Set(tag language.Tag, code, msg string) {
+	c.SetMessages(tag, map[string]string{code: msg})
+}
+
+// SetMessages sets the messages for language tag from a map of code to
+// message.
+func (c *Catalog) SetMessages(tag language.Tag, msgs map[string]string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.messages[tag]
+	if !ok {
+		m = map[string]string{}
+		c.messages[tag] = m
+		c.tags = append(c.tags, tag)
+		c.matcher = nil
+	}
+	for code, msg := range msgs {
+		m[code] = msg
+	}
+}
+
+// Languages returns the languages for which the Catalog has messages, in
+// the order they were first added.
+func (c *Catalog) Languages() []language.Tag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]language.Tag{}, c.tags...)
+}
+
+// messagesFor returns all the messages for language tag, by code, with the
+// same fallbacks as Message.
+func (c *Catalog) messagesFor(tag language.Tag) map[string]string {
+
+	c.mu.RLock()
+	codes := map[string]bool{}
+	for _, m := range c.messages {
+		for code := range m {
+			codes[code] = true
+		}
+	}
+	c.mu.RUnlock()
+
+	res := map[string]string{}
+	for code := range codes {
+		if msg, ok := c.Message(tag, code); ok {
+			res[code] = msg
+		}
+	}
+	return res
+}
+
+// Match returns the Catalog language best matching an Accept-Language
+// header value, or English if there is no match.
+func (c *Catalog) Match(acceptLanguage string) language.Tag {
+
+	wanted, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(wanted) == 0 {
+		return language.English
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.tags) == 0 {
+		return language.English
+	}
+	if c.matcher == nil {
+		// English is the default, so it must come first.
+		c.matched = []language.Tag{language.English}
+		for _, tag := range c.tags {
+			if tag != language.English {
+				c.matched = append(c.matched, tag)
+			}
+		}
+		c.matcher = language.NewMatcher(c.matched)
+	}
+	_, idx, conf := c.matcher.Match(wanted...)
+	if conf == language.No {
+		return language.English
+	}
+	return c.matched[idx]
+}
+
+// Message returns the message for code in language tag, falling back to
+// its parent languages and then to English.  If there is no message in
+// any of those, returns false.
+func (c *Catalog) Message(tag language.Tag, code string) (string, bool) {
+	if msg, ok := c.lookup(tag, code); ok {
+		return msg, true
+	}
+	return c.lookup(language.English, code)
+}
+
+// lookup returns the message for code in language tag or its parent
+// languages, without falling back to English.
+func (c *Catalog) lookup(tag language.Tag, code string) (string, bool) {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for {
+		if msg, ok := c.messages[tag][code]; ok {
+			return msg, true
+		}
+		if tag == language.Und {
+			return "", false
+		}
+		tag = tag.Parent()
+	}
+}
+
+// Format returns the message for fe in language tag, with its placeholders
+// replaced.  If the Catalog has no message for the Code, the message of the
+// underlying error is used if there is one, or else a generic message with
+// the Name and Code.
+func (c *Catalog) Format(tag language.Tag, fe *FieldError) string {
+	msg, ok := c.Message(tag, fe.Code)
+	if fe.Err != nil && (!ok || fe.Code == CodeInvalid) {
+		return fe.Err.Error()
+	}
+	if !ok {
+		return fe.Name + ": " + fe.Code
+	}
+	return fe.expand(msg)
+}
+
+func newDefaultCatalog() *Catalog {
+
+	c := NewCatalog()
+	c.SetMessages(language.English, map[string]string{
+		CodeRequired:    "{name} is required",
+		CodeConversion:  "{name} could not be converted to {type}",
+		CodeWrongType:   "{name} ({got}) is not a {type}",
+		CodeWrongLength: "{name} has the wrong length",
+		CodeTooShort:    "{name} is too short",
+		CodeTooLong:     "{name} is too long",
+		CodeTooLow:      "{name} is too low",
+		CodeTooHigh:     "{name} is too high",
+		CodeWrongStep:   "{name} must be in steps of {step}",
+		CodeTooPrecise:  "{name} may have at most {scale} decimal places",
+		CodeBadFormat:   "{name} has the wrong format",
+		CodeNotInList:   "{name} has the wrong value",
+		CodeNotAllowed:  "{name} has a value that is not allowed",
+		CodeTooFew:      "{name} has too few items",
+		CodeTooMany:     "{name} has too many items",
+		CodeInvalid:     "{name} is invalid",
+		CodeTooLarge:    "{name} is too large",
+		CodeForbidden:   "{name} must be empty",
+
+		CodeBadContentType: "{name} has an unsupported file type",
+		CodeBadFilename:    "{name} has an invalid file name",
+		CodeBadScheme:      "{name} has a URL scheme that is not allowed",
+		CodeBadCurrency:    "{name} has a currency that is not allowed",
+		CodeBadPrecision:   "{name} may only be given to the {precision}",
+
+		CodeMismatch:    "{name} does not match {other}",
+		CodeNotAfter:    "{name} must be after {other}",
+		CodeOneRequired: "At least one of {names} is required",
+	})
+	c.SetMessages(language.Hungarian, map[string]string{
+		CodeRequired:    "{name} megadása kötelező",
+		CodeConversion:  "{name} formátuma érvénytelen",
+		CodeWrongType:   "{name} típusa hibás ({got}, nem {type})",
+		CodeWrongLength: "{name} hossza nem megfelelő",
+		CodeTooShort:    "{name} túl rövid",
+		CodeTooLong:     "{name} túl hosszú",
+		CodeTooLow:      "{name} túl kicsi",
+		CodeTooHigh:     "{name} túl nagy",
+		CodeWrongStep:   "{name} csak {step} lépésközzel adható meg",
+		CodeTooPrecise:  "{name} legfeljebb {scale} tizedesjegyet tartalmazhat",
+		CodeBadFormat:   "{name} formátuma nem megfelelő",
+		CodeNotInList:   "{name} értéke nem megengedett",
+		CodeNotAllowed:  "{name} ezt az értéket nem veheti fel",
+		CodeTooFew:      "{name}: túl kevés elem",
+		CodeTooMany:     "{name}: túl sok elem",
+		CodeInvalid:     "{name} érvénytelen",
+		CodeTooLarge:    "{name} mérete túl nagy",
+		CodeForbidden:   "{name} nem adható meg",
+
+		CodeBadContentType: "{name} fájltípusa nem támogatott",
+		CodeBadFilename:    "{name} fájlneve érvénytelen",
+		CodeBadScheme:      "{name} URL-sémája nem megengedett",
+		CodeBadCurrency:    "{name} pénzneme nem megengedett",
+		CodeBadPrecision:   "{name} a megengedettnél pontosabb időpont",
+
+		CodeMismatch:    "{name} nem egyezik: {other}",
+		CodeNotAfter:    "{name} nem későbbi, mint {other}",
+		CodeOneRequired: "Legalább egy megadása kötelező: {names}",
+	})
+	return c
+}
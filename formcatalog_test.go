@@ -0,0 +1,142 @@
+// formcatalog_test.go
+// -------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"errors"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_Catalog_Match(t *testing.T) {
+
+	assert := assert.New(t)
+
+	c := vebben.DefaultCatalog
+	assert.Equal(language.Hungarian, c.Match("hu-HU,hu;q=0.9,en;q=0.8"))
+	assert.Equal(language.English, c.Match("de-DE,en;q=0.5"))
+	assert.Equal(language.English, c.Match("de-DE"), "no match")
+	assert.Equal(language.English, c.Match(""), "empty")
+	assert.Equal(language.English, c.Match("!!"), "garbage")
+	assert.Equal(language.English, vebben.NewCatalog().Match("hu"),
+		"empty catalog")
+
+}
+
+func Test_Catalog_Message(t *testing.T) {
+
+	assert := assert.New(t)
+
+	c := vebben.NewCatalog()
+	c.Set(language.English, "foo", "{name} is foo")
+	c.SetMessages(language.Hungarian, map[string]string{
+		"foo": "{name} foo",
+		"bar": "{name} bar",
+	})
+	assert.Equal([]language.Tag{language.English, language.Hungarian},
+		c.Languages())
+
+	msg, ok := c.Message(language.MustParse("hu-HU"), "foo")
+	assert.True(ok)
+	assert.Equal("{name} foo", msg, "parent language")
+	msg, ok = c.Message(language.German, "foo")
+	assert.True(ok)
+	assert.Equal("{name} is foo", msg, "English fallback")
+	_, ok = c.Message(language.German, "bar")
+	assert.False(ok, "no fallback for bar")
+
+}
+
+func Test_Catalog_Format(t *testing.T) {
+
+	assert := assert.New(t)
+
+	c := vebben.NewCatalog()
+	c.Set(language.English, "range", "{name} must be {min}-{max}, not {input}")
+	fe := &vebben.FieldError{
+		Key:    "foo",
+		Name:   "Foo",
+		Code:   "range",
+		Params: map[string]interface{}{"min": 1, "max": 4},
+		Input:  "5",
+	}
+	assert.Equal("Foo must be 1-4, not 5", c.Format(language.English, fe))
+
+	fe.Code = "nope"
+	assert.Equal("Foo: nope", c.Format(language.English, fe))
+	fe.Err = errors.New("inner")
+	assert.Equal("inner", c.Format(language.English, fe))
+
+	assert.Equal("Foo is too low", (&vebben.FieldError{
+		Name: "Foo",
+		Code: vebben.CodeTooLow,
+	}).Localize(language.English), "DefaultCatalog")
+	assert.Equal("Foo túl kicsi", (&vebben.FieldError{
+		Name: "Foo",
+		Code: vebben.CodeTooLow,
+	}).Localize(language.Hungarian), "DefaultCatalog")
+
+}
+
+func Test_DecodeForm_Language(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{"bar": []string{"9"}}
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("foo", "string", "", "A név"),
+		vebben.RequiredFormSpec("bar", "int", "1-4", "A méret"),
+	}
+
+	err := vebben.DecodeForm(f, specs, &SimpleType{},
+		vebben.Language(language.Hungarian))
+	if assert.Error(err) {
+		assert.Equal("A név megadása kötelező\nA méret túl nagy", err.Error())
+	}
+
+	err = vebben.DecodeForm(f, specs, &SimpleType{},
+		vebben.AcceptLanguage("hu;q=0.9, en;q=0.1"))
+	if assert.Error(err) {
+		assert.Equal("A név megadása kötelező\nA méret túl nagy", err.Error())
+	}
+
+	err = vebben.DecodeForm(f, specs, &SimpleType{},
+		vebben.AcceptLanguage("hu"), vebben.Language(language.English))
+	if assert.Error(err) {
+		assert.Equal("A név is required\nA méret is too high", err.Error())
+	}
+
+}
+
+func Test_DecodeForm_CustomCatalog(t *testing.T) {
+
+	assert := assert.New(t)
+
+	c := vebben.NewCatalog()
+	c.Set(language.English, "odd", "{name} is odd")
+	c.Set(language.Hungarian, "odd", "{name} páratlan")
+
+	spec := vebben.RequiredFormSpec("num", "int", "", "Number")
+	spec.Validator = func(fs *vebben.FormSpec, v interface{}) error {
+		if v.(int)%2 != 0 {
+			return fs.FieldError("odd", nil)
+		}
+		return nil
+	}
+	f := vebben.URLValues{"num": []string{"3"}}
+
+	err := vebben.DecodeForm(f, []*vebben.FormSpec{spec}, &SimpleType{},
+		vebben.WithCatalog(c), vebben.AcceptLanguage("hu"))
+	if assert.Error(err) {
+		assert.Equal("Number páratlan", err.Error())
+	}
+
+}
@@ -0,0 +1,131 @@
+// formconditions.go -- conditionally required and forbidden values.
+// -----------------
+
+package vebben
+
+import (
+	"strings"
+)
+
+// Condition describes a test of another form value, for use in the
+// RequiredIf and ForbiddenIf lists of a FormSpec.  It holds if the
+// whitespace-trimmed input for Key is one of the Values, or if Values is
+// empty, if there is any nonempty input for Key.  For multi-value inputs it
+// holds if any of the values matches.  If Not is set, the result is
+// reversed.
+//
+// The Key is relative to the group of the FormSpec, so in a repeated group
+// it refers to a value in the same row.
+type Condition struct {
+	Key    string
+	Values []string
+	Not    bool
+}
+
+// When returns a Condition that holds if the input for key is one of
+// values, or nonempty if there are none.
+func When(key string, values ...string) Condition {
+	return Condition{Key: key, Values: values}
+}
+
+// Unless returns a Condition that holds unless the input for key is one of
+// values, or nonempty if there are none.
+func Unless(key string, values ...string) Condition {
+	return Condition{Key: key, Values: values, Not: true}
+}
+
+// String returns the Condition in the syntax of the requiredif and
+// forbiddenif tag options, e.g. "status:cancelled|void" or "!phone".
+func (c Condition) String() string {
+	s := c.Key
+	if len(c.Values) > 0 {
+		s += ":" + strings.Join(c.Values, "|")
+	}
+	if c.Not {
+		s = "!" + s
+	}
+	return s
+}
+
+// parseCondition parses a Condition from the syntax returned by String.
+func parseCondition(s string) (Condition, bool) {
+	s = strings.TrimSpace(s)
+	c := Condition{}
+	if strings.HasPrefix(s, "!") {
+		c.Not = true
+		s = s[1:]
+	}
+	key, vals, hasVals := strings.Cut(s, ":")
+	c.Key = strings.TrimSpace(key)
+	if hasVals {
+		c.Values = strings.Split(vals, "|")
+	}
+	return c, c.Key != ""
+}
+
+// initConditions checks the RequiredIf and ForbiddenIf conditions, with
+// the same panics as Init.
+func (fs *FormSpec) initConditions() {
+	if len(fs.RequiredIf) == 0 && len(fs.ForbiddenIf) == 0 {
+		return
+	}
+	if fs.isGroup() {
+		panic("Conditions do not apply to " + fs.Type)
+	}
+	for _, c := range append(fs.RequiredIf, fs.ForbiddenIf...) {
+		if _, err := parseFormKey(c.Key); err != nil || c.Key == fs.Key {
+			panic("Bad Condition key: " + c.Key)
+		}
+	}
+}
+
+// conditionsHold returns true if all the conditions hold for the form, with
+// keys prefixed by keyPrefix; or false if there are none.
+func (d *formDecoding) conditionsHold(conds []Condition,
+	keyPrefix string) bool {
+
+	if len(conds) == 0 {
+		return false
+	}
+	for _, c := range conds {
+		if d.conditionHolds(c, keyPrefix) == c.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionHolds returns true if c holds, ignoring its Not flag.
+func (d *formDecoding) conditionHolds(c Condition, keyPrefix string) bool {
+	key := keyPrefix + c.Key
+	if len(c.Values) == 0 {
+		return d.hasValues(key)
+	}
+	for _, v := range formValues(d.f, key) {
+		v = strings.TrimSpace(v)
+		for _, want := range c.Values {
+			if v == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conditionalSpec returns spec as it applies to the form, with keys
+// prefixed by keyPrefix: a copy of it that is Required if its RequiredIf
+// conditions hold, or else spec itself.  If its ForbiddenIf conditions
+// hold and there is input for key, returns false.
+func (d *formDecoding) conditionalSpec(spec *FormSpec, key,
+	keyPrefix string) (*FormSpec, bool) {
+
+	if d.conditionsHold(spec.ForbiddenIf, keyPrefix) && d.hasValues(key) {
+		return spec, false
+	}
+	if !spec.Required && d.conditionsHold(spec.RequiredIf, keyPrefix) {
+		required := *spec
+		required.Required = true
+		return &required, true
+	}
+	return spec, true
+}
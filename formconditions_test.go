@@ -0,0 +1,159 @@
+// formconditions_test.go
+// ----------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+type Booking struct {
+	InvoiceType string   `json:"invoice_type" vebben:",,required,limit=person,company"`
+	CompanyName string   `json:"company_name" vebben:",,requiredif=invoice_type:company,forbiddenif=invoice_type:person,name=Company"`
+	Status      string   `json:"status" vebben:""`
+	Reason      string   `json:"reason" vebben:",,requiredif=status:cancelled|void,name=Reason"`
+	Phone       string   `json:"phone" vebben:",,requiredif=!email,name=Phone"`
+	Email       string   `json:"email" vebben:""`
+	Extras      []string `json:"extras" vebben:",,requiredif=status:deluxe,forbiddenif=!status"`
+}
+
+func Test_Condition_String(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal("status:cancelled|void",
+		vebben.When("status", "cancelled", "void").String())
+	assert.Equal("!phone", vebben.Unless("phone").String())
+	assert.Equal("!a:b", vebben.Unless("a", "b").String())
+
+}
+
+func Test_FormSpec_Init_ConditionPanics(t *testing.T) {
+
+	spec := vebben.OptionalFormSpec("foo", "string")
+	spec.RequiredIf = []vebben.Condition{vebben.When("foo")}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Bad Condition key: foo", "self")
+
+	spec.RequiredIf = nil
+	spec.ForbiddenIf = []vebben.Condition{vebben.When("x[")}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Bad Condition key: x[", "bad key")
+
+	spec = vebben.GroupFormSpec("foo", []*vebben.FormSpec{
+		vebben.OptionalFormSpec("bar", "string"),
+	})
+	spec.RequiredIf = []vebben.Condition{vebben.When("x")}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Conditions do not apply to group", "group")
+
+}
+
+func Test_FormSpecsFor_ConditionErrors(t *testing.T) {
+
+	type Bad struct {
+		Foo string `vebben:"foo,string,requiredif=:x"`
+	}
+	_, err := vebben.FormSpecsFor(Bad{})
+	if assert.Error(t, err) {
+		assert.Equal(t, `bad vebben tag on Bad.Foo: bad condition for `+
+			`requiredif: :x (tag: "foo,string,requiredif=:x")`, err.Error())
+	}
+
+}
+
+func Test_DecodeForm_Conditions(t *testing.T) {
+
+	specs := vebben.MustFormSpecsFor(Booking{})
+	for _, tc := range []struct {
+		form   vebben.URLValues
+		errors string
+	}{
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"}},
+			"",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"company"}, "email": {"x"}},
+			"Company is required",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"company_name": {"ACME"}},
+			"Company must be empty",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"company_name": {" "}},
+			"",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"status": {" void "}},
+			"Reason is required",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"status": {"confirmed"}},
+			"",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}},
+			"Phone is required",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"status": {"deluxe"}},
+			"extras is required",
+		},
+		{
+			vebben.URLValues{"invoice_type": {"person"}, "email": {"x"},
+				"extras": {"spa"}},
+			"extras must be empty",
+		},
+	} {
+		err := vebben.DecodeForm(tc.form, specs, &Booking{})
+		if tc.errors == "" {
+			assert.Nil(t, err, "no error for %v", tc.form)
+		} else if assert.Error(t, err, "error for %v", tc.form) {
+			assert.Equal(t, tc.errors, err.Error(), "errors for %v", tc.form)
+		}
+	}
+
+}
+
+func Test_DecodeForm_Conditions_Groups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	qty := vebben.OptionalFormSpec("qty", "int", "", "Quantity")
+	qty.RequiredIf = []vebben.Condition{vebben.When("sku")}
+	specs := []*vebben.FormSpec{
+		vebben.RepeatedFormSpec("items", []*vebben.FormSpec{
+			vebben.OptionalFormSpec("sku", "string"),
+			qty,
+		}),
+	}
+	f := vebben.URLValues{
+		"items[0].sku": {"A"},
+		"items[0].qty": {"1"},
+		"items[1].sku": {"B"},
+		"items[2].qty": {"3"},
+	}
+	err := vebben.DecodeForm(f, specs, &map[string]interface{}{})
+	if assert.Error(err) {
+		assert.Equal([]string{"items[1].qty"},
+			err.(*vebben.MultiError).Keys())
+		assert.Equal("Quantity is required", err.Error())
+	}
+	assert.False(qty.Required, "spec unchanged")
+
+}
@@ -0,0 +1,309 @@
+// formcsrf.go -- protection against cross-site request forgery.
+// -----------
+
+package vebben
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// CSRFFieldName is the name of the hidden form field holding the CSRF token.
+var CSRFFieldName = "csrf_token"
+
+// CSRFHeader is the request header that may hold the CSRF token instead of
+// the form field, e.g. for requests made by JavaScript.
+var CSRFHeader = "X-CSRF-Token"
+
+// CSRF issues and checks tokens protecting form submissions against
+// cross-site request forgery.  Tokens are signed with the Key and bound to
+// a random secret kept in a cookie (the "double-submit cookie" pattern),
+// or, if SessionID is set, to the session ID it returns for the request.
+// They expire after MaxAge, and are different each time they are issued.
+//
+// The usual setup wraps the handlers with Protect, renders the token in
+// each form with the csrffield template function, and checks it with the
+// WithCSRF option to DecodeForm or by letting Protect reject bad requests:
+//
+//   csrf := vebben.NewCSRF(key)
+//   http.Handle("/order", csrf.Protect(orderHandler))
+//
+//   {{ csrffield .Request }}
+//
+//   err := vebben.DecodeForm(r, specs, &order, vebben.WithCSRF(csrf, r))
+//
+// Create a CSRF with NewCSRF, and change its fields before use if needed.
+type CSRF struct {
+	Key        []byte        // HMAC key, at least 32 bytes
+	CookieName string        // name of the secret cookie
+	CookiePath string        // path of the secret cookie
+	Secure     bool          // set the Secure flag of the cookie
+	MaxAge     time.Duration // lifetime of tokens
+
+	// SessionID, if set, returns the ID of the session for the request;
+	// tokens are then bound to the session rather than to a cookie.
+	SessionID func(*http.Request) string
+
+	// FailureHandler is called by Protect for rejected requests, which
+	// are otherwise answered with 403 Forbidden.  The CSRFError is
+	// available from CSRFFailure.
+	FailureHandler http.Handler
+}
+
+// NewCSRF returns a CSRF using key, with a cookie named "csrf" for the
+// path "/" and set Secure, and a MaxAge of 12 hours.  Keys shorter than 32
+// bytes result in a panic.
+func NewCSRF(key []byte) *CSRF {
+	if len(key) < 32 {
+		panic("CSRF key must be at least 32 bytes")
+	}
+	return &CSRF{
+		Key:        key,
+		CookieName: "csrf",
+		CookiePath: "/",
+		Secure:     true,
+		MaxAge:     12 * time.Hour,
+	}
+}
+
+// CSRFError is the error returned when a CSRF check fails.  Its Reason is
+// suitable for logging, but should not be shown to users.
+type CSRFError struct {
+	Reason string
+}
+
+// Error returns the Reason with a prefix.
+func (e *CSRFError) Error() string {
+	return "CSRF check failed: " + e.Reason
+}
+
+const (
+	csrfSecretLen = 32
+	csrfNonceLen  = 8
+	csrfTimeLen   = 8
+)
+
+type csrfContextKey int
+
+const (
+	csrfStateKey csrfContextKey = iota
+	csrfErrorKey
+)
+
+// csrfState is stored in the request context by Protect.
+type csrfState struct {
+	csrf   *CSRF
+	secret string
+}
+
+// csrfSafeMethods are not checked by Protect.
+var csrfSafeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Protect returns a handler that makes sure the request has a secret
+// cookie, setting a new one if needed, and rejects requests other than
+// GET, HEAD, OPTIONS and TRACE that fail the Check with a token from the
+// CSRFHeader or the CSRFFieldName form value.  Requests passed on to next
+// can be used with Token and CSRFField.
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := &csrfState{csrf: c}
+		if c.SessionID == nil {
+			state.secret = c.cookieSecret(r)
+			if state.secret == "" {
+				state.secret = newCSRFSecret()
+				http.SetCookie(w, &http.Cookie{
+					Name:     c.CookieName,
+					Value:    state.secret,
+					Path:     c.CookiePath,
+					Secure:   c.Secure,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), csrfStateKey, state))
+
+		if !csrfSafeMethods[r.Method] {
+			if err := c.Check(r, requestCSRFToken(r)); err != nil {
+				ctx := context.WithValue(r.Context(), csrfErrorKey, err)
+				r = r.WithContext(ctx)
+				if c.FailureHandler != nil {
+					c.FailureHandler.ServeHTTP(w, r)
+				} else {
+					http.Error(w, http.StatusText(http.StatusForbidden),
+						http.StatusForbidden)
+				}
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRFFailure returns the error for a request rejected by Protect, for use
+// in a FailureHandler; or nil if there is none.
+func CSRFFailure(r *http.Request) error {
+	err, _ := r.Context().Value(csrfErrorKey).(error)
+	return err
+}
+
+// Token returns a new token for the request, or an empty string if there
+// is nothing to bind it to: no session, or no secret cookie and the request
+// was not handled by Protect.
+func (c *CSRF) Token(r *http.Request) string {
+	binding := c.binding(r)
+	if binding == "" {
+		return ""
+	}
+	b := make([]byte, csrfNonceLen+csrfTimeLen, csrfNonceLen+csrfTimeLen+sha256.Size)
+	if _, err := rand.Read(b[:csrfNonceLen]); err != nil {
+		panic("Error reading random bytes: " + err.Error())
+	}
+	binary.BigEndian.PutUint64(b[csrfNonceLen:], uint64(time.Now().UnixNano()))
+	b = append(b, c.sign(b, binding)...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Check returns a CSRFError unless token was issued by Token for the same
+// session or secret cookie and has not expired.
+func (c *CSRF) Check(r *http.Request, token string) error {
+	if token == "" {
+		return &CSRFError{"missing token"}
+	}
+	binding := c.binding(r)
+	if binding == "" {
+		if c.SessionID != nil {
+			return &CSRFError{"no session"}
+		}
+		return &CSRFError{"missing cookie"}
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != csrfNonceLen+csrfTimeLen+sha256.Size {
+		return &CSRFError{"malformed token"}
+	}
+	data, mac := b[:csrfNonceLen+csrfTimeLen], b[csrfNonceLen+csrfTimeLen:]
+	if !hmac.Equal(mac, c.sign(data, binding)) {
+		return &CSRFError{"invalid token"}
+	}
+	issued := time.Unix(0, int64(binary.BigEndian.Uint64(data[csrfNonceLen:])))
+	if time.Since(issued) > c.MaxAge {
+		return &CSRFError{"expired token"}
+	}
+	return nil
+}
+
+// sign returns the HMAC of data and binding.
+func (c *CSRF) sign(data []byte, binding string) []byte {
+	h := hmac.New(sha256.New, c.Key)
+	h.Write(data)
+	h.Write([]byte(binding))
+	return h.Sum(nil)
+}
+
+// binding returns the session ID or cookie secret tokens are bound to, or
+// an empty string if there is none.
+func (c *CSRF) binding(r *http.Request) string {
+	if c.SessionID != nil {
+		if id := c.SessionID(r); id != "" {
+			return "session:" + id
+		}
+		return ""
+	}
+	if state, ok := r.Context().Value(csrfStateKey).(*csrfState); ok &&
+		state.csrf == c && state.secret != "" {
+		return "cookie:" + state.secret
+	}
+	if secret := c.cookieSecret(r); secret != "" {
+		return "cookie:" + secret
+	}
+	return ""
+}
+
+// cookieSecret returns the secret from the request cookie, or an empty
+// string if it is missing or malformed.
+func (c *CSRF) cookieSecret(r *http.Request) string {
+	cookie, err := r.Cookie(c.CookieName)
+	if err != nil {
+		return ""
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(b) != csrfSecretLen {
+		return ""
+	}
+	return cookie.Value
+}
+
+func newCSRFSecret() string {
+	b := make([]byte, csrfSecretLen)
+	if _, err := rand.Read(b); err != nil {
+		panic("Error reading random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// requestCSRFToken returns the token from the CSRFHeader or form value.
+func requestCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(CSRFHeader); token != "" {
+		return token
+	}
+	return r.FormValue(CSRFFieldName)
+}
+
+// CSRFField returns a hidden input element named CSRFFieldName holding a
+// new token for a request handled by Protect, e.g. in a template using
+// NewFuncMap:
+//
+//   <form method="post">{{ csrffield .Request }} ...</form>
+//
+// Requests not handled by Protect, or without a session if the CSRF has a
+// SessionID function, result in an error.
+func CSRFField(r *http.Request) (template.HTML, error) {
+	state, ok := r.Context().Value(csrfStateKey).(*csrfState)
+	if !ok {
+		return "", errors.New("CSRFField requires a request handled by Protect")
+	}
+	token := state.csrf.Token(r)
+	if token == "" {
+		return "", errors.New("CSRFField requires a session")
+	}
+	a := &htmlAttrs{}
+	a.add("type", "hidden")
+	a.add("name", CSRFFieldName)
+	a.add("value", token)
+	return a.open("input"), nil
+}
+
+// WithCSRF makes DecodeForm check the CSRF token for the request r, taken
+// from the CSRFFieldName value of the form or the CSRFHeader of r, before
+// decoding anything.  If the check fails, DecodeForm returns the CSRFError.
+func WithCSRF(c *CSRF, r *http.Request) DecodeOption {
+	return func(dc *decodeConfig) {
+		dc.csrf = c
+		dc.csrfRequest = r
+	}
+}
+
+// checkCSRF checks the CSRF token if the WithCSRF option was given.
+func (c *decodeConfig) checkCSRF(f FormValuer) error {
+	if c.csrf == nil {
+		return nil
+	}
+	token := c.csrfRequest.Header.Get(CSRFHeader)
+	if token == "" {
+		token = f.FormValue(CSRFFieldName)
+	}
+	return c.csrf.Check(c.csrfRequest, token)
+}
@@ -0,0 +1,346 @@
+// formcsrf_test.go
+// ----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+var csrfKey = []byte("0123456789abcdef0123456789abcdef")
+
+var csrfTokenMatch = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// csrfServer returns a server with a form page rendering the CSRF field, and
+// an order handler decoding the form.
+func csrfServer(csrf *vebben.CSRF) *httptest.Server {
+
+	tmpl := template.Must(template.New("").Funcs(vebben.NewFuncMap()).Parse(
+		`<form method="post">{{ csrffield .Request }}</form>`))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]interface{}{"Request": r}
+		if err := tmpl.Execute(w, data); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	})
+	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ordered "+r.FormValue("item"))
+	})
+	return httptest.NewServer(csrf.Protect(mux))
+}
+
+// csrfFetch returns the token and cookie from the form page.
+func csrfFetch(t *testing.T, srv *httptest.Server) (string, *http.Cookie) {
+
+	res, err := http.Get(srv.URL + "/form")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	m := csrfTokenMatch.FindStringSubmatch(string(body))
+	if m == nil {
+		t.Fatalf("no token in %q", body)
+	}
+	var cookie *http.Cookie
+	for _, c := range res.Cookies() {
+		if c.Name == "csrf" {
+			cookie = c
+		}
+	}
+	return m[1], cookie
+}
+
+func csrfPost(t *testing.T, srv *httptest.Server, token string,
+	cookie *http.Cookie) (int, string) {
+
+	form := url.Values{"item": {"bread"}}
+	if token != "" {
+		form.Set("csrf_token", token)
+	}
+	req, _ := http.NewRequest("POST", srv.URL+"/order",
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, strings.TrimSpace(string(body))
+}
+
+func Test_NewCSRF(t *testing.T) {
+
+	assert := assert.New(t)
+
+	csrf := vebben.NewCSRF(csrfKey)
+	assert.Equal("csrf", csrf.CookieName)
+	assert.Equal("/", csrf.CookiePath)
+	assert.True(csrf.Secure)
+	assert.Equal(12*time.Hour, csrf.MaxAge)
+
+	testig.AssertPanicsWith(t, func() { vebben.NewCSRF([]byte("short")) },
+		"CSRF key must be at least 32 bytes", "panic on short key")
+}
+
+func Test_CSRF_Protect(t *testing.T) {
+
+	assert := assert.New(t)
+
+	srv := csrfServer(vebben.NewCSRF(csrfKey))
+	defer srv.Close()
+
+	token, cookie := csrfFetch(t, srv)
+	if !assert.NotNil(cookie, "cookie set") {
+		return
+	}
+	assert.True(cookie.HttpOnly, "cookie HttpOnly")
+	assert.True(cookie.Secure, "cookie Secure")
+	assert.Equal(http.SameSiteLaxMode, cookie.SameSite, "cookie SameSite")
+
+	code, body := csrfPost(t, srv, token, cookie)
+	assert.Equal(200, code, "good token")
+	assert.Equal("ordered bread", body)
+
+	code, _ = csrfPost(t, srv, "", cookie)
+	assert.Equal(403, code, "missing token")
+
+	code, _ = csrfPost(t, srv, token, nil)
+	assert.Equal(403, code, "missing cookie")
+
+	code, _ = csrfPost(t, srv, token+"x", cookie)
+	assert.Equal(403, code, "malformed token")
+
+	otherToken, otherCookie := csrfFetch(t, srv)
+	assert.NotEqual(cookie.Value, otherCookie.Value, "new secret")
+	code, _ = csrfPost(t, srv, otherToken, cookie)
+	assert.Equal(403, code, "token for other cookie")
+
+	// Tokens differ each time but all remain valid.
+	req, _ := http.NewRequest("GET", srv.URL+"/form", nil)
+	req.AddCookie(cookie)
+	got, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body2, _ := io.ReadAll(got.Body)
+	got.Body.Close()
+	assert.Empty(got.Cookies(), "no new cookie")
+	m := csrfTokenMatch.FindStringSubmatch(string(body2))
+	if assert.NotNil(m) {
+		assert.NotEqual(token, m[1], "new token")
+		code, _ = csrfPost(t, srv, m[1], cookie)
+		assert.Equal(200, code, "new token valid")
+		code, _ = csrfPost(t, srv, token, cookie)
+		assert.Equal(200, code, "old token still valid")
+	}
+}
+
+func Test_CSRF_Protect_Header(t *testing.T) {
+
+	assert := assert.New(t)
+
+	srv := csrfServer(vebben.NewCSRF(csrfKey))
+	defer srv.Close()
+
+	token, cookie := csrfFetch(t, srv)
+	req, _ := http.NewRequest("DELETE", srv.URL+"/order", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	req.AddCookie(cookie)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(200, res.StatusCode)
+}
+
+func Test_CSRF_Protect_FailureHandler(t *testing.T) {
+
+	assert := assert.New(t)
+
+	csrf := vebben.NewCSRF(csrfKey)
+	csrf.FailureHandler = http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(400)
+			io.WriteString(w, vebben.CSRFFailure(r).Error())
+		})
+	srv := csrfServer(csrf)
+	defer srv.Close()
+
+	_, cookie := csrfFetch(t, srv)
+	code, body := csrfPost(t, srv, "", cookie)
+	assert.Equal(400, code)
+	assert.Equal("CSRF check failed: missing token", body)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(vebben.CSRFFailure(r), "no failure")
+}
+
+func Test_CSRF_Check(t *testing.T) {
+
+	assert := assert.New(t)
+
+	csrf := vebben.NewCSRF(csrfKey)
+	csrf.MaxAge = 10 * time.Millisecond
+
+	// Without Protect, the cookie must already exist.
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Equal("", csrf.Token(r), "no cookie, no token")
+	assert.Equal("CSRF check failed: missing cookie",
+		csrf.Check(r, "abc").Error())
+
+	var cookie *http.Cookie
+	h := csrf.Protect(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	for _, c := range w.Result().Cookies() {
+		cookie = c
+	}
+	if !assert.NotNil(cookie) {
+		return
+	}
+	r = httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(cookie)
+	token := csrf.Token(r)
+	assert.NotEqual("", token)
+	assert.Nil(csrf.Check(r, token), "good token")
+
+	tampered := token[:len(token)-1] + "A"
+	if strings.HasSuffix(token, "A") {
+		tampered = token[:len(token)-1] + "B"
+	}
+	for token, reason := range map[string]string{
+		"":         "missing token",
+		"!!!":      "malformed token",
+		token[:20]: "malformed token",
+		tampered:   "invalid token",
+	} {
+		err := csrf.Check(r, token)
+		var ce *vebben.CSRFError
+		if assert.True(errors.As(err, &ce), token) {
+			assert.Equal(reason, ce.Reason, token)
+		}
+	}
+
+	other := vebben.NewCSRF([]byte("another key of at least 32 bytes!"))
+	assert.Equal("CSRF check failed: invalid token",
+		other.Check(r, token).Error(), "other key")
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal("CSRF check failed: expired token",
+		csrf.Check(r, token).Error(), "expired")
+}
+
+func Test_CSRF_SessionID(t *testing.T) {
+
+	assert := assert.New(t)
+
+	csrf := vebben.NewCSRF(csrfKey)
+	csrf.SessionID = func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}
+	r := httptest.NewRequest("POST", "/", nil)
+	assert.Equal("", csrf.Token(r), "no session")
+	assert.Equal("CSRF check failed: no session",
+		csrf.Check(r, "abc").Error())
+
+	r.Header.Set("X-Session", "s1")
+	token := csrf.Token(r)
+	assert.Nil(csrf.Check(r, token), "same session")
+
+	r.Header.Set("X-Session", "s2")
+	assert.Equal("CSRF check failed: invalid token",
+		csrf.Check(r, token).Error(), "other session")
+
+	// No cookie is set for sessions.
+	w := httptest.NewRecorder()
+	csrf.Protect(http.NotFoundHandler()).ServeHTTP(w,
+		httptest.NewRequest("GET", "/", nil))
+	assert.Empty(w.Result().Cookies())
+}
+
+func Test_CSRFField(t *testing.T) {
+
+	assert := assert.New(t)
+
+	_, err := vebben.CSRFField(httptest.NewRequest("GET", "/", nil))
+	assert.EqualError(err, "CSRFField requires a request handled by Protect")
+
+	csrf := vebben.NewCSRF(csrfKey)
+	csrf.SessionID = func(*http.Request) string { return "" }
+	var fieldErr error
+	csrf.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, fieldErr = vebben.CSRFField(r)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.EqualError(fieldErr, "CSRFField requires a session")
+}
+
+func Test_DecodeForm_WithCSRF(t *testing.T) {
+
+	assert := assert.New(t)
+
+	csrf := vebben.NewCSRF(csrfKey)
+	csrf.SessionID = func(*http.Request) string { return "sess" }
+	specs := []*vebben.FormSpec{vebben.RequiredFormSpec("item", "string")}
+
+	newRequest := func(form url.Values) *http.Request {
+		r := httptest.NewRequest("POST", "/order",
+			bytes.NewBufferString(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+	token := csrf.Token(newRequest(nil))
+
+	target := map[string]interface{}{}
+	r := newRequest(url.Values{"item": {"bread"}, "csrf_token": {token}})
+	err := vebben.DecodeForm(r, specs, &target, vebben.WithCSRF(csrf, r))
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"item": "bread"}, target)
+
+	// Header instead of field:
+	target = map[string]interface{}{}
+	r = newRequest(url.Values{"item": {"bread"}})
+	r.Header.Set("X-CSRF-Token", token)
+	err = vebben.DecodeForm(r, specs, &target, vebben.WithCSRF(csrf, r))
+	assert.Nil(err, "header")
+
+	// Failure means no decoding at all.
+	target = map[string]interface{}{}
+	r = newRequest(url.Values{"csrf_token": {"nope"}})
+	err = vebben.DecodeForm(r, specs, &target, vebben.WithCSRF(csrf, r))
+	var ce *vebben.CSRFError
+	if assert.True(errors.As(err, &ce), "CSRFError") {
+		assert.Equal("malformed token", ce.Reason)
+	}
+	assert.Empty(target)
+
+	// The form may be a different FormValuer.
+	r = newRequest(nil)
+	form := vebben.URLValues{"item": {"bread"}, "csrf_token": {token}}
+	err = vebben.DecodeForm(form, specs, &target, vebben.WithCSRF(csrf, r))
+	assert.Nil(err, "URLValues")
+}
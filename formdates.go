@@ -0,0 +1,351 @@
+// formdates.go -- adaptive matching of date and datetime layouts.
+// ------------
+
+package vebben
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// dateLayout is a layout of a format list, with the shape of the input it
+// can match.  Most layouts consist only of numeric elements and literal
+// separators, so they can only match input with the same separators and a
+// number of digits in their range, or if they end in seconds, also with a
+// fraction of a second, which is accepted even if the layout has none.
+// Others, e.g. those with month names or time zones, are not classified,
+// and are always tried.
+type dateLayout struct {
+	layout     string
+	seps       string // literal text, with runs of spaces collapsed
+	minDigits  int
+	maxDigits  int
+	frac       bool // ends in seconds, so may be followed by a fraction
+	classified bool
+
+	// earlier are the indexes of the layouts before this one in the list
+	// that may match the same input, and so take precedence over it.
+	earlier []int
+}
+
+// dateElements are the numeric layout elements that can be classified,
+// with their minimum and maximum number of digits, as parsed by package
+// time.  They are matched in order, so longer elements come first.
+var dateElements = []struct {
+	elem     string
+	min, max int
+	seconds  bool
+}{
+	{"2006", 4, 4, false},
+	{"01", 2, 2, false},
+	{"02", 2, 2, false},
+	{"03", 2, 2, false},
+	{"04", 2, 2, false},
+	{"05", 2, 2, true},
+	{"06", 2, 2, false},
+	{"15", 1, 2, false},
+	{"1", 1, 2, false},
+	{"2", 1, 2, false},
+	{"3", 1, 2, false},
+	{"4", 1, 2, false},
+	{"5", 1, 2, true},
+}
+
+// unclassifiedElements start the layout elements that are not classified,
+// other than fractional seconds (see isFraction) and those starting with a
+// digit but not listed in dateElements, e.g. "002".
+var unclassifiedElements = []string{
+	"Jan", "Mon", "MST", "PM", "pm", "Z07", "-07", "_2",
+}
+
+// isFraction returns true if the layout starts with a fractional second
+// element, e.g. ".000" or ",999".
+func isFraction(layout string) bool {
+	if len(layout) < 2 || layout[0] != '.' && layout[0] != ',' ||
+		layout[1] != '0' && layout[1] != '9' {
+		return false
+	}
+	i := 2
+	for i < len(layout) && layout[i] == layout[1] {
+		i++
+	}
+	return i == len(layout) || layout[i] < '0' || layout[i] > '9'
+}
+
+// isUnclassified returns true if the layout starts with an element that is
+// not classified.
+func isUnclassified(layout string) bool {
+	for _, e := range unclassifiedElements {
+		if strings.HasPrefix(layout, e) {
+			return true
+		}
+	}
+	return isFraction(layout)
+}
+
+// newDateLayout returns the dateLayout for layout.
+func newDateLayout(layout string) *dateLayout {
+
+	l := &dateLayout{layout: layout}
+	if layout == "" || layout[0] == ' ' || layout[len(layout)-1] == ' ' {
+		return l
+	}
+	var seps strings.Builder
+	space := false
+	rest := layout
+	for rest != "" {
+		c := rest[0]
+		if isUnclassified(rest) {
+			return l
+		}
+		if c < '0' || c > '9' {
+			if c != ' ' || !space {
+				seps.WriteByte(c)
+			}
+			space = c == ' '
+			rest = rest[1:]
+			continue
+		}
+		space = false
+		found := false
+		for _, e := range dateElements {
+			if strings.HasPrefix(rest, e.elem) {
+				l.minDigits += e.min
+				l.maxDigits += e.max
+				rest = rest[len(e.elem):]
+				found = true
+				if e.seconds && rest != "" {
+					return l // a fraction may follow
+				}
+				l.frac = e.seconds
+				break
+			}
+		}
+		if !found {
+			return l
+		}
+	}
+	l.seps = seps.String()
+	l.classified = true
+	return l
+}
+
+// overlaps returns true if the layouts may match the same input.
+func (l *dateLayout) overlaps(o *dateLayout) bool {
+	switch {
+	case !l.classified || !o.classified:
+		return true
+	case l.frac || o.frac:
+		return strings.HasPrefix(l.seps, o.seps) ||
+			strings.HasPrefix(o.seps, l.seps)
+	}
+	return l.seps == o.seps && l.minDigits <= o.maxDigits &&
+		o.minDigits <= l.maxDigits
+}
+
+// dateShape is the shape of an input value, for comparison with that of a
+// dateLayout; and if it ends in a fraction, e.g. ".250", also its shape
+// without the fraction.
+type dateShape struct {
+	seps       string
+	digits     int
+	fracSeps   string
+	fracDigits int
+	hasFrac    bool
+}
+
+// newDateShape returns the shape of raw.
+func newDateShape(raw string) dateShape {
+	var seps strings.Builder
+	digits := 0
+	space := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+			space = false
+		case c == ' ':
+			if !space {
+				seps.WriteByte(c)
+			}
+			space = true
+		default:
+			seps.WriteByte(c)
+			space = false
+		}
+	}
+	shape := dateShape{seps: seps.String(), digits: digits}
+	i := len(raw)
+	for i > 0 && raw[i-1] >= '0' && raw[i-1] <= '9' {
+		i--
+	}
+	if i > 0 && i < len(raw) && (raw[i-1] == '.' || raw[i-1] == ',') {
+		shape.fracSeps = shape.seps[:len(shape.seps)-1]
+		shape.fracDigits = digits - (len(raw) - i)
+		shape.hasFrac = true
+	}
+	return shape
+}
+
+// fits returns true if input of shape s may match the layout.
+func (l *dateLayout) fits(s dateShape) bool {
+	if !l.classified {
+		return true
+	}
+	if l.frac && s.hasFrac && l.seps == s.fracSeps &&
+		s.fracDigits >= l.minDigits && s.fracDigits <= l.maxDigits {
+		return true
+	}
+	return l.seps == s.seps &&
+		s.digits >= l.minDigits && s.digits <= l.maxDigits
+}
+
+// dateMatcher parses input with the first matching layout of a format list,
+// trying the layouts that matched recently first, and skipping those that
+// can not match at all.  The result is always that of the first matching
+// layout in the list, as a layout that matches takes precedence over any
+// later ones, and is checked against any earlier ones that may match the
+// same input.  A dateMatcher is safe for concurrent use.
+type dateMatcher struct {
+	layouts []*dateLayout
+	order   atomic.Value // []int: indexes of the layouts, most recent first
+}
+
+// newDateMatcher returns a new dateMatcher for the layouts.
+func newDateMatcher(layouts []string) *dateMatcher {
+	m := &dateMatcher{layouts: make([]*dateLayout, len(layouts))}
+	order := make([]int, len(layouts))
+	for i, layout := range layouts {
+		l := newDateLayout(layout)
+		for j := 0; j < i; j++ {
+			if l.overlaps(m.layouts[j]) {
+				l.earlier = append(l.earlier, j)
+			}
+		}
+		m.layouts[i] = l
+		order[i] = i
+	}
+	m.order.Store(order)
+	return m
+}
+
+// isFor returns true if the matcher is for the layouts.
+func (m *dateMatcher) isFor(layouts []string) bool {
+	if len(layouts) != len(m.layouts) {
+		return false
+	}
+	for i, layout := range layouts {
+		if m.layouts[i].layout != layout {
+			return false
+		}
+	}
+	return true
+}
+
+// parse returns raw as parsed in loc with the first matching layout, or
+// false if none matches.
+func (m *dateMatcher) parse(raw string, loc *time.Location) (time.Time,
+	bool) {
+
+	shape := newDateShape(raw)
+	order := m.order.Load().([]int)
+	for pos, i := range order {
+		l := m.layouts[i]
+		if !l.fits(shape) {
+			continue
+		}
+		t, err := time.ParseInLocation(l.layout, raw, loc)
+		if err != nil {
+			continue
+		}
+		for _, j := range l.earlier {
+			if !m.layouts[j].fits(shape) {
+				continue
+			}
+			if et, err := time.ParseInLocation(m.layouts[j].layout, raw,
+				loc); err == nil {
+				t, i, pos = et, j, -1
+				break
+			}
+		}
+		m.promote(order, pos, i)
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// promote moves layout i, at position pos in order or -1 if unknown, to
+// the front of the order.  Concurrent promotions may overwrite one
+// another, but the order always has every layout.
+func (m *dateMatcher) promote(order []int, pos, i int) {
+	if pos == 0 {
+		return
+	}
+	promoted := make([]int, 1, len(order))
+	promoted[0] = i
+	for _, o := range order {
+		if o != i {
+			promoted = append(promoted, o)
+		}
+	}
+	m.order.Store(promoted)
+}
+
+// dateMatcherCache holds the dateMatcher for the format list it was last
+// asked for.  It is safe for concurrent use.
+type dateMatcherCache struct {
+	matcher atomic.Value // *dateMatcher
+}
+
+// get returns the dateMatcher for the layouts, making a new one if the
+// layouts have changed.
+func (c *dateMatcherCache) get(layouts []string) *dateMatcher {
+	if m, _ := c.matcher.Load().(*dateMatcher); m != nil && m.isFor(layouts) {
+		return m
+	}
+	m := newDateMatcher(layouts)
+	c.matcher.Store(m)
+	return m
+}
+
+// dateMatcherCaches are the caches of a Decoder for its DateFormats and
+// DateTimeFormats.
+type dateMatcherCaches struct {
+	dates, dateTimes dateMatcherCache
+}
+
+// packageDateMatchers are the caches for the package-level format lists.
+var packageDateMatchers = &dateMatcherCaches{}
+
+// weekDateMatch matches ISO 8601 week dates, with or without hyphens, and
+// with or without the day of the week.
+var weekDateMatch = regexp.MustCompile(`^(\d{4})(-?)[Ww](\d{2})(?:(-?)([1-7]))?$`)
+
+// parseWeekDate returns the ISO 8601 week date raw, e.g. "2023-W14-3",
+// as a date in loc, or the Monday of the week if it has no day, e.g.
+// "2023-W14".  Hyphens must be used throughout or not at all.
+func parseWeekDate(raw string, loc *time.Location) (time.Time, bool) {
+
+	m := weekDateMatch.FindStringSubmatch(raw)
+	if m == nil || m[5] != "" && m[2] != m[4] {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(m[1])
+	week, _ := strconv.Atoi(m[3])
+	day := 1
+	if m[5] != "" {
+		day, _ = strconv.Atoi(m[5])
+	}
+	if _, last := time.Date(year, 12, 28, 0, 0, 0, 0, loc).ISOWeek(); week < 1 ||
+		week > last {
+		return time.Time{}, false
+	}
+	// January 4 is always in the first week.
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7+day-1), true
+}
@@ -0,0 +1,308 @@
+// formdates_test.go
+// -----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+// linearDecoder returns a Decoder with the settings of dec, which is not
+// made by NewDecoder and so tries every format in order.
+func linearDecoder(dec *vebben.Decoder) *vebben.Decoder {
+	return &vebben.Decoder{
+		TrimSpace:       dec.TrimSpace,
+		Location:        dec.Location,
+		DateFormats:     dec.DateFormats,
+		DateTimeFormats: dec.DateTimeFormats,
+	}
+}
+
+var dateInputs = []string{
+	"2023. 04. 05.", "2023. 04. 05", "2023. 4. 5.", "2023.4.5", "2023.04.05.",
+	"2023-04-05", "2023-4-5", "2023 04 05", "2023   4 5", "20230405",
+	"05.04.2023", "5.4.2023", "04/05/2023", "4/5/2023", "13/01/2023",
+	"2023-02-29", "2023-04-05x", "x2023-04-05", " 2023-04-05", "2023--04-05",
+	"2023. 04. 05. 06:07", "2023-04-05 06:07", "2023-04-05 6:07",
+	"2023-04-05T06:07", "20230405060708", "20230405060708.123",
+	"2023.4.5. 6:07", "05.04.2023 06:07", "4/5/2023 06:07", "2023-04-05 24:00",
+	"2023-04-05 06:7", "2023-04-05  06:07", "", "today", "12345",
+	"2023-04-05T06:07:08", "2023-04-05T06:07:08.25", "2023-04-05 06:07:08,5",
+	"2023. 04. 05. 06:07:08.123", "20230405T060708", "20230405T060708.1",
+	"2023-04-05T06:07:08Z", "2023-04-05T06:07:08.5+02:00",
+	"2023-04-05T06:07-05:30", "20230405T060708+0200", "2023-04-05T06:07:08.",
+	"2023-04-05T06:07.5", "2023-W14-3", "2023W143", "2023-W14",
+}
+
+func Test_Decoder_AdaptiveDates(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dec := vebben.NewDecoder()
+	linear := linearDecoder(dec)
+	for _, typ := range []string{"date", "datetime", "dateflex"} {
+		spec := vebben.OptionalFormSpec("x", typ)
+		// Twice, so the formats are tried in other orders.
+		for round := 0; round < 2; round++ {
+			for _, in := range dateInputs {
+				desc := fmt.Sprintf("%s %q", typ, in)
+				want, wantErr := linear.Convert(spec, in)
+				got, err := dec.Convert(spec, in)
+				assert.Equal(want, got, desc)
+				assert.Equal(wantErr == nil, err == nil, desc)
+			}
+		}
+	}
+}
+
+func Test_Decoder_AdaptiveDates_FirstFormatWins(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dec := vebben.NewDecoder()
+	dec.Location = time.UTC
+	dec.DateFormats = []string{"01/02/2006", "02/01/2006", "2/1/2006"}
+	spec := vebben.OptionalFormSpec("x", "date")
+
+	// Only the later formats match these, and so are tried first next.
+	for _, in := range []string{"13/01/2023", "13/1/2023"} {
+		v, err := dec.Convert(spec, in)
+		if assert.Nil(err, in) {
+			assert.Equal(time.Date(2023, 1, 13, 0, 0, 0, 0, time.UTC), v, in)
+		}
+	}
+
+	// But the first format still takes precedence.
+	v, err := dec.Convert(spec, "04/05/2023")
+	if assert.Nil(err) {
+		assert.Equal(time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC), v)
+	}
+
+	// Changed formats are used.
+	dec.DateFormats = []string{"02/01/2006"}
+	v, err = dec.Convert(spec, "04/05/2023")
+	if assert.Nil(err) {
+		assert.Equal(time.Date(2023, 5, 4, 0, 0, 0, 0, time.UTC), v)
+	}
+}
+
+func Test_Decoder_AdaptiveDates_Concurrent(t *testing.T) {
+
+	dec := vebben.NewDecoder()
+	linear := linearDecoder(dec)
+	spec := vebben.OptionalFormSpec("x", "dateflex")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := range dateInputs {
+				in := dateInputs[(i+j)%len(dateInputs)]
+				want, _ := linear.Convert(spec, in)
+				got, _ := dec.Convert(spec, in)
+				assert.Equal(t, want, got, in)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// realisticDates is a mix of dates as typed in a Hungarian shop, mostly in
+// the local format or from date inputs, with a few others.
+var realisticDates = []string{
+	"2023. 04. 05.", "2023-04-05", "2023. 04. 05.", "2023.04.05.",
+	"2023-04-06", "2023. 4. 7.", "2023. 04. 08.", "2023-04-09",
+	"2023. 04. 10.", "05.04.2023", "2023. 04. 11", "20230412",
+}
+
+// realisticDateTimes is the same for datetimes.
+var realisticDateTimes = []string{
+	"2023. 04. 05. 10:30", "2023-04-05T10:30", "2023. 04. 05. 11:00",
+	"2023.04.05. 12:15", "2023-04-06T09:00", "2023. 4. 7. 8:45",
+	"2023. 04. 08. 14:00", "2023-04-09 16:30", "1/2/2023 15:04",
+}
+
+func benchmarkDates(b *testing.B, dec *vebben.Decoder, typ string,
+	inputs []string) {
+
+	spec := vebben.OptionalFormSpec("x", typ)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dec.Convert(spec, inputs[i%len(inputs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDates_Linear(b *testing.B) {
+	benchmarkDates(b, linearDecoder(vebben.NewDecoder()), "date",
+		realisticDates)
+}
+
+func BenchmarkDates_Adaptive(b *testing.B) {
+	benchmarkDates(b, vebben.NewDecoder(), "date", realisticDates)
+}
+
+func BenchmarkDateTimes_Linear(b *testing.B) {
+	benchmarkDates(b, linearDecoder(vebben.NewDecoder()), "datetime",
+		realisticDateTimes)
+}
+
+func BenchmarkDateTimes_Adaptive(b *testing.B) {
+	benchmarkDates(b, vebben.NewDecoder(), "datetime", realisticDateTimes)
+}
+
+func BenchmarkDateFlex_Linear(b *testing.B) {
+	benchmarkDates(b, linearDecoder(vebben.NewDecoder()), "dateflex",
+		append(realisticDates, realisticDateTimes...))
+}
+
+func BenchmarkDateFlex_Adaptive(b *testing.B) {
+	benchmarkDates(b, vebben.NewDecoder(), "dateflex",
+		append(realisticDates, realisticDateTimes...))
+}
+
+func Test_FormSpec_Convert_ISODateTimes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	loc := vebben.FormValueTimeLocation
+	plus2 := time.FixedZone("", 2*3600)
+	minus530 := time.FixedZone("", -(5*3600 + 30*60))
+	for _, tc := range []struct {
+		in  string
+		out time.Time // zero for conversion errors
+	}{
+		{"2023-04-05T06:07", time.Date(2023, 4, 5, 6, 7, 0, 0, loc)},
+		{"2023-04-05T06:07:08", time.Date(2023, 4, 5, 6, 7, 8, 0, loc)},
+		{"2023-04-05T06:07:08.25",
+			time.Date(2023, 4, 5, 6, 7, 8, 250000000, loc)},
+		{"2023-04-05 06:07:08,5",
+			time.Date(2023, 4, 5, 6, 7, 8, 500000000, loc)},
+		{"2023. 04. 05. 06:07:08", time.Date(2023, 4, 5, 6, 7, 8, 0, loc)},
+		{"20230405T060708", time.Date(2023, 4, 5, 6, 7, 8, 0, loc)},
+		{"2023-04-05T06:07:08Z", time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)},
+		{"2023-04-05T06:07:08.5+02:00",
+			time.Date(2023, 4, 5, 6, 7, 8, 500000000, plus2)},
+		{"2023-04-05T06:07-05:30", time.Date(2023, 4, 5, 6, 7, 0, 0, minus530)},
+		{"2023-04-05 06:07:08+02:00", time.Date(2023, 4, 5, 6, 7, 8, 0, plus2)},
+		{"20230405T060708+0200", time.Date(2023, 4, 5, 6, 7, 8, 0, plus2)},
+		{"2023-04-05T06:07:08+25:00", time.Time{}},
+		{"2023-04-05T06:07:08 +02:00", time.Time{}},
+		{"2023-04-05T06:07:60", time.Time{}},
+	} {
+		v, err := vebben.OptionalFormSpec("x", "datetime").Convert(tc.in)
+		if tc.out.IsZero() {
+			assert.Error(err, tc.in)
+			continue
+		}
+		if assert.Nil(err, tc.in) {
+			// The offset is kept, not only the instant.
+			assert.True(tc.out.Equal(v.(time.Time)), tc.in)
+			_, want := tc.out.Zone()
+			_, got := v.(time.Time).Zone()
+			assert.Equal(want, got, tc.in)
+		}
+	}
+}
+
+func Test_FormSpec_Convert_WeekDates(t *testing.T) {
+
+	assert := assert.New(t)
+
+	loc := vebben.FormValueTimeLocation
+	for _, tc := range []struct {
+		typ string
+		in  string
+		out time.Time // zero for conversion errors
+	}{
+		{"date", "2023-W14-3", time.Date(2023, 4, 5, 0, 0, 0, 0, loc)},
+		{"date", "2023W143", time.Date(2023, 4, 5, 0, 0, 0, 0, loc)},
+		{"date", "2023-W14", time.Date(2023, 4, 3, 0, 0, 0, 0, loc)},
+		{"date", "2023-W01-1", time.Date(2023, 1, 2, 0, 0, 0, 0, loc)},
+		{"date", "2025-W01-1", time.Date(2024, 12, 30, 0, 0, 0, 0, loc)},
+		{"date", "2026-W53-7", time.Date(2027, 1, 3, 0, 0, 0, 0, loc)},
+		{"dateflex", "2023-w52-7", time.Date(2023, 12, 31, 0, 0, 0, 0, loc)},
+		{"date", "2023-W53-1", time.Time{}},
+		{"date", "2023-W00", time.Time{}},
+		{"date", "2023-W14-8", time.Time{}},
+		{"date", "2023-W143", time.Time{}},
+		{"date", "2023W14-3", time.Time{}},
+		{"datetime", "2023-W14-3", time.Time{}},
+	} {
+		desc := fmt.Sprintf("%s %q", tc.typ, tc.in)
+		v, err := vebben.OptionalFormSpec("x", tc.typ).Convert(tc.in)
+		if tc.out.IsZero() {
+			assert.Error(err, desc)
+			continue
+		}
+		if assert.Nil(err, desc) {
+			assert.Equal(tc.out, v, desc)
+		}
+	}
+}
+
+func Test_DecodeForm_DateTimePrecision(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		limit string
+		in    string
+		ok    bool
+	}{
+		{"", "2023-04-05T06:07:08.123", true},
+		{"precision=minute", "2023-04-05T06:07", true},
+		{"precision=minute", "2023-04-05T06:07:00", true},
+		{"precision=minute", "2023-04-05T06:07:08", false},
+		{"precision=minute", "2023. 04. 05.", true},
+		{"precision=second", "2023-04-05T06:07:08", true},
+		{"precision=second", "2023-04-05T06:07:08.000", true},
+		{"precision=second", "2023-04-05T06:07:08.5", false},
+		{"precision=millisecond", "2023-04-05T06:07:08.123", true},
+		{"precision=millisecond", "2023-04-05T06:07:08.1234", false},
+	} {
+		desc := fmt.Sprintf("%q %q", tc.limit, tc.in)
+		specs := []*vebben.FormSpec{
+			vebben.OptionalFormSpec("at", "dateflex", tc.limit, "At"),
+		}
+		err := vebben.DecodeForm(vebben.URLValues{"at": {tc.in}}, specs,
+			&map[string]interface{}{})
+		if tc.ok {
+			assert.Nil(err, desc)
+			continue
+		}
+		if assert.Error(err, desc) {
+			fe := err.(*vebben.MultiError).FieldErrors()[0]
+			assert.Equal(vebben.CodeBadPrecision, fe.Code, desc)
+			assert.Equal(tc.in, fe.Input, desc)
+		}
+	}
+
+	err := vebben.DecodeForm(vebben.URLValues{"at": {"2023-04-05 06:07:08"}},
+		[]*vebben.FormSpec{vebben.OptionalFormSpec("at", "datetime",
+			"precision=minute", "At")}, &map[string]interface{}{})
+	if assert.Error(err) {
+		assert.Equal("At may only be given to the minute", err.Error())
+	}
+
+	testig.AssertPanicsWith(t, func() {
+		vebben.OptionalFormSpec("at", "date", "precision=second")
+	}, "Precision limit does not apply to date", "date")
+	testig.AssertPanicsWith(t, func() {
+		vebben.OptionalFormSpec("at", "datetime", "precision=hour")
+	}, "Bad precision limit: hour", "bad precision")
+}
@@ -0,0 +1,395 @@
+// formdecimal.go -- exact decimal and money FormSpec types.
+// -------------
+
+package vebben
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+// Decimal is an exact decimal number of Units × 10^-Scale, e.g. 12.30 is
+// Decimal{1230, 2}.  The Scale, the number of digits after the decimal
+// point, is kept as given: "12.30" and "12.3" are equal, but not the same.
+// It is the value of the "decimal" FormSpec type.
+type Decimal struct {
+	Units int64
+	Scale int
+}
+
+// Money is an exact amount of money in the currency with the ISO 4217 code
+// Currency, e.g. "EUR".  It is the value of the "money" FormSpec type.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+// maxDecimalScale is the largest Scale that fits the int64 Units.
+const maxDecimalScale = 18
+
+var decimalMatch = regexp.MustCompile(`^[-+]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)$`)
+var moneyMatch = regexp.MustCompile(`^([A-Za-z]{3})? ?(.*?) ?([A-Za-z]{3})?$`)
+
+var errDecimalSyntax = errors.New("invalid decimal syntax")
+var errDecimalRange = errors.New("decimal out of range")
+var errDecimalPrecision = errors.New("too many decimal places")
+
+// roundingModes are the modes of the "round=" Limit clause.
+var roundingModes = map[string]bool{
+	"half-up":   true, // to nearest, halves away from zero
+	"half-even": true, // to nearest, halves to even ("banker's rounding")
+	"up":        true, // away from zero
+	"down":      true, // toward zero, i.e. truncated
+}
+
+// ParseDecimal parses s, e.g. "-12.30", as a Decimal with the scale as
+// given.  Exponents and digit separators are not accepted.
+func ParseDecimal(s string) (Decimal, error) {
+
+	if !decimalMatch.MatchString(s) {
+		return Decimal{}, errDecimalSyntax
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > maxDecimalScale {
+		return Decimal{}, errDecimalRange
+	}
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Decimal{}, errDecimalRange
+	}
+	return Decimal{Units: units, Scale: len(frac)}, nil
+}
+
+// String returns the decimal with all of its Scale digits, e.g. "12.30".
+func (d Decimal) String() string {
+
+	u := uint64(d.Units)
+	if d.Units < 0 {
+		u = uint64(-d.Units)
+	}
+	s := strconv.FormatUint(u, 10)
+	if d.Scale > 0 {
+		if len(s) <= d.Scale {
+			s = strings.Repeat("0", d.Scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+	}
+	if d.Units < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseDecimal.
+func (d *Decimal) UnmarshalText(b []byte) error {
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Cmp compares d and e by value, returning -1, 0 or 1 if d is less than,
+// equal to or greater than e.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.rat().Cmp(e.rat())
+}
+
+// Float64 returns the nearest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+func (d Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(d.Units), pow10(d.Scale))
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// rescale returns d with the given scale, rounding with mode if digits
+// are lost; without a mode that is an errDecimalPrecision.
+func (d Decimal) rescale(scale int, mode string) (Decimal, error) {
+
+	units := big.NewInt(d.Units)
+	if scale >= d.Scale {
+		units.Mul(units, pow10(scale-d.Scale))
+	} else {
+		div := pow10(d.Scale - scale)
+		var rem big.Int
+		units.QuoRem(units, div, &rem)
+		if rem.Sign() != 0 {
+			if mode == "" {
+				return Decimal{}, errDecimalPrecision
+			}
+			half := rem.Abs(&rem).Lsh(&rem, 1).Cmp(div)
+			away := false
+			switch mode {
+			case "up":
+				away = true
+			case "half-up":
+				away = half >= 0
+			case "half-even":
+				away = half > 0 || half == 0 && units.Bit(0) == 1
+			}
+			if away && d.Units < 0 {
+				units.Sub(units, big.NewInt(1))
+			} else if away {
+				units.Add(units, big.NewInt(1))
+			}
+		}
+	}
+	if !units.IsInt64() {
+		return Decimal{}, errDecimalRange
+	}
+	return Decimal{Units: units.Int64(), Scale: scale}, nil
+}
+
+// ParseMoney parses s as an amount and an ISO 4217 currency code, in
+// either order, e.g. "12.30 EUR" or "EUR 12.30".
+func ParseMoney(s string) (Money, error) {
+	m, err := parseMoney(s, nil)
+	if err == nil && m.Currency == "" {
+		err = errors.New("missing currency code")
+	}
+	if err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// parseMoney parses s as ParseMoney does, but without requiring the
+// currency code, and with the amount in the format of the NumberLocale of
+// d unless it is nil.
+func parseMoney(s string, d *Decoder) (Money, error) {
+
+	m := moneyMatch.FindStringSubmatch(s)
+	if m == nil || m[1] != "" && m[3] != "" {
+		return Money{}, errDecimalSyntax
+	}
+	num := m[2]
+	if d != nil {
+		var ok bool
+		if num, ok = d.localNumber(num); !ok {
+			return Money{}, errDecimalSyntax
+		}
+	}
+	amount, err := ParseDecimal(num)
+	if err != nil {
+		return Money{}, err
+	}
+	code := strings.ToUpper(m[1] + m[3])
+	if code != "" {
+		if _, err := currency.ParseISO(code); err != nil {
+			return Money{}, err
+		}
+	}
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+// String returns the amount followed by the currency code, e.g.
+// "12.30 EUR".
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseMoney.
+func (m *Money) UnmarshalText(b []byte) error {
+	v, err := ParseMoney(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// decimalFormat holds the scale, rounding and currency limits of a decimal
+// or money FormSpec.
+type decimalFormat struct {
+	scale      int
+	hasScale   bool
+	rounding   string
+	currencies []string
+}
+
+// decimalFormat returns the decimal limits of fs.
+func (fs *FormSpec) decimalFormat() *decimalFormat {
+	f := &decimalFormat{}
+	for _, l := range fs.limits {
+		switch {
+		case l.hasScale:
+			f.scale, f.hasScale = l.scale, true
+		case l.rounding != "":
+			f.rounding = l.rounding
+		case l.currencies != nil:
+			f.currencies = l.currencies
+		}
+	}
+	return f
+}
+
+// scaleFor returns the scale for amounts in currency code: the scale limit
+// if there is one, or else the standard scale of the currency.
+func (f *decimalFormat) scaleFor(code string) (int, bool) {
+	if f.hasScale || code == "" {
+		return f.scale, f.hasScale
+	}
+	scale, _ := currency.Standard.Rounding(currency.MustParseISO(code))
+	return scale, true
+}
+
+// fixedCurrency returns the currency of a money FormSpec accepting only
+// one, or an empty string.
+func (f *decimalFormat) fixedCurrency() string {
+	if len(f.currencies) == 1 {
+		return f.currencies[0]
+	}
+	return ""
+}
+
+func decimalConverter(d *Decoder, raw string) (interface{}, bool) {
+	if raw == "" {
+		return Decimal{}, true
+	}
+	raw, ok := d.localNumber(raw)
+	if !ok {
+		return nil, false
+	}
+	dec, err := ParseDecimal(raw)
+	if err != nil {
+		return nil, false
+	}
+	return dec, true
+}
+
+func moneyConverter(d *Decoder, raw string) (interface{}, bool) {
+	if raw == "" {
+		return Money{}, true
+	}
+	m, err := parseMoney(raw, d)
+	if err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// decimalFinish applies the scale, rounding and currency limits of fs to
+// the converted value v.
+func decimalFinish(_ *Decoder, fs *FormSpec, v interface{}) (interface{},
+	error) {
+
+	f := fs.decimalFormat()
+	var amount Decimal
+	var m Money
+	switch v := v.(type) {
+	case Decimal:
+		amount = v
+	case Money:
+		m = v
+		switch {
+		case m.Currency == "" && len(f.currencies) == 0:
+			return nil, fs.FieldError(CodeConversion,
+				map[string]interface{}{"type": fs.itemType()})
+		case m.Currency == "":
+			m.Currency = f.currencies[0]
+		case len(f.currencies) > 0 && !hasString(f.currencies, m.Currency):
+			return nil, fs.FieldError(CodeBadCurrency,
+				map[string]interface{}{
+					"currency":   m.Currency,
+					"currencies": f.currencies,
+				})
+		}
+		amount = m.Amount
+	default:
+		return v, nil
+	}
+
+	if scale, ok := f.scaleFor(m.Currency); ok {
+		var err error
+		amount, err = amount.rescale(scale, f.rounding)
+		switch err {
+		case nil:
+		case errDecimalPrecision:
+			return nil, fs.FieldError(CodeTooPrecise,
+				map[string]interface{}{"scale": scale})
+		default:
+			return nil, fs.FieldError(CodeConversion,
+				map[string]interface{}{"type": fs.itemType()})
+		}
+	}
+	if _, ok := v.(Money); ok {
+		m.Amount = amount
+		return m, nil
+	}
+	return amount, nil
+}
+
+func decimalValidator(fs *FormSpec, v interface{}) error {
+
+	d, ok := decimalAmount(v)
+	if !ok {
+		return wrongTypeError(fs, fs.itemType(), v)
+	}
+	for _, l := range fs.limits {
+		if err := l.checkDecimal(fs, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDecimal checks the decimal amount d.
+func (l *formLimit) checkDecimal(fs *FormSpec, d Decimal) error {
+
+	if l.rng == nil {
+		return nil
+	}
+	switch l.rng.compareDecimal(d) {
+	case -1:
+		return fs.FieldError(CodeTooLow, l.rng.params())
+	case 1:
+		return fs.FieldError(CodeTooHigh, l.rng.params())
+	}
+	if !l.rng.onStepDecimal(d) {
+		return fs.FieldError(CodeWrongStep, l.rng.params())
+	}
+	return nil
+}
+
+// decimalAmount returns the Decimal value, or amount of Money, of v.
+func decimalAmount(v interface{}) (Decimal, bool) {
+	switch v := v.(type) {
+	case Decimal:
+		return v, true
+	case Money:
+		return v.Amount, true
+	}
+	return Decimal{}, false
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
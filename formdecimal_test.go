@@ -0,0 +1,333 @@
+// formdecimal_test.go
+// -------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_ParseDecimal(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		in, out string
+		units   int64
+		scale   int
+	}{
+		{"12.30", "12.30", 1230, 2},
+		{"12.3", "12.3", 123, 1},
+		{"-0.05", "-0.05", -5, 2},
+		{"+7", "7", 7, 0},
+		{".5", "0.5", 5, 1},
+		{"12.", "12", 12, 0},
+		{"-9223372036854775808", "-9223372036854775808", -1 << 63, 0},
+		{"0.000000000000000001", "0.000000000000000001", 1, 18},
+	} {
+		d, err := vebben.ParseDecimal(tc.in)
+		if assert.Nil(err, tc.in) {
+			assert.Equal(vebben.Decimal{Units: tc.units, Scale: tc.scale}, d,
+				tc.in)
+			assert.Equal(tc.out, d.String(), tc.in)
+		}
+	}
+
+	for _, in := range []string{"", ".", "-", "1e3", "1,000", "1 000",
+		"0x10", "9223372036854775808", "0.0000000000000000001"} {
+		_, err := vebben.ParseDecimal(in)
+		assert.Error(err, in)
+	}
+}
+
+func Test_Decimal_Methods(t *testing.T) {
+
+	assert := assert.New(t)
+
+	a := vebben.Decimal{Units: 1230, Scale: 2}
+	b := vebben.Decimal{Units: 123, Scale: 1}
+	c := vebben.Decimal{Units: 1231, Scale: 2}
+	assert.Equal(0, a.Cmp(b))
+	assert.Equal(-1, b.Cmp(c))
+	assert.Equal(1, c.Cmp(a))
+	assert.Equal(12.3, a.Float64())
+
+	text, err := a.MarshalText()
+	assert.Nil(err)
+	assert.Equal("12.30", string(text))
+	var d vebben.Decimal
+	assert.Nil(d.UnmarshalText(text))
+	assert.Equal(a, d)
+	assert.Error(d.UnmarshalText([]byte("x")))
+}
+
+func Test_ParseMoney(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, in := range []string{"12.30 EUR", "EUR 12.30", "eur12.30",
+		"12.30EUR"} {
+		m, err := vebben.ParseMoney(in)
+		if assert.Nil(err, in) {
+			assert.Equal(vebben.Money{
+				Amount:   vebben.Decimal{Units: 1230, Scale: 2},
+				Currency: "EUR",
+			}, m, in)
+			assert.Equal("12.30 EUR", m.String(), in)
+		}
+	}
+	for _, in := range []string{"12.30", "EUR", "EUR 12.30 USD", "12.30 ABC",
+		"12.30  EUR", "1e3 EUR"} {
+		_, err := vebben.ParseMoney(in)
+		assert.Error(err, in)
+	}
+
+	var m vebben.Money
+	assert.Nil(m.UnmarshalText([]byte("HUF 1500")))
+	text, _ := m.MarshalText()
+	assert.Equal("1500 HUF", string(text))
+}
+
+func Test_FormSpec_Convert_Decimal(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		typ, limit, in, out, code string
+	}{
+		{"decimal", "", "12.30", "12.30", ""},
+		{"decimal", "", "12.300", "12.300", ""},
+		{"decimal", "", "1e3", "", "conversion"},
+		{"decimal", "scale=2", "12.3", "12.30", ""},
+		{"decimal", "scale=2", "12", "12.00", ""},
+		{"decimal", "scale=2", "12.345", "", "too_precise"},
+		{"decimal", "scale=2;round=half-up", "12.345", "12.35", ""},
+		{"decimal", "scale=2;round=half-up", "-12.345", "-12.35", ""},
+		{"decimal", "scale=2;round=half-even", "12.345", "12.34", ""},
+		{"decimal", "scale=2;round=half-even", "12.355", "12.36", ""},
+		{"decimal", "scale=2;round=half-even", "12.3451", "12.35", ""},
+		{"decimal", "scale=2;round=up", "12.341", "12.35", ""},
+		{"decimal", "scale=2;round=up", "-12.341", "-12.35", ""},
+		{"decimal", "scale=2;round=down", "12.349", "12.34", ""},
+		{"decimal", "scale=0;round=down", "-0.9", "0", ""},
+		{"decimal", "scale=18", "10", "", "conversion"},
+		{"money", "", "12.30 EUR", "12.30 EUR", ""},
+		{"money", "", "12.3 eur", "12.30 EUR", ""},
+		{"money", "", "1500 JPY", "1500 JPY", ""},
+		{"money", "", "1500.5 JPY", "", "too_precise"},
+		{"money", "", "12.30", "", "conversion"},
+		{"money", "", "12.30 XYZ", "", "conversion"},
+		{"money", "currency=HUF", "1500", "1500.00 HUF", ""},
+		{"money", "currency=HUF;scale=0", "1500", "1500 HUF", ""},
+		{"money", "currency=HUF,EUR", "EUR 5", "5.00 EUR", ""},
+		{"money", "currency=HUF,EUR", "5 USD", "", "bad_currency"},
+		{"money", "round=half-even", "0.125 USD", "0.12 USD", ""},
+	} {
+		desc := fmt.Sprintf("%s %q %q", tc.typ, tc.limit, tc.in)
+		spec := vebben.OptionalFormSpec("x", tc.typ, tc.limit)
+		v, err := spec.Convert(tc.in)
+		if tc.code != "" {
+			if assert.Error(err, desc) {
+				fe := err.(*vebben.FieldError)
+				assert.Equal(tc.code, fe.Code, desc)
+				assert.Equal(tc.in, fe.Input, desc)
+			}
+			continue
+		}
+		if assert.Nil(err, desc) {
+			assert.Equal(tc.out, fmt.Sprint(v), desc)
+		}
+	}
+}
+
+func Test_FormSpec_DecimalLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		typ, limit, in, code string
+		params               map[string]interface{}
+	}{
+		{"decimal", "0.01-999.99", "0.01", "", nil},
+		{"decimal", "0.01-999.99", "0.001", "too_low",
+			map[string]interface{}{
+				"min": vebben.Decimal{Units: 1, Scale: 2},
+				"max": vebben.Decimal{Units: 99999, Scale: 2},
+			}},
+		{"decimal", "range=>0 <=100", "0.000", "too_low",
+			map[string]interface{}{
+				"min": vebben.Decimal{},
+				"max": vebben.Decimal{Units: 100},
+			}},
+		{"decimal", "range=0- step=0.05", "0.35", "", nil},
+		{"decimal", "range=0- step=0.05", "0.36", "wrong_step",
+			map[string]interface{}{
+				"min":  vebben.Decimal{},
+				"step": vebben.Decimal{Units: 5, Scale: 2},
+			}},
+		{"money", "range=<=1000;currency=EUR", "1000.01", "too_high",
+			map[string]interface{}{"max": vebben.Decimal{Units: 1000}}},
+		{"money", "range=<=1000;currency=EUR", "HUF 5", "bad_currency",
+			map[string]interface{}{"currency": "HUF",
+				"currencies": []string{"EUR"}}},
+	} {
+		desc := fmt.Sprintf("%s %q %q", tc.typ, tc.limit, tc.in)
+		specs := []*vebben.FormSpec{
+			vebben.OptionalFormSpec("x", tc.typ, tc.limit),
+		}
+		err := vebben.DecodeForm(vebben.URLValues{"x": {tc.in}}, specs,
+			&map[string]interface{}{})
+		if tc.code == "" {
+			assert.Nil(err, desc)
+			continue
+		}
+		if assert.Error(err, desc) {
+			fe := err.(*vebben.MultiError).FieldErrors()[0]
+			assert.Equal(tc.code, fe.Code, desc)
+			assert.Equal(tc.params, fe.Params, desc)
+		}
+	}
+
+	spec := vebben.OptionalFormSpec("price", "decimal",
+		"scale=2;range=0- step=0.05", "Price")
+	err := vebben.DecodeForm(vebben.URLValues{"price": {"0.333"}},
+		[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+	assert.EqualError(err, "Price may have at most 2 decimal places")
+	err = vebben.DecodeForm(vebben.URLValues{"price": {"0.33"}},
+		[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+	assert.EqualError(err, "Price must be in steps of 0.05")
+}
+
+func Test_FormSpec_DecimalLimits_Panics(t *testing.T) {
+
+	for _, tc := range []struct {
+		typ, limit, msg string
+	}{
+		{"decimal", "1e3-", "Bad range limit: 1e3 is not a decimal: invalid decimal syntax"},
+		{"decimal", "range=0- step=0", "Bad range limit: step must be positive"},
+		{"decimal", "range=2-1.5", "Bad range limit: upper < lower"},
+		{"decimal", "range=>1 <1.00", "Bad range limit: empty range"},
+		{"decimal", "scale=x", "Bad scale limit: x"},
+		{"decimal", "scale=19", "Bad scale limit: 19"},
+		{"decimal", "round=nearest", "Bad rounding mode: nearest"},
+		{"decimal", "currency=EUR", "Currency limit does not apply to decimal"},
+		{"money", "currency=EUR,XYZ", "Bad currency code: XYZ"},
+		{"float", "scale=2", "Scale limit does not apply to float"},
+		{"int", "round=up", "Rounding does not apply to int"},
+		{"decimal", "1,2", "Value list not compatible with type decimal"},
+	} {
+		spec := &vebben.FormSpec{Key: "x", Type: tc.typ, Limit: tc.limit}
+		testig.AssertPanicsWith(t, func() { spec.Init() }, tc.msg,
+			"panic for "+tc.limit)
+	}
+}
+
+func Test_DecodeForm_DecimalTypes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	type Invoice struct {
+		Rate     vebben.Decimal   `vebben:",,limit=scale=4"`
+		Total    vebben.Money     `vebben:",,limit=currency=HUF,EUR"`
+		Tip      *vebben.Money    `vebben:",money,limit=currency=EUR"`
+		Cents    int64            `vebben:",money,limit=currency=EUR"`
+		Discount float64          `vebben:",decimal"`
+		Note     string           `vebben:",decimal"`
+		Extras   []vebben.Decimal `vebben:""`
+	}
+	specs := vebben.MustFormSpecsFor(Invoice{})
+	types := []string{}
+	for _, spec := range specs {
+		types = append(types, spec.Type)
+	}
+	assert.Equal([]string{"decimal", "money", "money", "money", "decimal",
+		"decimal", "[]decimal"}, types)
+
+	target := &Invoice{}
+	err := vebben.DecodeForm(vebben.URLValues{
+		"Rate":     {"0.27"},
+		"Total":    {"12.30 EUR"},
+		"Tip":      {"1.5"},
+		"Cents":    {"19.99"},
+		"Discount": {"0.25"},
+		"Note":     {"12.30"},
+		"Extras":   {"1", "2.50"},
+	}, specs, target)
+	if assert.Nil(err) {
+		assert.Equal(vebben.Decimal{Units: 2700, Scale: 4}, target.Rate)
+		assert.Equal("12.30 EUR", target.Total.String())
+		assert.Equal("1.50 EUR", target.Tip.String())
+		assert.Equal(int64(1999), target.Cents)
+		assert.Equal(0.25, target.Discount)
+		assert.Equal("12.30", target.Note)
+		assert.Equal([]vebben.Decimal{{Units: 1}, {Units: 250, Scale: 2}},
+			target.Extras)
+	}
+}
+
+func Test_FormInput_DecimalTypes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	html, _ := vebben.FormInput(vebben.OptionalFormSpec("price", "decimal",
+		"scale=2;range=0-"), vebben.Decimal{Units: 1230, Scale: 2}, nil)
+	assert.Equal(`<input name="price" id="price" type="number" step="0.01" `+
+		`min="0" value="12.30">`, string(html))
+
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("rate", "decimal",
+		"range=0-1 step=0.25"), nil, nil)
+	assert.Equal(`<input name="rate" id="rate" type="number" step="0.25" `+
+		`min="0" max="1" value="">`, string(html))
+
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("x", "decimal"),
+		nil, nil)
+	assert.Equal(`<input name="x" id="x" type="number" step="any" `+
+		`value="">`, string(html))
+
+	eur := vebben.Money{Amount: vebben.Decimal{Units: 500, Scale: 2},
+		Currency: "EUR"}
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("fee", "money",
+		"currency=JPY"), nil, nil)
+	assert.Equal(`<input name="fee" id="fee" type="number" step="1" `+
+		`value="">`, string(html))
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("fee", "money",
+		"currency=EUR"), eur, nil)
+	assert.Equal(`<input name="fee" id="fee" type="number" step="0.01" `+
+		`value="5.00">`, string(html))
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("fee", "money"),
+		eur, nil)
+	assert.Equal(`<input name="fee" id="fee" type="text" `+
+		`value="5.00 EUR">`, string(html))
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("fee", "money"),
+		vebben.Money{}, nil)
+	assert.Equal(`<input name="fee" id="fee" type="text" value="">`,
+		string(html))
+}
+
+func Test_JSONSchema_DecimalTypes(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("a", "decimal"),
+		vebben.OptionalFormSpec("b", "decimal", "scale=2;range=>0 <=99.99"),
+		vebben.OptionalFormSpec("c", "decimal", "range=0- step=0.05"),
+		vebben.OptionalFormSpec("d", "money", "currency=JPY"),
+		vebben.OptionalFormSpec("e", "money"),
+	}
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema",`+
+		`"properties":{`+
+		`"a":{"type":"number"},`+
+		`"b":{"exclusiveMinimum":0,"maximum":99.99,"multipleOf":0.01,"type":"number"},`+
+		`"c":{"minimum":0,"multipleOf":0.05,"type":"number"},`+
+		`"d":{"multipleOf":1,"type":"number"},`+
+		`"e":{"type":"string"}},`+
+		`"type":"object"}`, schemaJSON(t, specs))
+}
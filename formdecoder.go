@@ -0,0 +1,229 @@
+// formdecoder.go -- form decoders with their own settings.
+// --------------
+
+package vebben
+
+import (
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Decoder decodes forms as DecodeForm does, but with its own settings
+// instead of the package-level ones, so that e.g. handlers for different
+// time zones can decode forms side by side:
+//
+//   dec := vebben.NewDecoder()
+//   dec.Location, _ = time.LoadLocation("America/New_York")
+//   dec.DateFormats = []string{"01/02/2006", "2006-01-02"}
+//   err := dec.DecodeForm(r, specs, &order)
+//
+// A Decoder is safe for concurrent use by multiple goroutines, provided its
+// settings are not changed once it is in use.  Decoders made with
+// NewDecoder, unlike those made as struct literals, try the date and
+// datetime formats that matched recently first, and skip those that can
+// not match the input.
+type Decoder struct {
+	// TrimSpace controls whether values are whitespace-trimmed before any
+	// processing occurs.
+	TrimSpace bool
+
+	// Location is the location (time zone) of dates and times; if nil, UTC
+	// is used.
+	Location *time.Location
+
+	// DateFormats and DateTimeFormats are the layouts accepted for dates
+	// and datetimes, in the order they are tried; see the package-level
+	// DateFormats and DateTimeFormats.
+	DateFormats     []string
+	DateTimeFormats []string
+
+	// NumberLocale and StrictNumbers control the numbers accepted by the
+	// numeric types; see the package-level NumberLocale and StrictNumbers.
+	NumberLocale  language.Tag
+	StrictNumbers bool
+
+	// RelativeDates controls whether relative dates such as "tomorrow" are
+	// accepted; see the package-level RelativeDates.  They are relative to
+	// the time returned by Clock, or by time.Now if it is nil.
+	RelativeDates bool
+	Clock         func() time.Time
+
+	// URLSchemes, DomainsToASCII and LowercaseEmails control the values
+	// accepted by the network types; see the package-level ones.
+	URLSchemes      []string
+	DomainsToASCII  bool
+	LowercaseEmails bool
+
+	// Types is the TypeRegistry of FormSpecs whose Types is nil, in place
+	// of DefaultTypes if set.  Such FormSpecs are decoded as copies
+	// initialized with Types, so that their types, limits and standard
+	// Validators all come from there.
+	Types *TypeRegistry
+
+	// Helpers for matching the formats:
+	matchers *dateMatcherCaches
+}
+
+// NewDecoder returns a new Decoder with the current package-level settings,
+// i.e. DecodeFormTrimSpace, FormValueTimeLocation, DateFormats,
+// DateTimeFormats, NumberLocale, StrictNumbers, RelativeDates,
+// URLSchemes, DomainsToASCII and LowercaseEmails; its Types is nil, for
+// DefaultTypes.  The lists are copied, so later changes to the
+// package-level ones do not affect it.
+func NewDecoder() *Decoder {
+	d := packageDecoder()
+	d.DateFormats = append([]string(nil), DateFormats...)
+	d.DateTimeFormats = append([]string(nil), DateTimeFormats...)
+	d.URLSchemes = append([]string(nil), URLSchemes...)
+	d.matchers = &dateMatcherCaches{}
+	return d
+}
+
+// packageDecoder returns a Decoder using the package-level settings as they
+// are, for DecodeForm and FormSpec.Convert.
+func packageDecoder() *Decoder {
+	return &Decoder{
+		TrimSpace:       DecodeFormTrimSpace,
+		Location:        FormValueTimeLocation,
+		DateFormats:     DateFormats,
+		DateTimeFormats: DateTimeFormats,
+		NumberLocale:    NumberLocale,
+		StrictNumbers:   StrictNumbers,
+		RelativeDates:   RelativeDates,
+		URLSchemes:      URLSchemes,
+		DomainsToASCII:  DomainsToASCII,
+		LowercaseEmails: LowercaseEmails,
+		matchers:        packageDateMatchers,
+	}
+}
+
+// DecodeForm populates the target structure from the values of a submitted
+// form with the settings of the Decoder.  It is otherwise the same as the
+// package-level DecodeForm; see there.
+func (d *Decoder) DecodeForm(f FormValuer, specs []*FormSpec,
+	target interface{}, opts ...DecodeOption) error {
+
+	if err := checkFormTarget(target); err != nil {
+		return err
+	}
+	config := newDecodeConfig(opts)
+	if err := config.checkCSRF(f); err != nil {
+		return err
+	}
+	fd := &formDecoding{
+		dec:    d,
+		f:      f,
+		config: config,
+		specs:  map[string]*FormSpec{},
+	}
+	fd.decodeSpecs(specs, "", "")
+	fd.runRules()
+	if len(fd.errors) == 0 {
+		for _, res := range fd.results {
+			err := assignFormValue(target, res.path, res.value)
+			if err != nil {
+				fe := res.spec.FieldError(CodeAssignment, nil)
+				fe.Key = res.key
+				fe.Err = err
+				fd.errors = append(fd.errors, fe)
+			}
+		}
+	}
+	fd.config.localize(fd.errors)
+	fd.fillState()
+	if len(fd.errors) > 0 {
+		return &MultiError{fd.errors}
+	}
+
+	return nil
+}
+
+// Convert converts raw as the FormSpec's Convert method does, but with the
+// settings of the Decoder.
+func (d *Decoder) Convert(fs *FormSpec, raw string) (interface{}, error) {
+
+	fs = d.spec(fs)
+	t := fs.specType()
+	var val interface{}
+	var ok bool
+	if t.decode != nil {
+		val, ok = t.decode(d, raw)
+	} else {
+		val, ok = t.converter(raw)
+	}
+	if !ok {
+		// TODO: consider bubbling up errors for things like int out of range.
+		fe := fs.FieldError(CodeConversion,
+			map[string]interface{}{"type": fs.itemType()})
+		fe.Input = raw
+		return nil, fe
+	}
+	if t.finish != nil && raw != "" {
+		val, err := t.finish(d, fs, val)
+		if err != nil {
+			return nil, fs.asFieldError(err, raw)
+		}
+		return val, nil
+	}
+	return val, nil
+
+}
+
+// spec returns fs, or if it has no Types and the Decoder has, its copy
+// initialized with the Types of the Decoder.
+func (d *Decoder) spec(fs *FormSpec) *FormSpec {
+	if fs.Types != nil || d.Types == nil {
+		return fs
+	}
+	return d.Types.specFor(fs)
+}
+
+// location returns the Location of the Decoder, or UTC if it is nil.
+func (d *Decoder) location() *time.Location {
+	if d.Location == nil {
+		return time.UTC
+	}
+	return d.Location
+}
+
+// dateMatcher returns the dateMatcher for the DateFormats, or nil if the
+// Decoder has no matcher caches, as when it is a struct literal rather than
+// made by NewDecoder or for the package-level settings.
+func (d *Decoder) dateMatcher() *dateMatcher {
+	if d.matchers == nil {
+		return nil
+	}
+	return d.matchers.dates.get(d.DateFormats)
+}
+
+// dateTimeMatcher returns the dateMatcher for the DateTimeFormats, or nil
+// if the Decoder has no matcher caches; see dateMatcher.
+func (d *Decoder) dateTimeMatcher() *dateMatcher {
+	if d.matchers == nil {
+		return nil
+	}
+	return d.matchers.dateTimes.get(d.DateTimeFormats)
+}
+
+// parseTime returns raw as a time.Time, parsed with the first matching
+// layout in the Decoder's location, or false if none matches.  The layouts
+// are matched by m unless it is nil.
+func (d *Decoder) parseTime(raw string, layouts []string,
+	m *dateMatcher) (interface{}, bool) {
+
+	loc := d.location()
+	if m != nil {
+		if t, ok := m.parse(raw, loc); ok {
+			return t, true
+		}
+		return nil, false
+	}
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, raw, loc)
+		if err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
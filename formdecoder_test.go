@@ -0,0 +1,226 @@
+// formdecoder_test.go
+// -------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"net/mail"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_NewDecoder_Defaults(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dec := vebben.NewDecoder()
+	assert.True(dec.TrimSpace)
+	assert.Equal(vebben.FormValueTimeLocation, dec.Location)
+	assert.Equal(vebben.DateFormats, dec.DateFormats)
+	assert.Equal(vebben.DateTimeFormats, dec.DateTimeFormats)
+	assert.Equal(language.Und, dec.NumberLocale)
+	assert.False(dec.StrictNumbers)
+	assert.Equal(vebben.URLSchemes, dec.URLSchemes)
+	assert.True(dec.DomainsToASCII)
+	assert.False(dec.LowercaseEmails)
+	assert.Nil(dec.Types)
+
+	// The lists are copies.
+	dec.DateFormats[0] = "nope"
+	assert.NotEqual("nope", vebben.DateFormats[0])
+	dec.URLSchemes[0] = "nope"
+	assert.NotEqual("nope", vebben.URLSchemes[0])
+}
+
+func Test_Decoder_NetworkOptions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("email", "email"),
+		vebben.OptionalFormSpec("home", "url"),
+		vebben.OptionalFormSpec("feed", "url", "schemes=https"),
+	}
+	form := vebben.URLValues{
+		"email": {"Joe@Bücher.Example"},
+		"home":  {"ftp://bücher.example/"},
+		"feed":  {"https://example.com/"},
+	}
+
+	dec := vebben.NewDecoder()
+	dec.URLSchemes = []string{"ftp"}
+	dec.DomainsToASCII = false
+	dec.LowercaseEmails = true
+
+	target := map[string]interface{}{}
+	if assert.Nil(dec.DecodeForm(form, specs, &target)) {
+		assert.Equal("joe@bücher.example",
+			target["email"].(*mail.Address).Address)
+		assert.Equal("bücher.example", target["home"].(*url.URL).Host)
+	}
+
+	// The package-level settings are unchanged.
+	err := vebben.DecodeForm(form, specs, &map[string]interface{}{})
+	if assert.Error(err) {
+		fes := err.(*vebben.MultiError).FieldErrors()
+		if assert.Len(fes, 1) {
+			assert.Equal("home", fes[0].Key)
+			assert.Equal(vebben.CodeBadScheme, fes[0].Code)
+		}
+	}
+	v, err := specs[0].Convert("Joe@Bücher.Example")
+	if assert.Nil(err) {
+		assert.Equal("Joe@xn--bcher-kva.example", v.(*mail.Address).Address)
+	}
+}
+
+func Test_Decoder_Types(t *testing.T) {
+
+	assert := assert.New(t)
+
+	reg := vebben.NewTypeRegistry()
+	reg.Add("string", upperConverter,
+		func(fs *vebben.FormSpec, v interface{}) error {
+			if v == "NOPE" {
+				return fs.FieldError(vebben.CodeInvalid, nil)
+			}
+			return nil
+		})
+	custom := vebben.OptionalFormSpec("custom", "string")
+	custom.SetValidator(func(fs *vebben.FormSpec, v interface{}) error {
+		return nil
+	})
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("name", "string", "2"),
+		custom,
+		reg.Clone().OptionalFormSpec("own", "string"),
+	}
+	form := vebben.URLValues{
+		"name":   {"nope"},
+		"custom": {"nope"},
+		"own":    {"nope"},
+	}
+
+	// The same specs with other types.
+	dec := vebben.NewDecoder()
+	dec.Types = reg
+	err := dec.DecodeForm(form, specs, &map[string]interface{}{})
+	if assert.Error(err) {
+		fes := err.(*vebben.MultiError).FieldErrors()
+		if assert.Len(fes, 2) {
+			assert.Equal("name", fes[0].Key)
+			assert.Equal(vebben.CodeInvalid, fes[0].Code)
+			assert.Equal("own", fes[1].Key)
+		}
+	}
+	form["name"] = []string{"ok"}
+	form["own"] = []string{"ok"}
+	target := map[string]interface{}{}
+	if assert.Nil(dec.DecodeForm(form, specs, &target)) {
+		assert.Equal(map[string]interface{}{
+			"name": "OK", "custom": "NOPE", "own": "OK"}, target)
+	}
+
+	// And with the default types.
+	target = map[string]interface{}{}
+	if assert.Nil(vebben.NewDecoder().DecodeForm(form, specs, &target)) {
+		assert.Equal("ok", target["name"])
+		assert.Equal("nope", target["custom"])
+	}
+}
+
+func Test_Decoder_DecodeForm_Settings(t *testing.T) {
+
+	assert := assert.New(t)
+
+	type Booking struct {
+		Name  string    `vebben:",,limit=2-"`
+		Day   time.Time `vebben:"day,date"`
+		Price float64   `vebben:""`
+	}
+	specs := vebben.MustFormSpecsFor(Booking{})
+	form := vebben.URLValues{
+		"Name":  {" Al "},
+		"day":   {"04/05/2023"},
+		"Price": {"1 234,5"},
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if !assert.Nil(err) {
+		return
+	}
+	dec := vebben.NewDecoder()
+	dec.TrimSpace = false
+	dec.Location = ny
+	dec.DateFormats = []string{"01/02/2006"}
+	dec.NumberLocale = language.Hungarian
+
+	target := &Booking{}
+	if assert.Nil(dec.DecodeForm(form, specs, target)) {
+		assert.Equal(&Booking{
+			Name:  " Al ",
+			Day:   time.Date(2023, 4, 5, 0, 0, 0, 0, ny),
+			Price: 1234.5,
+		}, target)
+	}
+
+	// The package-level settings are unchanged.
+	err = vebben.DecodeForm(form, specs, &Booking{})
+	if assert.Error(err) {
+		fe := err.(*vebben.MultiError).FieldErrors()[0]
+		assert.Equal("Price", fe.Key)
+		assert.Equal(vebben.CodeConversion, fe.Code)
+	}
+}
+
+func Test_Decoder_Convert_NilLocation(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dec := vebben.NewDecoder()
+	dec.Location = nil
+	v, err := dec.Convert(vebben.OptionalFormSpec("x", "datetime"),
+		"2023-04-05 06:07")
+	if assert.Nil(err) {
+		assert.Equal(time.Date(2023, 4, 5, 6, 7, 0, 0, time.UTC), v)
+	}
+}
+
+func Test_Decoder_Concurrent(t *testing.T) {
+
+	assert := assert.New(t)
+
+	utc := vebben.NewDecoder()
+	utc.Location = time.UTC
+	tokyo := vebben.NewDecoder()
+	tokyo.Location = time.FixedZone("JST", 9*3600)
+
+	specs := []*vebben.FormSpec{vebben.RequiredFormSpec("at", "datetime")}
+	form := vebben.URLValues{"at": {"2023-04-05 06:07"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for _, dec := range []*vebben.Decoder{utc, tokyo} {
+			wg.Add(1)
+			go func(dec *vebben.Decoder) {
+				defer wg.Done()
+				target := map[string]interface{}{}
+				if assert.Nil(dec.DecodeForm(form, specs, &target)) {
+					assert.Equal(time.Date(2023, 4, 5, 6, 7, 0, 0,
+						dec.Location), target["at"])
+				}
+			}(dec)
+		}
+	}
+	wg.Wait()
+}
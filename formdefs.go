@@ -0,0 +1,335 @@
+// formdefs.go -- FormSpecs defined in JSON and YAML files.
+// -----------
+
+package vebben
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// FormSpecFileError describes a problem in a FormSpec definition file,
+// with the line on which it occurs if known.
+type FormSpecFileError struct {
+	File string
+	Line int // zero if unknown
+	Msg  string
+}
+
+// Error returns the file, line and message in the usual compiler style.
+func (e *FormSpecFileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return e.File + ": " + e.Msg
+}
+
+// LoadFormSpecs reads named sets of FormSpecs from the definition files in
+// fsys matching pattern, which may be a single file name; fsys may be an
+// embed.FS so that the definitions are built into the binary, or e.g. an
+// os.DirFS:
+//
+//   //go:embed forms/*.yaml
+//   var formDefs embed.FS
+//
+//   var formSpecs = vebben.MustLoadFormSpecs(formDefs, "forms/*.yaml")
+//
+// The sets of all the files are merged; a set name defined in more than one
+// file is an error.  See ParseFormSpecs for the file format.
+func LoadFormSpecs(fsys fs.FS, pattern string) (map[string][]*FormSpec,
+	error) {
+
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no FormSpec files match %q", pattern)
+	}
+	sets := map[string][]*FormSpec{}
+	from := map[string]string{}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		fileSets, err := ParseFormSpecs(name, data)
+		if err != nil {
+			return nil, err
+		}
+		for set, specs := range fileSets {
+			if other, ok := from[set]; ok {
+				return nil, &FormSpecFileError{File: name,
+					Msg: fmt.Sprintf("set %q already defined in %s", set, other)}
+			}
+			from[set] = name
+			sets[set] = specs
+		}
+	}
+	return sets, nil
+}
+
+// MustLoadFormSpecs is LoadFormSpecs, panicking on error, for use in
+// package variables.
+func MustLoadFormSpecs(fsys fs.FS, pattern string) map[string][]*FormSpec {
+	sets, err := LoadFormSpecs(fsys, pattern)
+	if err != nil {
+		panic(err.Error())
+	}
+	return sets
+}
+
+// ParseFormSpecs parses named sets of FormSpecs from the definition data,
+// whose file name is used in errors.  The data is YAML, of which JSON is a
+// subset, so JSON definitions work as well.  It maps set names to lists of
+// FormSpec definitions, e.g.:
+//
+//   signup:
+//     - key: email
+//       required: true
+//       limit: "re:^[^@]+@[^@]+$"
+//       name: Email
+//       messages:
+//         bad_format: "{name} is not an email address"
+//         hu:
+//           bad_format: "{name}: érvénytelen e-mail cím"
+//     - key: age
+//       type: int
+//       limit: 18-130
+//
+// The fields of a definition are:
+//
+//   key            // the Key (required)
+//   type           // the Type (default: string)
+//   required       // true to set Required
+//   limit          // the Limit
+//   name           // the Name (default: the key)
+//   sensitive      // true to set Sensitive
+//   min, max       // MinItems and MaxItems (slice types only)
+//   maxsize        // MaxSize (file types only)
+//   accept         // list of Accept types (file types only)
+//   requiredif     // list of RequiredIf Conditions, as in struct tags
+//   forbiddenif    // list of ForbiddenIf Conditions, as in struct tags
+//   group          // list of definitions (group types only)
+//   messages       // messages by error code, or by language and error code
+//
+// Messages given directly by code apply to all languages, and those given
+// under a language tag to that language; they are set as the Messages of
+// the FormSpec.
+//
+// Each FormSpec is initialized, and any problem, including those for which
+// Init would panic, results in a FormSpecFileError.
+func ParseFormSpecs(name string, data []byte) (map[string][]*FormSpec,
+	error) {
+
+	p := &formSpecParser{file: name}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, p.yamlError(err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, p.errorf(0, "no FormSpec sets defined")
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, p.errorf(root.Line, "expected a map of FormSpec sets")
+	}
+	sets := map[string][]*FormSpec{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		if _, ok := sets[k.Value]; ok {
+			return nil, p.errorf(k.Line, "duplicate set %q", k.Value)
+		}
+		specs, err := p.specList(v)
+		if err != nil {
+			return nil, err
+		}
+		sets[k.Value] = specs
+	}
+	return sets, nil
+}
+
+// formSpecParser builds FormSpecs from YAML nodes.
+type formSpecParser struct {
+	file string
+}
+
+func (p *formSpecParser) errorf(line int, format string,
+	args ...interface{}) error {
+
+	return &FormSpecFileError{File: p.file, Line: line,
+		Msg: fmt.Sprintf(format, args...)}
+}
+
+var yamlLineError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlError converts a YAML syntax error.
+func (p *formSpecParser) yamlError(err error) error {
+	if m := yamlLineError.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return p.errorf(line, "%s", m[2])
+	}
+	return p.errorf(0, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// specList returns the FormSpecs defined in a sequence node.
+func (p *formSpecParser) specList(n *yaml.Node) ([]*FormSpec, error) {
+	if n.Kind != yaml.SequenceNode {
+		return nil, p.errorf(n.Line, "expected a list of FormSpecs")
+	}
+	specs := []*FormSpec{}
+	for _, item := range n.Content {
+		spec, err := p.spec(item)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// spec returns the initialized FormSpec defined in a mapping node.
+func (p *formSpecParser) spec(n *yaml.Node) (spec *FormSpec, err error) {
+
+	if n.Kind != yaml.MappingNode {
+		return nil, p.errorf(n.Line, "expected a FormSpec definition")
+	}
+	spec = &FormSpec{Type: "string"}
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if seen[k.Value] {
+			return nil, p.errorf(k.Line, "duplicate field %q", k.Value)
+		}
+		seen[k.Value] = true
+		if err := p.field(spec, k.Value, v); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(spec.Key) == "" {
+		return nil, p.errorf(n.Line, "missing key")
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Key
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			spec, err = nil, p.errorf(n.Line, "%v", r)
+		}
+	}()
+	spec.Init()
+	return spec, nil
+}
+
+// field sets the field named k of spec from the value node v.
+func (p *formSpecParser) field(spec *FormSpec, k string, v *yaml.Node) error {
+
+	var err error
+	switch k {
+	case "key":
+		err = p.decode(v, &spec.Key)
+	case "type":
+		err = p.decode(v, &spec.Type)
+	case "required":
+		err = p.decode(v, &spec.Required)
+	case "limit":
+		err = p.decode(v, &spec.Limit)
+	case "name":
+		err = p.decode(v, &spec.Name)
+	case "sensitive":
+		err = p.decode(v, &spec.Sensitive)
+	case "min":
+		err = p.decode(v, &spec.MinItems)
+	case "max":
+		err = p.decode(v, &spec.MaxItems)
+	case "maxsize":
+		err = p.decode(v, &spec.MaxSize)
+	case "accept":
+		err = p.decode(v, &spec.Accept)
+	case "requiredif", "forbiddenif":
+		var conds []string
+		if err := p.decode(v, &conds); err != nil {
+			return err
+		}
+		for idx, s := range conds {
+			c, ok := parseCondition(s)
+			if !ok {
+				return p.errorf(v.Content[idx].Line, "bad condition: %q", s)
+			}
+			if k == "requiredif" {
+				spec.RequiredIf = append(spec.RequiredIf, c)
+			} else {
+				spec.ForbiddenIf = append(spec.ForbiddenIf, c)
+			}
+		}
+	case "group":
+		spec.Group, err = p.specList(v)
+	case "messages":
+		spec.Messages, err = p.messages(v)
+	default:
+		return p.errorf(v.Line, "unknown field %q", k)
+	}
+	return err
+}
+
+// decode decodes the value node v into target.
+func (p *formSpecParser) decode(v *yaml.Node, target interface{}) error {
+	if v.Kind == yaml.SequenceNode {
+		if _, ok := target.(*[]string); !ok {
+			return p.errorf(v.Line, "unexpected list")
+		}
+		for _, item := range v.Content {
+			if item.Kind != yaml.ScalarNode {
+				return p.errorf(item.Line, "expected a string")
+			}
+		}
+	}
+	if err := v.Decode(target); err != nil {
+		msg := err.Error()
+		if te, ok := err.(*yaml.TypeError); ok && len(te.Errors) > 0 {
+			msg = te.Errors[0]
+			if m := yamlTypeError.FindStringSubmatch(msg); m != nil {
+				msg = m[1]
+			}
+		}
+		return p.errorf(v.Line, "%s", msg)
+	}
+	return nil
+}
+
+var yamlTypeError = regexp.MustCompile(`^line \d+: (.*)$`)
+
+// messages returns a Catalog of the messages in the mapping node v, which
+// maps codes to messages for all languages, or language tags to maps of
+// codes to messages.
+func (p *formSpecParser) messages(v *yaml.Node) (*Catalog, error) {
+
+	if v.Kind != yaml.MappingNode {
+		return nil, p.errorf(v.Line, "expected a map of messages")
+	}
+	c := NewCatalog()
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		k, m := v.Content[i], v.Content[i+1]
+		if m.Kind == yaml.ScalarNode {
+			c.Set(language.Und, k.Value, m.Value)
+			continue
+		}
+		tag, err := language.Parse(k.Value)
+		if err != nil {
+			return nil, p.errorf(k.Line, "bad language %q", k.Value)
+		}
+		var msgs map[string]string
+		if err := p.decode(m, &msgs); err != nil {
+			return nil, err
+		}
+		c.SetMessages(tag, msgs)
+	}
+	return c, nil
+}
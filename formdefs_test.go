@@ -0,0 +1,213 @@
+// formdefs_test.go
+// ----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+var formDefsYAML = `# Forms maintained by the shop team.
+signup:
+  - key: email
+    required: true
+    limit: "re:^[^@]+@[^@]+$"
+    name: Email
+    messages:
+      bad_format: "{name} is not an email address"
+      hu:
+        bad_format: "{name}: érvénytelen e-mail cím"
+  - key: password
+    required: true
+    sensitive: true
+    limit: 8-64
+  - key: age
+    type: int
+    limit: 18-130
+  - key: tags
+    type: "[]string"
+    max: 3
+  - key: company
+    requiredif: ["type:company"]
+    forbiddenif: ["!type"]
+  - key: address
+    type: group
+    group:
+      - key: city
+        required: true
+`
+
+var formDefsJSON = `{
+	"contact": [
+		{"key": "subject", "required": true, "limit": 3, "name": "Subject"},
+		{"key": "photo", "type": "file", "maxsize": 1000,
+		 "accept": ["image/png", "image/gif"]}
+	]
+}`
+
+func Test_LoadFormSpecs(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"forms/signup.yaml":  {Data: []byte(formDefsYAML)},
+		"forms/contact.json": {Data: []byte(formDefsJSON)},
+	}
+	sets, err := vebben.LoadFormSpecs(fsys, "forms/*")
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Len(sets, 2)
+
+	signup := sets["signup"]
+	if assert.Len(signup, 6) {
+		assert.Equal("email", signup[0].Key)
+		assert.Equal("string", signup[0].Type)
+		assert.True(signup[0].Required)
+		assert.Equal("Email", signup[0].Name)
+		assert.True(signup[1].Sensitive)
+		assert.Equal("password", signup[1].Name)
+		assert.Equal("int", signup[2].Type)
+		assert.Equal("18-130", signup[2].Limit)
+		assert.Equal(3, signup[3].MaxItems)
+		assert.Equal([]vebben.Condition{vebben.When("type", "company")},
+			signup[4].RequiredIf)
+		assert.Equal([]vebben.Condition{vebben.Unless("type")},
+			signup[4].ForbiddenIf)
+		assert.Equal("city", signup[5].Group[0].Key)
+	}
+	contact := sets["contact"]
+	if assert.Len(contact, 2) {
+		assert.Equal("3", contact[0].Limit)
+		assert.Equal(int64(1000), contact[1].MaxSize)
+		assert.Equal([]string{"image/png", "image/gif"}, contact[1].Accept)
+	}
+
+	// The specs work:
+	form := vebben.URLValues{
+		"email":        {"nope"},
+		"password":     {"longenough"},
+		"age":          {"12"},
+		"address.city": {"Pécs"},
+	}
+	target := map[string]interface{}{}
+	err = vebben.DecodeForm(form, signup, &target)
+	if assert.Error(err) {
+		assert.Equal("Email is not an email address\nage is too low",
+			err.Error())
+	}
+	err = vebben.DecodeForm(form, signup, &target,
+		vebben.Language(language.Hungarian))
+	if assert.Error(err) {
+		assert.Equal("Email: érvénytelen e-mail cím\nage túl kicsi",
+			err.Error())
+	}
+}
+
+func Test_LoadFormSpecs_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"a.yaml": {Data: []byte("one:\n  - key: x\n")},
+		"b.yaml": {Data: []byte("two:\n  - key: x\none:\n  - key: y\n")},
+	}
+	_, err := vebben.LoadFormSpecs(fsys, "*.yaml")
+	assert.EqualError(err, `b.yaml: set "one" already defined in a.yaml`)
+
+	_, err = vebben.LoadFormSpecs(fsys, "*.json")
+	assert.EqualError(err, `no FormSpec files match "*.json"`)
+
+	_, err = vebben.LoadFormSpecs(fsys, "[")
+	assert.Error(err, "bad pattern")
+
+	testig.AssertPanicsWith(t, func() {
+		vebben.MustLoadFormSpecs(fsys, "*.json")
+	}, `no FormSpec files match "*.json"`, "Must panics")
+}
+
+func Test_ParseFormSpecs_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		data string
+		err  string
+	}{
+		{"", "f.yaml: no FormSpec sets defined"},
+		{"- a\n- b\n", "f.yaml:1: expected a map of FormSpec sets"},
+		{"one: x\n", "f.yaml:1: expected a list of FormSpecs"},
+		{"one:\n  - x\n", "f.yaml:2: expected a FormSpec definition"},
+		{"one: [\n  {key: x}\n", "f.yaml:2: did not find expected ',' or ']'"},
+		{"one:\n  - key: x\none:\n  - key: y\n", `f.yaml:3: duplicate set "one"`},
+		{"one:\n  - name: X\n", "f.yaml:2: missing key"},
+		{"one:\n  - key: x\n    key: y\n", `f.yaml:3: duplicate field "key"`},
+		{"one:\n  - key: x\n    requird: true\n", `f.yaml:3: unknown field "requird"`},
+		{"one:\n  - key: x\n    required: maybe\n",
+			"f.yaml:3: cannot unmarshal !!str `maybe` into bool"},
+		{"one:\n  - key: x\n    type: \"[]int\"\n    min: [1]\n",
+			"f.yaml:4: unexpected list"},
+		{"one:\n  - key: x\n    accept:\n      - {a: b}\n",
+			"f.yaml:4: expected a string"},
+		{"one:\n  - key: x\n    requiredif:\n      - y\n      - \":z\"\n",
+			`f.yaml:5: bad condition: ":z"`},
+		{"one:\n  - key: x\n    type: nonesuch\n",
+			"f.yaml:2: Unsupported FormSpec type: nonesuch"},
+		{"one:\n  - key: x\n    type: int\n    limit: abc\n",
+			`f.yaml:2: Bad range limit: bad clause "abc"`},
+		{"one:\n  - key: x\n    messages: hi\n",
+			"f.yaml:3: expected a map of messages"},
+		{"one:\n  - key: x\n    messages:\n      q-q-q-q: {a: b}\n",
+			`f.yaml:4: bad language "q-q-q-q"`},
+		{"one:\n  - key: g\n    type: group\n    group:\n      - type: int\n",
+			"f.yaml:5: missing key"},
+	} {
+		_, err := vebben.ParseFormSpecs("f.yaml", []byte(tc.data))
+		var fe *vebben.FormSpecFileError
+		if assert.True(errors.As(err, &fe), tc.data) {
+			assert.Equal(tc.err, err.Error(), tc.data)
+		}
+	}
+}
+
+func Test_FormSpec_Messages(t *testing.T) {
+
+	assert := assert.New(t)
+
+	spec := vebben.RequiredFormSpec("code", "string", "4", "Code")
+	spec.Messages = vebben.NewCatalog()
+	spec.Messages.Set(language.English, vebben.CodeRequired,
+		"Please enter the {name}")
+	spec.Messages.Set(language.German, vebben.CodeWrongLength,
+		"{name} muss 4 Zeichen haben")
+
+	fe := spec.FieldError(vebben.CodeRequired, nil)
+	assert.Equal("Please enter the Code", fe.Error())
+	assert.Equal("Code megadása kötelező", fe.Localize(language.Hungarian),
+		"catalog before spec English fallback")
+	assert.Equal("Please enter the Code", fe.Localize(language.German),
+		"spec English fallback without catalog message")
+
+	fe = spec.FieldError(vebben.CodeWrongLength, nil)
+	assert.Equal("Code muss 4 Zeichen haben", fe.Localize(language.German))
+	assert.Equal("Code has the wrong length", fe.Error())
+
+	// Custom validator errors get the spec messages too:
+	spec.Validator = func(*vebben.FormSpec, interface{}) error {
+		return &vebben.FieldError{Code: vebben.CodeRequired}
+	}
+	err := vebben.DecodeForm(vebben.URLValues{"code": {"abcd"}},
+		[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+	assert.EqualError(err, "Please enter the Code")
+}
@@ -0,0 +1,219 @@
+// formerrors.go -- structured errors for form fields.
+// -------------
+
+package vebben
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Error codes used in FieldErrors by DecodeForm and the standard
+// validators.  Custom validators may of course use their own codes.
+const (
+	CodeRequired    = "required"     // required value is missing
+	CodeConversion  = "conversion"   // could not convert to the spec Type
+	CodeWrongType   = "wrong_type"   // validator got the wrong Go type
+	CodeWrongLength = "wrong_length" // exact length limit failed
+	CodeTooShort    = "too_short"    // string length below range
+	CodeTooLong     = "too_long"     // string length above range
+	CodeTooLow      = "too_low"      // number below range
+	CodeTooHigh     = "too_high"     // number above range
+	CodeWrongStep   = "wrong_step"   // number not on a step of range
+	CodeTooPrecise  = "too_precise"  // decimal has too many decimal places
+	CodeBadFormat   = "bad_format"   // regexp limit failed
+	CodeNotInList   = "not_in_list"  // value list limit failed
+	CodeNotAllowed  = "not_allowed"  // value in a list of those not allowed
+	CodeTooFew      = "too_few"      // too few items in a slice
+	CodeTooMany     = "too_many"     // too many items in a slice
+	CodeTooLarge    = "too_large"    // file is larger than MaxSize
+	CodeInvalid     = "invalid"      // custom validator returned an error
+	CodeAssignment  = "assignment"   // value could not be assigned to target
+	CodeForbidden   = "forbidden"    // value given although ForbiddenIf holds
+
+	CodeBadContentType = "bad_content_type" // file type is not accepted
+	CodeBadFilename    = "bad_filename"     // file name is not acceptable
+	CodeBadScheme      = "bad_scheme"       // URL scheme is not accepted
+	CodeBadCurrency    = "bad_currency"     // money currency is not accepted
+	CodeBadPrecision   = "bad_precision"    // datetime is too precise
+)
+
+// Error codes used by the standard Rules.
+const (
+	CodeMismatch    = "mismatch"     // value differs from another (SameAs)
+	CodeNotAfter    = "not_after"    // value not after another (After)
+	CodeOneRequired = "one_required" // all of a set are empty (AtLeastOne)
+)
+
+// FieldError describes a validation failure for a single FormSpec, with a
+// stable Code suitable for machine processing.  The Params hold the limit
+// parameters relevant to the Code, e.g. "min" and "max" for CodeTooLow and
+// CodeTooHigh; and Input holds the raw input, if any.
+//
+// For slice types, errors in individual items have the item's raw Input.
+//
+// The message returned by Error is taken from a Catalog in the language
+// Lang, which DecodeForm sets according to its options; see DecodeOption.
+type FieldError struct {
+	Key    string
+	Name   string
+	Code   string
+	Params map[string]interface{}
+	Input  string
+	Err    error        // underlying error, if any
+	Lang   language.Tag // message language; Und means English
+
+	catalog  *Catalog
+	messages *Catalog // from the FormSpec
+}
+
+// FieldError returns a new FieldError for the FormSpec with the given code
+// and params, which may be nil.  This is intended for use by Validator
+// functions; DecodeForm fills in the Input.
+func (fs *FormSpec) FieldError(code string,
+	params map[string]interface{}) *FieldError {
+
+	return &FieldError{
+		Key:      fs.Key,
+		Name:     fs.Name,
+		Code:     code,
+		Params:   params,
+		messages: fs.Messages,
+	}
+}
+
+// Error implements the error interface for FieldError, returning the
+// message for the Code in the FieldError's Lang.  The Catalog is the one
+// used by DecodeForm, or the DefaultCatalog.
+func (e *FieldError) Error() string {
+	return e.Localize(e.Lang)
+}
+
+// Localize returns the message for the FieldError in language tag.  The
+// Messages of its FormSpec take precedence over the Catalog, except that
+// their English fallback is only used if the Catalog has no message in
+// the language either.
+func (e *FieldError) Localize(tag language.Tag) string {
+	c := e.catalog
+	if c == nil {
+		c = DefaultCatalog
+	}
+	if e.messages != nil {
+		if msg, ok := e.messages.lookup(tag, e.Code); ok {
+			return e.expand(msg)
+		}
+		if _, ok := c.lookup(tag, e.Code); !ok {
+			if msg, ok := e.messages.Message(tag, e.Code); ok {
+				return e.expand(msg)
+			}
+		}
+	}
+	return c.Format(tag, e)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// expand replaces the placeholders in msg.
+func (e *FieldError) expand(msg string) string {
+
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(msg, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(msg[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(msg[:start])
+		b.WriteString(e.placeholder(msg[start+1 : end]))
+		msg = msg[end+1:]
+	}
+	b.WriteString(msg)
+
+	return b.String()
+}
+
+func (e *FieldError) placeholder(name string) string {
+	switch name {
+	case "name":
+		return e.Name
+	case "key":
+		return e.Key
+	case "input":
+		return e.Input
+	}
+	if v, ok := e.Params[name]; ok {
+		return fmt.Sprint(v)
+	}
+	return "{" + name + "}"
+}
+
+// asFieldError returns err as a FieldError for spec fs, wrapping it with
+// CodeInvalid if it is not one already, and filling in missing data.
+func (fs *FormSpec) asFieldError(err error, input string) *FieldError {
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		fe = fs.FieldError(CodeInvalid, nil)
+		fe.Err = err
+	}
+	if fe.Key == "" {
+		fe.Key = fs.Key
+	}
+	if fe.Name == "" {
+		fe.Name = fs.Name
+	}
+	if fe.Input == "" {
+		fe.Input = input
+	}
+	if fe.messages == nil {
+		fe.messages = fs.Messages
+	}
+	return fe
+}
+
+// FieldErrors returns all the FieldErrors in the MultiError, in order.
+func (e *MultiError) FieldErrors() []*FieldError {
+	res := []*FieldError{}
+	for _, err := range e.Errors {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			res = append(res, fe)
+		}
+	}
+	return res
+}
+
+// ByKey returns the FieldErrors for the FormSpec Key key, in order.  The
+// result is empty if there are none.
+func (e *MultiError) ByKey(key string) []*FieldError {
+	res := []*FieldError{}
+	for _, fe := range e.FieldErrors() {
+		if fe.Key == key {
+			res = append(res, fe)
+		}
+	}
+	return res
+}
+
+// Keys returns the distinct keys of the FieldErrors, in order of first
+// appearance.
+func (e *MultiError) Keys() []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, fe := range e.FieldErrors() {
+		if !seen[fe.Key] {
+			seen[fe.Key] = true
+			res = append(res, fe.Key)
+		}
+	}
+	return res
+}
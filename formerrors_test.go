@@ -0,0 +1,174 @@
+// formerrors_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"errors"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FieldError_Error(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fe := &vebben.FieldError{
+		Key:    "foo",
+		Name:   "The Foo",
+		Code:   vebben.CodeConversion,
+		Params: map[string]interface{}{"type": "int"},
+		Input:  "x",
+	}
+	assert.Equal("The Foo could not be converted to int", fe.Error())
+	assert.Nil(fe.Unwrap())
+
+	fe.Code = "custom"
+	assert.Equal("The Foo: custom", fe.Error(), "unknown code")
+
+	inner := errors.New("inner message")
+	fe.Err = inner
+	assert.Equal("inner message", fe.Error(), "unknown code uses Err")
+	assert.True(errors.Is(fe, inner), "unwraps")
+
+	fe.Code = vebben.CodeTooLow
+	assert.Equal("The Foo is too low", fe.Error(), "known code ignores Err")
+
+}
+
+func Test_FormSpec_FieldError(t *testing.T) {
+
+	assert := assert.New(t)
+
+	spec := vebben.RequiredFormSpec("foo", "string", "", "The Foo")
+	fe := spec.FieldError(vebben.CodeBadFormat,
+		map[string]interface{}{"pattern": "^x$"})
+	assert.Equal("foo", fe.Key)
+	assert.Equal("The Foo", fe.Name)
+	assert.Equal("bad_format", fe.Code)
+	assert.Equal("^x$", fe.Params["pattern"])
+	assert.Equal("The Foo has the wrong format", fe.Error())
+
+}
+
+func Test_DecodeForm_FieldErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	custom := vebben.OptionalFormSpec("custom", "string")
+	custom.Validator = func(fs *vebben.FormSpec, v interface{}) error {
+		return errors.New("custom is no good")
+	}
+
+	f := vebben.URLValues{
+		"len":    []string{"abc"},
+		"short":  []string{"a"},
+		"long":   []string{"abcdef"},
+		"re":     []string{"abc"},
+		"list":   []string{"d"},
+		"low":    []string{"0"},
+		"high":   []string{"99"},
+		"ilist":  []string{"4"},
+		"flow":   []string{"0.5"},
+		"fhigh":  []string{"4.5"},
+		"conv":   []string{"x"},
+		"items":  []string{"1", "2", "9", "y"},
+		"custom": []string{"anything"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("req", "string"),
+		vebben.OptionalFormSpec("len", "string", "4"),
+		vebben.OptionalFormSpec("short", "string", "2-4"),
+		vebben.OptionalFormSpec("long", "string", "2-4"),
+		vebben.OptionalFormSpec("re", "string", "re:^\\d+$"),
+		vebben.OptionalFormSpec("list", "string", "a,b,c"),
+		vebben.OptionalFormSpec("low", "int", "1-10"),
+		vebben.OptionalFormSpec("high", "int64", "1-10"),
+		vebben.OptionalFormSpec("ilist", "int", "1,2,3"),
+		vebben.OptionalFormSpec("flow", "float", "1-4"),
+		vebben.OptionalFormSpec("fhigh", "float", "1.0-4.0"),
+		vebben.OptionalFormSpec("conv", "int"),
+		vebben.OptionalFormSpec("items", "[]int", "1-4"),
+		custom,
+	}
+
+	err := vebben.DecodeForm(f, specs, &SimpleType{})
+	if !assert.Error(err) {
+		return
+	}
+	me, ok := err.(*vebben.MultiError)
+	if !assert.True(ok, "is MultiError") {
+		return
+	}
+
+	exp := []struct {
+		key, code, input string
+		params           map[string]interface{}
+	}{
+		{"req", "required", "", nil},
+		{"len", "wrong_length", "abc", map[string]interface{}{"length": 4}},
+		{"short", "too_short", "a",
+			map[string]interface{}{"min": int64(2), "max": int64(4)}},
+		{"long", "too_long", "abcdef",
+			map[string]interface{}{"min": int64(2), "max": int64(4)}},
+		{"re", "bad_format", "abc",
+			map[string]interface{}{"pattern": "^\\d+$"}},
+		{"list", "not_in_list", "d",
+			map[string]interface{}{"list": []string{"a", "b", "c"}}},
+		{"low", "too_low", "0",
+			map[string]interface{}{"min": int64(1), "max": int64(10)}},
+		{"high", "too_high", "99",
+			map[string]interface{}{"min": int64(1), "max": int64(10)}},
+		{"ilist", "not_in_list", "4",
+			map[string]interface{}{"list": []int64{1, 2, 3}}},
+		{"flow", "too_low", "0.5",
+			map[string]interface{}{"min": 1.0, "max": 4.0}},
+		{"fhigh", "too_high", "4.5",
+			map[string]interface{}{"min": 1.0, "max": 4.0}},
+		{"conv", "conversion", "x",
+			map[string]interface{}{"type": "int"}},
+		{"items", "too_high", "9",
+			map[string]interface{}{"min": int64(1), "max": int64(4)}},
+		{"items", "conversion", "y",
+			map[string]interface{}{"type": "int"}},
+		{"custom", "invalid", "anything", nil},
+	}
+	fes := me.FieldErrors()
+	if !assert.Equal(len(exp), len(fes), "error count") {
+		return
+	}
+	for idx, e := range exp {
+		fe := fes[idx]
+		assert.Equal(e.key, fe.Key, "Key for %d", idx)
+		assert.Equal(e.code, fe.Code, "Code for %s", e.key)
+		assert.Equal(e.input, fe.Input, "Input for %s", e.key)
+		assert.Equal(e.params, fe.Params, "Params for %s", e.key)
+	}
+	assert.Equal("custom is no good", fes[len(fes)-1].Error())
+
+	assert.Equal([]string{"req", "len", "short", "long", "re", "list", "low",
+		"high", "ilist", "flow", "fhigh", "conv", "items", "custom"},
+		me.Keys(), "Keys")
+	assert.Equal(2, len(me.ByKey("items")), "two errors for items")
+	assert.Equal(0, len(me.ByKey("nope")), "no errors for nope")
+
+}
+
+func Test_MultiError_PlainErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	me := &vebben.MultiError{[]error{
+		errors.New("plain"),
+		&vebben.FieldError{Key: "foo", Code: "required"},
+	}}
+	assert.Equal(1, len(me.FieldErrors()), "plain errors ignored")
+	assert.Equal([]string{"foo"}, me.Keys())
+
+}
@@ -0,0 +1,178 @@
+// formfiles.go -- file uploads in forms.
+// ------------
+
+package vebben
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// FileFormValuer is a FormValuer that can also return the files uploaded
+// for a key, as needed for the "file" FormSpec type.  The http.Request does
+// not implement it, but is handled specially by DecodeForm, which reads the
+// files from its parsed MultipartForm.
+type FileFormValuer interface {
+	FormValuer
+	FormFiles(string) []*multipart.FileHeader
+}
+
+// MultipartForm adapts a multipart.Form to the MultiFormValuer and
+// FileFormValuer interfaces.
+type MultipartForm struct {
+	*multipart.Form
+}
+
+// FormValue returns the first value for key k, or an empty string.
+func (m MultipartForm) FormValue(k string) string {
+	if vals := m.Value[k]; len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// FormValues returns all values for key k.
+func (m MultipartForm) FormValues(k string) []string {
+	return m.Value[k]
+}
+
+// FormFiles returns all files for key k.
+func (m MultipartForm) FormFiles(k string) []*multipart.FileHeader {
+	return m.File[k]
+}
+
+// formFiles returns all the files available from f for key k, which is
+// nil unless f is a FileFormValuer or a multipart http.Request.
+func formFiles(f FormValuer, k string) []*multipart.FileHeader {
+	switch v := f.(type) {
+	case FileFormValuer:
+		return v.FormFiles(k)
+	case *http.Request:
+		v.FormValue(k) // parses the multipart form if that is not yet done.
+		if v.MultipartForm != nil {
+			return v.MultipartForm.File[k]
+		}
+	}
+	return nil
+}
+
+// SniffContentType returns the content type of the uploaded file fh as
+// detected by http.DetectContentType, without any parameters.
+func SniffContentType(fh *multipart.FileHeader) (string, error) {
+
+	file, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	ct := http.DetectContentType(buf[:n])
+	if idx := strings.IndexByte(ct, ';'); idx >= 0 {
+		ct = ct[:idx]
+	}
+	return ct, nil
+}
+
+// decodeFiles validates the files for a file-type FormSpec, returning a
+// *multipart.FileHeader, or for "[]file" a slice of them.  Any files after
+// the first are ignored for the "file" type.
+func (d *Decoder) decodeFiles(fs *FormSpec,
+	files []*multipart.FileHeader) (interface{}, []error) {
+
+	if !fs.isMulti() && len(files) > 1 {
+		files = files[:1]
+	}
+	items := []interface{}{}
+	errors := []error{}
+	for _, fh := range files {
+		if fs.Validator != nil {
+			if err := fs.Validator(fs, fh); err != nil {
+				errors = append(errors, fs.asFieldError(err, fh.Filename))
+				continue
+			}
+		}
+		items = append(items, fh)
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	if fs.isMulti() {
+		return fs.itemSlice(items)
+	}
+	if len(items) == 0 {
+		if fs.Required {
+			return nil, []error{fs.FieldError(CodeRequired, nil)}
+		}
+		return (*multipart.FileHeader)(nil), nil
+	}
+	return items[0], nil
+}
+
+// fileConverter always fails, as files can not be converted from strings.
+func fileConverter(raw string) (interface{}, bool) { return nil, false }
+
+func fileValidator(fs *FormSpec, v interface{}) error {
+
+	fh, ok := v.(*multipart.FileHeader)
+	if !ok || fh == nil {
+		return wrongTypeError(fs, "file", v)
+	}
+
+	if fs.MaxSize > 0 && fh.Size > fs.MaxSize {
+		return fs.FieldError(CodeTooLarge, map[string]interface{}{
+			"max":  fs.MaxSize,
+			"size": fh.Size,
+		})
+	}
+
+	// Filename rules first, then the Limit, which is like that of strings.
+	name := fh.Filename
+	if name == "" || name == "." || name == ".." ||
+		strings.IndexFunc(name, badFilenameRune) >= 0 {
+		return fs.FieldError(CodeBadFilename, nil)
+	}
+	if err := stringValidator(fs, name); err != nil {
+		return err
+	}
+
+	if len(fs.Accept) > 0 {
+		ct, err := SniffContentType(fh)
+		if err != nil {
+			return err
+		}
+		if !acceptsContentType(fs.Accept, ct) {
+			return fs.FieldError(CodeBadContentType, map[string]interface{}{
+				"type":   ct,
+				"accept": strings.Join(fs.Accept, ", "),
+			})
+		}
+	}
+
+	return nil
+}
+
+func badFilenameRune(r rune) bool {
+	return r == '/' || r == '\\' || unicode.IsControl(r)
+}
+
+// acceptsContentType returns true if ct matches one of the accept types.
+func acceptsContentType(accept []string, ct string) bool {
+	for _, a := range accept {
+		if a == ct {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") &&
+			strings.HasPrefix(ct, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+// formfiles_test.go
+// -----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+var pngBytes = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01")
+
+type UploadType struct {
+	Title  string                  `json:"title" vebben:",,required"`
+	Avatar *multipart.FileHeader   `json:"avatar" vebben:",,required,maxsize=100,accept=image/png,image/gif"`
+	Photos []*multipart.FileHeader `json:"photos" vebben:",,max=2,accept=image/*"`
+	Notes  *multipart.FileHeader   `json:"notes" vebben:",,limit=re:\\.txt$"`
+}
+
+type uploadFile struct {
+	key, name string
+	content   []byte
+}
+
+func uploadRequest(t *testing.T, values map[string]string,
+	files ...uploadFile) *http.Request {
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for k, v := range values {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		fw, err := w.CreateFormFile(f.key, f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(f.content)
+	}
+	w.Close()
+
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func Test_FormSpecsFor_Files(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := vebben.MustFormSpecsFor(UploadType{})
+	if assert.Equal(4, len(specs)) {
+		assert.Equal("file", specs[1].Type)
+		assert.Equal(int64(100), specs[1].MaxSize)
+		assert.Equal([]string{"image/png", "image/gif"}, specs[1].Accept)
+		assert.Equal("[]file", specs[2].Type)
+		assert.Equal([]string{"image/*"}, specs[2].Accept)
+	}
+
+}
+
+func Test_FormSpec_Init_FileLimitsRequireFile(t *testing.T) {
+
+	spec := &vebben.FormSpec{Key: "foo", Type: "string", MaxSize: 10}
+	assert.PanicsWithValue(t, "File limits do not apply to string",
+		func() { spec.Init() })
+
+	spec = &vebben.FormSpec{Key: "foo", Type: "file", MaxSize: -1}
+	assert.PanicsWithValue(t, "Bad file size limit: negative size",
+		func() { spec.Init() })
+
+}
+
+func Test_DecodeForm_Files(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r := uploadRequest(t, map[string]string{"title": "Hello"},
+		uploadFile{"avatar", "me.png", pngBytes},
+		uploadFile{"photos", "one.png", pngBytes},
+		uploadFile{"photos", "two.png", pngBytes},
+		uploadFile{"notes", "notes.txt", []byte("some notes")},
+	)
+
+	target := &UploadType{}
+	err := vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("Hello", target.Title)
+	if assert.NotNil(target.Avatar) {
+		assert.Equal("me.png", target.Avatar.Filename)
+	}
+	if assert.Equal(2, len(target.Photos)) {
+		assert.Equal("one.png", target.Photos[0].Filename)
+		assert.Equal("two.png", target.Photos[1].Filename)
+	}
+	if assert.NotNil(target.Notes) {
+		assert.Equal("notes.txt", target.Notes.Filename)
+		ct, err := vebben.SniffContentType(target.Notes)
+		assert.Nil(err)
+		assert.Equal("text/plain", ct)
+	}
+
+}
+
+func Test_DecodeForm_Files_Optional(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r := uploadRequest(t, map[string]string{"title": "Hello"},
+		uploadFile{"avatar", "me.png", pngBytes},
+	)
+
+	target := &UploadType{}
+	err := vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(target.Photos)
+	assert.Nil(target.Notes)
+
+}
+
+func Test_DecodeForm_Files_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	big := append(append([]byte{}, pngBytes...), make([]byte, 100)...)
+	r := uploadRequest(t, map[string]string{"title": "Hello"},
+		uploadFile{"avatar", "me.png", big},
+		uploadFile{"photos", "one.png", pngBytes},
+		uploadFile{"photos", "two.png", []byte("not an image")},
+		uploadFile{"notes", "notes.md", []byte("some notes")},
+	)
+
+	target := &UploadType{}
+	err := vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if !assert.Error(err) {
+		return
+	}
+	fes := err.(*vebben.MultiError).FieldErrors()
+	if assert.Equal(3, len(fes)) {
+		assert.Equal("avatar", fes[0].Key)
+		assert.Equal("too_large", fes[0].Code)
+		assert.Equal("me.png", fes[0].Input)
+		assert.Equal(int64(100), fes[0].Params["max"])
+		assert.Equal(int64(len(big)), fes[0].Params["size"])
+		assert.Equal("photos", fes[1].Key)
+		assert.Equal("bad_content_type", fes[1].Code)
+		assert.Equal("two.png", fes[1].Input)
+		assert.Equal("text/plain", fes[1].Params["type"])
+		assert.Equal("photos has an unsupported file type", fes[1].Error())
+		assert.Equal("notes", fes[2].Key)
+		assert.Equal("bad_format", fes[2].Code)
+	}
+
+	r = uploadRequest(t, map[string]string{"title": "Hello"},
+		uploadFile{"photos", "1.png", pngBytes},
+		uploadFile{"photos", "2.png", pngBytes},
+		uploadFile{"photos", "3.png", pngBytes},
+	)
+	err = vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if assert.Error(err) {
+		assert.Equal("avatar is required\nphotos has too many items",
+			err.Error())
+	}
+
+}
+
+func Test_DecodeForm_Files_BadFilename(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.MultipartForm{&multipart.Form{
+		Value: map[string][]string{"title": {"Hello"}},
+		File: map[string][]*multipart.FileHeader{
+			"avatar": {{Filename: "bad\x00name.png"}},
+			"photos": {{Filename: ".."}, {Filename: "a/b"}},
+		},
+	}}
+
+	target := &UploadType{}
+	err := vebben.DecodeForm(f, vebben.MustFormSpecsFor(target), target)
+	if !assert.Error(err) {
+		return
+	}
+	fes := err.(*vebben.MultiError).FieldErrors()
+	if assert.Equal(3, len(fes)) {
+		for _, fe := range fes {
+			assert.Equal("bad_filename", fe.Code)
+		}
+	}
+	assert.Equal("Hello", f.FormValue("title"))
+	assert.Equal([]string{"Hello"}, f.FormValues("title"))
+	assert.Equal("", f.FormValue("nope"))
+
+}
+
+func Test_DecodeForm_Files_NotMultipart(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r, _ := http.NewRequest("GET", "/?title=Hello", nil)
+	target := &UploadType{}
+	err := vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if assert.Error(err) {
+		assert.Equal("avatar is required", err.Error())
+	}
+
+}
@@ -0,0 +1,198 @@
+// formgroups.go -- nested and repeated groups of FormSpecs.
+// -------------
+
+package vebben
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyedFormValuer is a FormValuer that can also list the keys for which it
+// has values.  This is needed to find the rows of repeated groups with
+// gaps in their indexes, e.g. "items[0]" and "items[3]"; without it,
+// DecodeForm stops looking for rows at the first missing index.  The
+// http.Request, URLValues and MultipartForm are all handled as if they
+// implemented it.
+type KeyedFormValuer interface {
+	FormValuer
+	FormKeys() []string
+}
+
+// FormKeys returns the keys of the URLValues.
+func (v URLValues) FormKeys() []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// FormKeys returns the keys of the MultipartForm's values and files.
+func (m MultipartForm) FormKeys() []string {
+	return multipartFormKeys(m.Form)
+}
+
+func multipartFormKeys(m *multipart.Form) []string {
+	keys := make([]string, 0, len(m.Value)+len(m.File))
+	for k := range m.Value {
+		keys = append(keys, k)
+	}
+	for k := range m.File {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// formKeys returns the keys available from f, and false if f can not list
+// its keys.
+func formKeys(f FormValuer) ([]string, bool) {
+	switch v := f.(type) {
+	case KeyedFormValuer:
+		return v.FormKeys(), true
+	case *http.Request:
+		v.FormValue("") // parses the form if that is not yet done.
+		keys := URLValues(v.Form).FormKeys()
+		if v.MultipartForm != nil {
+			for k := range v.MultipartForm.File {
+				keys = append(keys, k)
+			}
+		}
+		return keys, true
+	}
+	return nil, false
+}
+
+// GroupFormSpec returns an initialized FormSpec of type "group" for key k,
+// which applies specs to the form keys prefixed with k and a dot.  For
+// example, with k "address" a spec with Key "city" applies to the form key
+// "address.city", which is assigned to the City field of the Address field
+// of the target.
+func GroupFormSpec(k string, specs []*FormSpec) *FormSpec {
+	f := &FormSpec{Key: k, Type: "group", Name: k, Group: specs}
+	f.Init()
+	return f
+}
+
+// RepeatedFormSpec returns an initialized FormSpec of type "[]group" for
+// key k, which applies specs to every row of a repeated group in the form,
+// with keys prefixed by k and the row index in brackets.  For example, with
+// k "items" a spec with Key "qty" applies to "items[0].qty", "items[1].qty"
+// and so on.  Rows with no values at all are skipped, and the remaining
+// rows are assigned to a slice in order, so that "items[0]" and "items[3]"
+// become the first and second items of the Items field of the target.
+//
+// The Required, MinItems and MaxItems properties apply to the number of
+// rows, and may be set on the returned FormSpec.  Errors within rows have
+// the full form key, e.g. "items[3].qty".
+func RepeatedFormSpec(k string, specs []*FormSpec) *FormSpec {
+	f := &FormSpec{Key: k, Type: "[]group", Name: k, Group: specs}
+	f.Init()
+	return f
+}
+
+// isGroup returns true if the FormSpec has a group type or slice thereof.
+func (fs *FormSpec) isGroup() bool {
+	t := fs.specType()
+	return t != nil && t.group
+}
+
+// groupConverter always fails, as groups can not be converted from strings.
+func groupConverter(raw string) (interface{}, bool) { return nil, false }
+
+// decodeGroup decodes the values for the group spec with the full form key
+// key and target key path.
+func (d *formDecoding) decodeGroup(spec *FormSpec, key, path string) {
+
+	if !spec.isMulti() {
+		d.decodeSpecs(spec.Group, key+".", path+".")
+		return
+	}
+
+	rows := d.groupRows(spec, key)
+	if err := spec.checkCount(len(rows)); err != nil {
+		d.addErrors(spec, key, []error{err})
+		return
+	}
+	for idx, row := range rows {
+		d.decodeSpecs(spec.Group,
+			fmt.Sprintf("%s[%d].", key, row),
+			fmt.Sprintf("%s[%d].", path, idx))
+	}
+}
+
+// maxGroupProbe limits the number of rows found by probing a FormValuer
+// that can not list its keys.
+const maxGroupProbe = 1000
+
+// groupRows returns the sorted indexes of the rows with values for the
+// repeated group spec with form key key.
+func (d *formDecoding) groupRows(spec *FormSpec, key string) []int {
+
+	keys, ok := formKeys(d.f)
+	if !ok {
+		rows := []int{}
+		for row := 0; row < maxGroupProbe; row++ {
+			if !d.probeRow(spec, fmt.Sprintf("%s[%d].", key, row)) {
+				break
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	present := map[int]bool{}
+	prefix := key + "["
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := k[len(prefix):]
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			continue
+		}
+		row, err := strconv.Atoi(rest[:end])
+		if err != nil || row < 0 || present[row] {
+			continue
+		}
+		if next := rest[end+1:]; next != "" && next[0] != '.' && next[0] != '[' {
+			continue
+		}
+		if d.hasValues(k) {
+			present[row] = true
+		}
+	}
+	rows := make([]int, 0, len(present))
+	for row := range present {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// hasValues returns true if the form has a nonempty value or a file for
+// key k.
+func (d *formDecoding) hasValues(k string) bool {
+	for _, v := range formValues(d.f, k) {
+		if strings.TrimSpace(v) != "" {
+			return true
+		}
+	}
+	return len(formFiles(d.f, k)) > 0
+}
+
+// probeRow returns true if the form has any values for the (non-group)
+// specs of the group spec, in the row with key prefix rowKey.
+func (d *formDecoding) probeRow(spec *FormSpec, rowKey string) bool {
+	for _, sub := range spec.Group {
+		if !sub.isGroup() && d.hasValues(rowKey+sub.Key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+// formgroups_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+type GroupAddress struct {
+	City string `json:"city" vebben:",,required,name=City"`
+	Zip  int    `json:"zip" vebben:",,limit=1000-9999,name=Zip"`
+}
+
+type GroupItem struct {
+	SKU  string            `json:"sku" vebben:",,required"`
+	Qty  int               `json:"qty" vebben:",,required,limit=1-10"`
+	Tags []string          `json:"tags" vebben:""`
+	Meta map[string]string `json:"meta"`
+}
+
+type GroupOrder struct {
+	Name    string        `json:"name" vebben:",,required"`
+	Address *GroupAddress `json:"address" vebben:",group"`
+	Items   []GroupItem   `json:"items" vebben:",[]group,required,max=3"`
+}
+
+func Test_FormSpec_Init_BadKeys(t *testing.T) {
+
+	for key, exp := range map[string]string{
+		"items[2":   `Bad FormSpec key: unclosed bracket in "items[2"`,
+		"items[].x": `Bad FormSpec key: bad brackets in "items[].x"`,
+		"items..x":  `Bad FormSpec key: empty name in "items..x"`,
+		"items]":    `Bad FormSpec key: missing dot in "items]"`,
+		".items":    `Bad FormSpec key: empty name in ".items"`,
+	} {
+		spec := &vebben.FormSpec{Key: key, Type: "string"}
+		testig.AssertPanicsWith(t, func() { spec.Init() }, exp,
+			"panic for "+key)
+	}
+
+}
+
+func Test_FormSpec_Init_GroupPanics(t *testing.T) {
+
+	spec := &vebben.FormSpec{Key: "foo", Type: "group"}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Group FormSpec requires Group specs", "no specs")
+
+	sub := []*vebben.FormSpec{vebben.OptionalFormSpec("bar", "string")}
+	spec = &vebben.FormSpec{Key: "foo", Type: "[]group", Group: sub,
+		Limit: "3"}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Limit does not apply to []group", "limit")
+
+	spec = &vebben.FormSpec{Key: "foo", Type: "string", Group: sub}
+	testig.AssertPanicsWith(t, func() { spec.Init() },
+		"Group specs do not apply to string", "not group")
+
+}
+
+func Test_DecodeForm_NestedKeys(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"address.city":      {"Budapest"},
+		"items[1].sku":      {"B-2"},
+		"items[0].meta.a":   {"x"},
+		"items[0].meta[b]":  {"y"},
+		"items[0].tags[]":   {"one", "two"},
+		"items[1].tags[1]":  {"second"},
+		"items[1].qty":      {"4"},
+		"items[1].nope.foo": {"ignored"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("address.city", "string"),
+		vebben.OptionalFormSpec("items[1].sku", "string"),
+		vebben.OptionalFormSpec("items[0].meta.a", "string"),
+		vebben.OptionalFormSpec("items[0].meta[b]", "string"),
+		vebben.OptionalFormSpec("items[0].tags[]", "[]string"),
+		vebben.OptionalFormSpec("items[1].tags[1]", "string"),
+		vebben.OptionalFormSpec("items[1].qty", "int"),
+		vebben.OptionalFormSpec("items[1].nope.foo", "string"),
+	}
+
+	target := &GroupOrder{}
+	err := vebben.DecodeForm(f, specs, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(target.Address) {
+		assert.Equal("Budapest", target.Address.City)
+	}
+	assert.Equal([]GroupItem{
+		{
+			Tags: []string{"one", "two"},
+			Meta: map[string]string{"a": "x", "b": "y"},
+		},
+		{SKU: "B-2", Qty: 4, Tags: []string{"", "second"}},
+	}, target.Items)
+
+}
+
+func Test_DecodeForm_NestedKeys_MapTarget(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"a.b":        {"1"},
+		"a.c[1].d":   {"2"},
+		"flat":       {"3"},
+		"list[0][x]": {"4"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("a.b", "int"),
+		vebben.OptionalFormSpec("a.c[1].d", "int"),
+		vebben.OptionalFormSpec("flat", "int"),
+		vebben.OptionalFormSpec("list[0][x]", "int"),
+	}
+
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(f, specs, &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": 1,
+			"c": []interface{}{nil, map[string]interface{}{"d": 2}},
+		},
+		"flat": 3,
+		"list": []interface{}{map[string]interface{}{"x": 4}},
+	}, target)
+
+}
+
+func Test_DecodeForm_NestedKeys_AssignmentErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"name.x":  {"1"},
+		"name[0]": {"2"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("name.x", "string"),
+		vebben.OptionalFormSpec("name[0]", "string"),
+	}
+
+	err := vebben.DecodeForm(f, specs, &GroupOrder{})
+	if assert.Error(err) {
+		assert.Equal(`name.x: can not set "x" in string`+"\n"+
+			`name[0]: can not index string with 0`, err.Error())
+	}
+
+}
+
+func Test_DecodeForm_Groups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"name":         {"Order"},
+		"address.city": {"Szeged"},
+		"address.zip":  {"6720"},
+		"items[0].sku": {"A-1"},
+		"items[0].qty": {"1"},
+		"items[2].sku": {" "},
+		"items[2].qty": {""},
+		"items[5].sku": {"C-3"},
+		"items[5].qty": {"3"},
+		"items[x].sku": {"ignored"},
+		"items[9]x":    {"ignored"},
+	}
+
+	target := &GroupOrder{}
+	err := vebben.DecodeForm(f, vebben.MustFormSpecsFor(target), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("Order", target.Name)
+	assert.Equal(&GroupAddress{City: "Szeged", Zip: 6720}, target.Address)
+	assert.Equal([]GroupItem{
+		{SKU: "A-1", Qty: 1},
+		{SKU: "C-3", Qty: 3},
+	}, target.Items)
+
+}
+
+func Test_DecodeForm_Groups_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"name":         {"Order"},
+		"address.zip":  {"99"},
+		"items[0].sku": {"A-1"},
+		"items[3].qty": {"11"},
+	}
+
+	err := vebben.DecodeForm(f, vebben.MustFormSpecsFor(GroupOrder{}),
+		&GroupOrder{})
+	if !assert.Error(err) {
+		return
+	}
+	me := err.(*vebben.MultiError)
+	assert.Equal([]string{"address.city", "address.zip", "items[0].qty",
+		"items[3].sku", "items[3].qty"}, me.Keys())
+	assert.Equal("City is required\nZip is too low\nqty is required\n"+
+		"sku is required\nqty is too high", err.Error())
+
+	f = vebben.URLValues{
+		"name":         {"Order"},
+		"address.city": {"Pécs"},
+		"address.zip":  {"7621"},
+	}
+	err = vebben.DecodeForm(f, vebben.MustFormSpecsFor(GroupOrder{}),
+		&GroupOrder{})
+	if assert.Error(err) {
+		assert.Equal([]string{"items"}, err.(*vebben.MultiError).Keys())
+		assert.Equal("items is required", err.Error())
+	}
+
+	f = vebben.URLValues{"name": {"Order"}, "address.zip": {"7621"}}
+	for _, idx := range []string{"0", "1", "2", "3"} {
+		f["items["+idx+"].sku"] = []string{"X"}
+		f["items["+idx+"].qty"] = []string{"1"}
+	}
+	err = vebben.DecodeForm(f, vebben.MustFormSpecsFor(GroupOrder{}),
+		&GroupOrder{})
+	if assert.Error(err) {
+		assert.Equal("address.city", err.(*vebben.MultiError).Keys()[0])
+		assert.Equal("City is required\nitems has too many items",
+			err.Error())
+	}
+
+}
+
+func Test_DecodeForm_Groups_Probing(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := &TestFormValuer{map[string]string{
+		"rows[0].sku": "A",
+		"rows[1].qty": "2",
+		"rows[3].sku": "not found",
+	}}
+	specs := []*vebben.FormSpec{
+		vebben.RepeatedFormSpec("rows", []*vebben.FormSpec{
+			vebben.OptionalFormSpec("sku", "string"),
+			vebben.OptionalFormSpec("qty", "int"),
+		}),
+	}
+
+	target := map[string][]GroupItem{}
+	err := vebben.DecodeForm(f, specs, &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]GroupItem{{SKU: "A"}, {Qty: 2}}, target["rows"])
+
+}
+
+func Test_DecodeForm_Groups_Request(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r := uploadRequest(t, map[string]string{
+		"name":         "Order",
+		"address.city": "Győr",
+		"address.zip":  "9021",
+		"items[7].sku": "Z",
+		"items[7].qty": "7",
+	})
+
+	target := &GroupOrder{}
+	err := vebben.DecodeForm(r, vebben.MustFormSpecsFor(target), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]GroupItem{{SKU: "Z", Qty: 7}}, target.Items)
+
+}
+
+func Test_GroupFormSpec(t *testing.T) {
+
+	assert := assert.New(t)
+
+	spec := vebben.GroupFormSpec("address", []*vebben.FormSpec{
+		vebben.RequiredFormSpec("city", "string"),
+	})
+	assert.Equal("group", spec.Type)
+	assert.Equal("address", spec.Name)
+	assert.Equal(1, len(spec.Group))
+
+	target := &GroupOrder{}
+	err := vebben.DecodeForm(vebben.URLValues{"address.city": {"Eger"}},
+		[]*vebben.FormSpec{spec}, target)
+	if assert.Nil(err) {
+		assert.Equal("Eger", target.Address.City)
+	}
+}
@@ -0,0 +1,433 @@
+// formhtml.go -- HTML form inputs rendered from FormSpecs.
+// -----------
+
+package vebben
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormInputErrorClass is the class set on inputs rendered by FormInput for
+// FormSpecs with errors.
+var FormInputErrorClass = "error"
+
+// FormInput returns an HTML input element for the FormSpec fs, with the
+// current value and error state, e.g. in a template using NewFuncMap:
+//
+//   {{ forminput .Specs.Email .Form.Email .Err }}
+//
+// The element depends on the Type and Limit of the FormSpec:
+//
+//   string         // text input, with minlength, maxlength and pattern
+//   int, int64     // number input, with step, min and max
+//   float          // number input, with step (default "any"), min and max
+//   bool           // checkbox with the value "true"
+//   date           // date input
+//   datetime       // datetime-local input, with step for precision limits
+//   email, url     // email or url input, as for strings
+//   file           // file input, with accept
+//   list limits    // select, with an empty option unless Required
+//
+// Slice types render as multiple selects if they have a list limit, as
+// multiple file inputs for files, and otherwise as one text input per
+// value.  Required specs have the required attribute.  Regexp limits are
+// rendered as the pattern attribute if they can be expressed as JavaScript
+// regular expressions, and are otherwise left to the server.
+//
+// The RequiredIf and ForbiddenIf Conditions are rendered as the
+// data-required-if and data-forbidden-if attributes, as a comma-separated
+// list in the syntax of Condition.String, for use by client-side code.
+//
+// The value may be the converted value or the raw input; slice types also
+// accept slices of either.  If err is a MultiError with FieldErrors for the
+// Key, or a FieldError for it, the element has the FormInputErrorClass and
+// aria-invalid="true".  The err is not declared as an error so that nil
+// values from template data maps can be passed.  Either may also be a
+// FormState, whose input and errors for the Key are then used:
+//
+//   {{ forminput .Specs.Email .State .State }}
+//
+// Sensitive strings are rendered as password inputs, and the values of
+// Sensitive specs are never rendered.
+//
+// Times are rendered in FormValueTimeLocation, as they are decoded by
+// DecodeForm; see Decoder.FormInput for other locations.
+//
+// Group types can not be rendered, and result in an error.
+func FormInput(fs *FormSpec, value interface{},
+	err interface{}) (template.HTML, error) {
+
+	return packageDecoder().FormInput(fs, value, err)
+}
+
+// FormInput returns an HTML input element as the package-level FormInput
+// does, but with times in the location of the Decoder, so that values it
+// decoded are rendered as they were input.  For templates, it may replace
+// the forminput function of NewFuncMap:
+//
+//   fm := vebben.NewFuncMap()
+//   fm["forminput"] = dec.FormInput
+func (d *Decoder) FormInput(fs *FormSpec, value interface{},
+	err interface{}) (template.HTML, error) {
+
+	if fs.isGroup() {
+		return "", errors.New("FormInput does not apply to " + fs.Type)
+	}
+
+	a := &htmlAttrs{}
+	a.add("name", fs.Key)
+	if !fs.isMulti() {
+		a.add("id", fs.Key)
+	}
+	if fs.Required {
+		a.flag("required")
+	}
+	if len(fs.RequiredIf) > 0 {
+		a.add("data-required-if", conditionList(fs.RequiredIf))
+	}
+	if len(fs.ForbiddenIf) > 0 {
+		a.add("data-forbidden-if", conditionList(fs.ForbiddenIf))
+	}
+	if state, ok := value.(*FormState); ok {
+		value = nil
+		if state != nil {
+			value = state.Input[fs.Key]
+		}
+	}
+	if fs.Sensitive {
+		value = nil
+	}
+	if hasFieldError(err, fs.Key) {
+		a.add("class", FormInputErrorClass)
+		a.add("aria-invalid", "true")
+	}
+
+	if options := fs.listOptions(); options != nil {
+		return fs.selectHTML(a, options, value, d.location()), nil
+	}
+	if fs.isFile() {
+		return fs.fileHTML(a), nil
+	}
+	if fs.isMulti() {
+		var b strings.Builder
+		values := inputValues(fs, value, d.location())
+		if len(values) == 0 {
+			values = []string{""}
+		}
+		for _, v := range values {
+			item := &htmlAttrs{parts: append([]string{}, a.parts...)}
+			b.WriteString(string(fs.inputHTML(item, v)))
+		}
+		return template.HTML(b.String()), nil
+	}
+	values := inputValues(fs, value, d.location())
+	if len(values) == 0 {
+		values = []string{""}
+	}
+	return fs.inputHTML(a, values[0]), nil
+}
+
+// inputHTML returns the input element for a single value.
+func (fs *FormSpec) inputHTML(a *htmlAttrs, v string) template.HTML {
+
+	switch fs.itemType() {
+	case "bool":
+		a.add("type", "checkbox")
+		a.add("value", "true")
+		if v == "true" {
+			a.flag("checked")
+		}
+		return a.open("input")
+	case "int", "int64", "float":
+		a.add("type", "number")
+		fs.rangeAttrs(a)
+	case "decimal", "money":
+		if fs.itemType() == "money" && fs.decimalFormat().fixedCurrency() == "" {
+			a.add("type", "text")
+			break
+		}
+		a.add("type", "number")
+		fs.rangeAttrs(a)
+	case "date":
+		a.add("type", "date")
+	case "datetime":
+		a.add("type", "datetime-local")
+		switch fs.timePrecision() {
+		case "second":
+			a.add("step", "1")
+		case "millisecond":
+			a.add("step", "0.001")
+		}
+	case "email", "url":
+		a.add("type", fs.itemType())
+		fs.lengthAttrs(a)
+	default:
+		if fs.Sensitive && fs.itemType() == "string" {
+			a.add("type", "password")
+		} else {
+			a.add("type", "text")
+		}
+		fs.lengthAttrs(a)
+	}
+	a.add("value", v)
+	return a.open("input")
+}
+
+// rangeAttrs adds the step, min and max attributes for a numeric range
+// limit.  Exclusive bounds are rendered as inclusive ones.  Decimals
+// without a step are stepped by their scale, if known.
+func (fs *FormSpec) rangeAttrs(a *htmlAttrs) {
+	var min, max, step string
+	if r := fs.rangeLimit(); r != nil {
+		min, max, step = r.formatNumbers()
+	}
+	switch t := fs.itemType(); {
+	case step != "":
+		a.add("step", step)
+	case t == "decimal" || t == "money":
+		f := fs.decimalFormat()
+		if scale, ok := f.scaleFor(f.fixedCurrency()); ok {
+			a.add("step", Decimal{Units: 1, Scale: scale}.String())
+		} else {
+			a.add("step", "any")
+		}
+	case t == "float":
+		a.add("step", "any")
+	default:
+		a.add("step", "1")
+	}
+	if min != "" {
+		a.add("min", min)
+	}
+	if max != "" {
+		a.add("max", max)
+	}
+}
+
+// lengthAttrs adds the minlength, maxlength and pattern attributes for the
+// length and regexp limits of a string.
+func (fs *FormSpec) lengthAttrs(a *htmlAttrs) {
+	for _, l := range fs.limits {
+		switch {
+		case l.length > 0:
+			n := strconv.Itoa(l.length)
+			a.add("minlength", n)
+			a.add("maxlength", n)
+		case l.rng != nil:
+			min, max, _ := l.rng.formatNumbers()
+			if min != "" {
+				a.add("minlength", min)
+			}
+			if max != "" {
+				a.add("maxlength", max)
+			}
+		case l.re != nil:
+			if p, ok := jsPattern(l.re.String()); ok {
+				a.add("pattern", p)
+			}
+		}
+	}
+}
+
+// fileHTML returns the file input element.
+func (fs *FormSpec) fileHTML(a *htmlAttrs) template.HTML {
+	a.add("type", "file")
+	if len(fs.Accept) > 0 {
+		a.add("accept", strings.Join(fs.Accept, ","))
+	}
+	if fs.isMulti() {
+		a.flag("multiple")
+	}
+	return a.open("input")
+}
+
+// listOptions returns the values of a list limit, or nil if there is none.
+func (fs *FormSpec) listOptions() []string {
+	l := fs.listLimit()
+	switch {
+	case l == nil:
+		return nil
+	case len(l.listString) > 0:
+		return l.listString
+	}
+	options := make([]string, len(l.listInt))
+	for idx, i := range l.listInt {
+		options[idx] = strconv.FormatInt(i, 10)
+	}
+	return options
+}
+
+// selectHTML returns a select element with options, of which those in
+// value, with times in loc, are selected.
+func (fs *FormSpec) selectHTML(a *htmlAttrs, options []string,
+	value interface{}, loc *time.Location) template.HTML {
+
+	if fs.isMulti() {
+		a.flag("multiple")
+	}
+	selected := map[string]bool{}
+	for _, v := range inputValues(fs, value, loc) {
+		selected[v] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(string(a.open("select")))
+	if !fs.Required && !fs.isMulti() {
+		b.WriteString(`<option value=""></option>`)
+	}
+	for _, opt := range options {
+		oa := &htmlAttrs{}
+		oa.add("value", opt)
+		if selected[opt] {
+			oa.flag("selected")
+		}
+		b.WriteString(string(oa.open("option")))
+		b.WriteString(template.HTMLEscapeString(opt))
+		b.WriteString("</option>")
+	}
+	b.WriteString("</select>")
+	return template.HTML(b.String())
+}
+
+// inputValues returns value as input strings for the FormSpec: the items
+// of a slice, or the value itself.  Nil values result in no strings, and
+// zero times in empty strings; other times are in loc.
+func inputValues(fs *FormSpec, value interface{},
+	loc *time.Location) []string {
+
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		values := []string{}
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values,
+				inputValues(fs, rv.Index(i).Interface(), loc)...)
+		}
+		return values
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		return inputValues(fs, rv.Elem().Interface(), loc)
+	}
+	return []string{fs.inputValue(value, loc)}
+}
+
+// inputValue formats a single value as input for the FormSpec, with times
+// in loc.
+func (fs *FormSpec) inputValue(value interface{}, loc *time.Location) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		v = v.In(loc)
+		switch fs.itemType() {
+		case "date":
+			return v.Format("2006-01-02")
+		case "datetime":
+			if v.Second() != 0 || v.Nanosecond() != 0 {
+				return v.Format("2006-01-02T15:04:05.999")
+			}
+			return v.Format("2006-01-02T15:04")
+		}
+		return v.Format("2006-01-02 15:04")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case mail.Address:
+		return v.Address
+	case url.URL:
+		return v.String()
+	case Money:
+		switch v.Currency {
+		case "":
+			return ""
+		case fs.decimalFormat().fixedCurrency():
+			return v.Amount.String()
+		}
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// hasFieldError returns true if err is, or is a MultiError containing, a
+// FieldError for key; or is a FormState with one.
+func hasFieldError(v interface{}, key string) bool {
+	if state, ok := v.(*FormState); ok {
+		return state.HasError(key)
+	}
+	err, ok := v.(error)
+	if !ok {
+		return false
+	}
+	var me *MultiError
+	if errors.As(err, &me) {
+		return len(me.ByKey(key)) > 0
+	}
+	var fe *FieldError
+	return errors.As(err, &fe) && fe.Key == key
+}
+
+// conditionList returns conditions in the syntax of Condition.String,
+// separated by commas.
+func conditionList(conditions []Condition) string {
+	list := make([]string, len(conditions))
+	for idx, c := range conditions {
+		list[idx] = c.String()
+	}
+	return strings.Join(list, ",")
+}
+
+// jsPattern returns the Go regular expression re as a pattern for HTML
+// inputs, which must match the whole value, and false if it uses syntax
+// that JavaScript does not support in the same way.
+func jsPattern(re string) (string, bool) {
+	if jsIncompatible.MatchString(re) {
+		return "", false
+	}
+	if strings.HasPrefix(re, "^") && strings.HasSuffix(re, "$") &&
+		!strings.HasSuffix(re, `\$`) && !strings.Contains(re, "|") {
+		return re, true
+	}
+	return `[\s\S]*(?:` + re + `)[\s\S]*`, true
+}
+
+// jsIncompatible matches Go regexp syntax with no JavaScript equivalent:
+// flags, \A, \z, \Q, ASCII classes, \C and one-letter Unicode classes.
+var jsIncompatible = regexp.MustCompile(`\(\?|\\[AzQC]|\[\[:|\\[pP][^{]`)
+
+// htmlAttrs builds escaped HTML attributes.
+type htmlAttrs struct {
+	parts []string
+}
+
+func (a *htmlAttrs) add(name, value string) {
+	a.parts = append(a.parts,
+		name+`="`+template.HTMLEscapeString(value)+`"`)
+}
+
+func (a *htmlAttrs) flag(name string) {
+	a.parts = append(a.parts, name)
+}
+
+// open returns the opening tag, which for inputs is the whole element.
+func (a *htmlAttrs) open(tag string) template.HTML {
+	if len(a.parts) == 0 {
+		return template.HTML("<" + tag + ">")
+	}
+	return template.HTML("<" + tag + " " + strings.Join(a.parts, " ") + ">")
+}
@@ -0,0 +1,287 @@
+// formhtml_test.go
+// ----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"bytes"
+	"errors"
+	"html/template"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FormInput(t *testing.T) {
+
+	assert := assert.New(t)
+
+	when := time.Date(2023, 4, 5, 6, 7, 0, 0, vebben.FormValueTimeLocation)
+	conditional := vebben.OptionalFormSpec("company", "string")
+	conditional.RequiredIf = []vebben.Condition{
+		vebben.When("type", "company"),
+		vebben.Unless("country"),
+	}
+	conditional.ForbiddenIf = []vebben.Condition{vebben.When("type", "person")}
+
+	for _, tc := range []struct {
+		spec  *vebben.FormSpec
+		value interface{}
+		exp   string
+	}{
+		{
+			vebben.RequiredFormSpec("name", "string", "2-20"),
+			`Joe "X" <y>`,
+			`<input name="name" id="name" required type="text" ` +
+				`minlength="2" maxlength="20" value="Joe &#34;X&#34; &lt;y&gt;">`,
+		},
+		{
+			vebben.OptionalFormSpec("code", "string", "4"),
+			nil,
+			`<input name="code" id="code" type="text" minlength="4" ` +
+				`maxlength="4" value="">`,
+		},
+		{
+			vebben.OptionalFormSpec("sku", "string", `re:^\w\d+$`),
+			"a1",
+			`<input name="sku" id="sku" type="text" pattern="^\w\d+$" ` +
+				`value="a1">`,
+		},
+		{
+			vebben.OptionalFormSpec("sub", "string", `re:\d`),
+			"",
+			`<input name="sub" id="sub" type="text" ` +
+				`pattern="[\s\S]*(?:\d)[\s\S]*" value="">`,
+		},
+		{
+			vebben.OptionalFormSpec("goonly", "string", `re:(?i)^x$`),
+			"",
+			`<input name="goonly" id="goonly" type="text" value="">`,
+		},
+		{
+			vebben.OptionalFormSpec("size", "int", "1-4"),
+			3,
+			`<input name="size" id="size" type="number" step="1" min="1" ` +
+				`max="4" value="3">`,
+		},
+		{
+			vebben.OptionalFormSpec("big", "int64", ""),
+			int64(1234567890123),
+			`<input name="big" id="big" type="number" step="1" ` +
+				`value="1234567890123">`,
+		},
+		{
+			vebben.OptionalFormSpec("strength", "float", "0.5-1.5"),
+			0.75,
+			`<input name="strength" id="strength" type="number" step="any" ` +
+				`min="0.5" max="1.5" value="0.75">`,
+		},
+		{
+			vebben.OptionalFormSpec("ok", "bool"),
+			true,
+			`<input name="ok" id="ok" type="checkbox" value="true" checked>`,
+		},
+		{
+			vebben.OptionalFormSpec("ok", "bool"),
+			"false",
+			`<input name="ok" id="ok" type="checkbox" value="true">`,
+		},
+		{
+			vebben.OptionalFormSpec("day", "date"),
+			when,
+			`<input name="day" id="day" type="date" value="2023-04-05">`,
+		},
+		{
+			vebben.OptionalFormSpec("at", "datetime"),
+			&when,
+			`<input name="at" id="at" type="datetime-local" ` +
+				`value="2023-04-05T06:07">`,
+		},
+		{
+			vebben.OptionalFormSpec("at", "datetime"),
+			time.Time{},
+			`<input name="at" id="at" type="datetime-local" value="">`,
+		},
+		{
+			vebben.OptionalFormSpec("at", "datetime", "precision=second"),
+			when.Add(8 * time.Second),
+			`<input name="at" id="at" type="datetime-local" step="1" ` +
+				`value="2023-04-05T06:07:08">`,
+		},
+		{
+			vebben.OptionalFormSpec("at", "datetime", "precision=millisecond"),
+			when.Add(8250 * time.Millisecond),
+			`<input name="at" id="at" type="datetime-local" step="0.001" ` +
+				`value="2023-04-05T06:07:08.25">`,
+		},
+		{
+			vebben.OptionalFormSpec("flex", "dateflex"),
+			when,
+			`<input name="flex" id="flex" type="text" ` +
+				`value="2023-04-05 06:07">`,
+		},
+		{
+			vebben.RequiredFormSpec("color", "string", "red,green"),
+			"green",
+			`<select name="color" id="color" required>` +
+				`<option value="red">red</option>` +
+				`<option value="green" selected>green</option></select>`,
+		},
+		{
+			vebben.OptionalFormSpec("n", "int", "1,3"),
+			3,
+			`<select name="n" id="n"><option value=""></option>` +
+				`<option value="1">1</option>` +
+				`<option value="3" selected>3</option></select>`,
+		},
+		{
+			vebben.OptionalFormSpec("colors", "[]string", "red,green,blue"),
+			[]string{"red", "blue"},
+			`<select name="colors" multiple>` +
+				`<option value="red" selected>red</option>` +
+				`<option value="green">green</option>` +
+				`<option value="blue" selected>blue</option></select>`,
+		},
+		{
+			vebben.OptionalFormSpec("tags", "[]string", "1-5"),
+			[]interface{}{"a", "b"},
+			`<input name="tags" type="text" minlength="1" maxlength="5" ` +
+				`value="a"><input name="tags" type="text" minlength="1" ` +
+				`maxlength="5" value="b">`,
+		},
+		{
+			vebben.OptionalFormSpec("nums", "[]int"),
+			nil,
+			`<input name="nums" type="number" step="1" value="">`,
+		},
+		{
+			&vebben.FormSpec{Key: "pics", Type: "[]file",
+				Accept: []string{"image/png", "image/*"}},
+			nil,
+			`<input name="pics" type="file" accept="image/png,image/*" ` +
+				`multiple>`,
+		},
+		{
+			conditional,
+			"",
+			`<input name="company" id="company" ` +
+				`data-required-if="type:company,!country" ` +
+				`data-forbidden-if="type:person" type="text" value="">`,
+		},
+	} {
+		tc.spec.Init()
+		html, err := vebben.FormInput(tc.spec, tc.value, nil)
+		if assert.Nil(err) {
+			assert.Equal(template.HTML(tc.exp), html, tc.spec.Key)
+		}
+	}
+
+}
+
+func Test_FormInput_ErrorState(t *testing.T) {
+
+	assert := assert.New(t)
+
+	spec := vebben.RequiredFormSpec("name", "string")
+	exp := template.HTML(`<input name="name" id="name" required ` +
+		`class="error" aria-invalid="true" type="text" value="">`)
+
+	err := vebben.DecodeForm(vebben.URLValues{}, []*vebben.FormSpec{spec},
+		&map[string]interface{}{})
+	html, _ := vebben.FormInput(spec, "", err)
+	assert.Equal(exp, html, "MultiError")
+
+	html, _ = vebben.FormInput(spec, "", spec.FieldError("x", nil))
+	assert.Equal(exp, html, "FieldError")
+
+	other := vebben.RequiredFormSpec("other", "string")
+	html, _ = vebben.FormInput(spec, "", other.FieldError("x", nil))
+	assert.NotContains(html, "aria-invalid", "other FieldError")
+
+	html, _ = vebben.FormInput(spec, "", errors.New("oops"))
+	assert.NotContains(html, "aria-invalid", "plain error")
+
+}
+
+func Test_FormInput_Group(t *testing.T) {
+
+	spec := vebben.GroupFormSpec("g", []*vebben.FormSpec{
+		vebben.OptionalFormSpec("x", "string"),
+	})
+	_, err := vebben.FormInput(spec, nil, nil)
+	if assert.Error(t, err) {
+		assert.Equal(t, "FormInput does not apply to group", err.Error())
+	}
+
+}
+
+func Test_FormInput_Template(t *testing.T) {
+
+	assert := assert.New(t)
+
+	tmpl := template.Must(template.New("").Funcs(vebben.NewFuncMap()).Parse(
+		`<form>{{ forminput .Spec .Value .Err }}</form>`))
+	data := map[string]interface{}{
+		"Spec":  vebben.RequiredFormSpec("size", "int", "1-4"),
+		"Value": "2",
+		"Err":   nil,
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); assert.Nil(err, "%v", err) {
+		assert.Equal(`<form><input name="size" id="size" required `+
+			`type="number" step="1" min="1" max="4" value="2"></form>`,
+			b.String())
+	}
+
+}
+
+func Test_DecodeForm_DateTimeLocal(t *testing.T) {
+
+	spec := vebben.RequiredFormSpec("at", "datetime")
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(vebben.URLValues{"at": {"2023-04-05T06:07"}},
+		[]*vebben.FormSpec{spec}, &target)
+	if assert.Nil(t, err) {
+		assert.Equal(t, time.Date(2023, 4, 5, 6, 7, 0, 0,
+			vebben.FormValueTimeLocation), target["at"])
+	}
+}
+
+func Test_Decoder_FormInput(t *testing.T) {
+
+	assert := assert.New(t)
+
+	ny, err := time.LoadLocation("America/New_York")
+	if !assert.Nil(err) {
+		return
+	}
+	dec := vebben.NewDecoder()
+	dec.Location = ny
+	spec := vebben.RequiredFormSpec("at", "datetime")
+	target := map[string]interface{}{}
+	err = dec.DecodeForm(vebben.URLValues{"at": {"2023-04-05T06:07"}},
+		[]*vebben.FormSpec{spec}, &target)
+	if !assert.Nil(err) {
+		return
+	}
+
+	// Rendered as input with the Decoder...
+	html, err := dec.FormInput(spec, target["at"], nil)
+	if assert.Nil(err) {
+		assert.Contains(string(html), `value="2023-04-05T06:07"`)
+	}
+
+	// ...and otherwise in FormValueTimeLocation.
+	html, err = vebben.FormInput(spec, target["at"], nil)
+	if assert.Nil(err) {
+		assert.Contains(string(html), `value="2023-04-05T`+
+			target["at"].(time.Time).In(vebben.FormValueTimeLocation).
+				Format("15:04")+`"`)
+	}
+}
@@ -0,0 +1,331 @@
+// formjs.go -- client-side JavaScript validation for FormSpecs.
+// ---------
+
+package vebben
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed formjs.js
+var validatorJS string
+
+// ValidatorJS returns a self-contained JavaScript module that validates
+// form values in the browser as DecodeForm would validate them with specs,
+// so users need not wait for the server to learn of simple mistakes.  The
+// module exports a single function:
+//
+//   validate(input) // returns an array of errors
+//
+// The input may be a form element, a FormData or URLSearchParams, or an
+// object mapping keys to strings or arrays of strings.  Each error has the
+// key, name, code, params, input and message of the FieldError DecodeForm
+// would return.  The messages are taken from the Catalog and language set
+// by opts, as for DecodeForm.
+//
+// The module mirrors the conversions and the standard validators for the
+// standard types, including GlyphLength for string lengths and the current
+// DateFormats and DateTimeFormats; as well as item counts, repeated groups,
+// the RequiredIf and ForbiddenIf Conditions and the Messages of the specs.
+// Regexp limits are only checked if they are compatible with JavaScript.
+// Files, the network, decimal and money types, custom types and custom
+// Validators are left to the server, as are Rules; and date formats with
+// elements other than numeric dates and times are only checked on the
+// server, as are numbers with separators if NumberLocale is set, dates in
+// no format if RelativeDates is set, and the precision of datetimes.
+func ValidatorJS(specs []*FormSpec, opts ...DecodeOption) string {
+	return packageDecoder().ValidatorJS(specs, opts...)
+}
+
+// ValidatorJS returns a JavaScript module validating form values as the
+// Decoder's DecodeForm would validate them with specs.  It is otherwise
+// the same as the package-level ValidatorJS; see there.
+func (d *Decoder) ValidatorJS(specs []*FormSpec, opts ...DecodeOption) string {
+
+	c := newDecodeConfig(opts)
+	layouts := map[string][]interface{}{
+		"date":     jsDateLayouts(d.DateFormats),
+		"datetime": jsDateLayouts(d.DateTimeFormats),
+	}
+	if d.RelativeDates {
+		// Dates matching no layout are left to the server.
+		for typ, list := range layouts {
+			layouts[typ] = append(list, nil)
+		}
+	}
+
+	return strings.NewReplacer(
+		"/*SPECS*/[]", mustMarshalJS(d.jsSpecsFor(specs, c)),
+		"/*MESSAGES*/{}", mustMarshalJS(c.catalog.messagesFor(c.lang)),
+		"/*LAYOUTS*/{ date: [], datetime: [] }", mustMarshalJS(layouts),
+		"/*TRIM*/true", strconv.FormatBool(d.TrimSpace),
+		"/*LOCALNUMBERS*/false",
+		strconv.FormatBool(d.NumberLocale != language.Und),
+	).Replace(validatorJS)
+}
+
+// jsSpec is the JSON form of a FormSpec used by the JavaScript module.
+type jsSpec struct {
+	Key         string            `json:"key"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"` // item type; empty if custom
+	Multi       bool              `json:"multi,omitempty"`
+	Required    bool              `json:"required,omitempty"`
+	MinItems    int               `json:"minItems,omitempty"`
+	MaxItems    int               `json:"maxItems,omitempty"`
+	File        bool              `json:"file,omitempty"`
+	Sensitive   bool              `json:"sensitive,omitempty"`
+	Messages    map[string]string `json:"messages,omitempty"`
+	Group       []*jsSpec         `json:"group,omitempty"`
+	Validate    bool              `json:"validate,omitempty"`
+	Limits      []*jsLimit        `json:"limits,omitempty"`
+	RequiredIf  []jsCondition     `json:"requiredIf,omitempty"`
+	ForbiddenIf []jsCondition     `json:"forbiddenIf,omitempty"`
+}
+
+// jsLimit is one constraint of a Limit.
+type jsLimit struct {
+	Length     int      `json:"length,omitempty"`
+	Range      *jsRange `json:"range,omitempty"`
+	Pattern    *string  `json:"pattern,omitempty"`
+	JSPattern  bool     `json:"jsPattern,omitempty"`
+	ListString []string `json:"listString,omitempty"`
+	ListInt    []string `json:"listInt,omitempty"`
+	Not        bool     `json:"not,omitempty"`
+}
+
+// jsRange is a range limit, with integers as strings for BigInt.
+type jsRange struct {
+	Min     interface{} `json:"min,omitempty"`
+	Max     interface{} `json:"max,omitempty"`
+	MinExcl bool        `json:"minExcl,omitempty"`
+	MaxExcl bool        `json:"maxExcl,omitempty"`
+	Step    interface{} `json:"step,omitempty"`
+	Base    interface{} `json:"base,omitempty"`
+}
+
+type jsCondition struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values,omitempty"`
+	Not    bool     `json:"not,omitempty"`
+}
+
+// jsSpecsFor returns the specs as decoded by the Decoder.
+func (d *Decoder) jsSpecsFor(specs []*FormSpec, c *decodeConfig) []*jsSpec {
+	res := make([]*jsSpec, len(specs))
+	for idx, fs := range specs {
+		res[idx] = d.spec(fs).jsSpec(d, c)
+	}
+	return res
+}
+
+func (fs *FormSpec) jsSpec(d *Decoder, c *decodeConfig) *jsSpec {
+
+	s := &jsSpec{
+		Key:         fs.Key,
+		Name:        fs.Name,
+		Multi:       fs.isMulti(),
+		Required:    fs.Required,
+		MinItems:    fs.MinItems,
+		MaxItems:    fs.MaxItems,
+		File:        fs.isFile(),
+		Sensitive:   fs.Sensitive,
+		Messages:    jsMessages(fs, c),
+		RequiredIf:  jsConditions(fs.RequiredIf),
+		ForbiddenIf: jsConditions(fs.ForbiddenIf),
+	}
+	if fs.isGroup() {
+		s.Type = "group"
+		s.Group = d.jsSpecsFor(fs.Group, c)
+		return s
+	}
+	t := fs.specType()
+	if t == nil || t.custom {
+		return s
+	}
+	s.Type = fs.itemType()
+	s.Validate = fs.standardValidator
+
+	for _, l := range fs.limits {
+		s.Limits = append(s.Limits, l.jsLimit())
+	}
+	return s
+}
+
+func (l *formLimit) jsLimit() *jsLimit {
+	res := &jsLimit{Length: l.length, ListString: l.listString, Not: l.not}
+	if l.rng != nil {
+		res.Range = l.rng.jsRange()
+	}
+	if l.re != nil {
+		re := l.re.String()
+		res.Pattern = &re
+		res.JSPattern = !jsIncompatible.MatchString(re)
+	}
+	for _, i := range l.listInt {
+		res.ListInt = append(res.ListInt, strconv.FormatInt(i, 10))
+	}
+	return res
+}
+
+func (r *numRange) jsRange() *jsRange {
+	res := &jsRange{MinExcl: r.minExcl, MaxExcl: r.maxExcl}
+	if r.float {
+		if r.hasMin {
+			res.Min = r.minFloat
+		}
+		if r.hasMax {
+			res.Max = r.maxFloat
+		}
+		if r.stepFloat != 0 {
+			res.Step, res.Base = r.stepFloat, r.baseFloat
+		}
+		return res
+	}
+	if r.dec {
+		if r.hasMin {
+			res.Min = r.minDec.String()
+		}
+		if r.hasMax {
+			res.Max = r.maxDec.String()
+		}
+		if r.stepDec.Units != 0 {
+			res.Step, res.Base = r.stepDec.String(), r.baseDec.String()
+		}
+		return res
+	}
+	if r.hasMin {
+		res.Min = strconv.FormatInt(r.minInt, 10)
+	}
+	if r.hasMax {
+		res.Max = strconv.FormatInt(r.maxInt, 10)
+	}
+	if r.stepInt != 0 {
+		res.Step = strconv.FormatInt(r.stepInt, 10)
+		res.Base = strconv.FormatInt(r.baseInt, 10)
+	}
+	return res
+}
+
+// jsMessages returns the Messages of fs that take precedence over the
+// Catalog in the configured language, as in FieldError.Localize.
+func jsMessages(fs *FormSpec, c *decodeConfig) map[string]string {
+	if fs.Messages == nil {
+		return nil
+	}
+	res := map[string]string{}
+	for code, msg := range fs.Messages.messagesFor(c.lang) {
+		if _, ok := fs.Messages.lookup(c.lang, code); ok {
+			res[code] = msg
+		} else if _, ok := c.catalog.lookup(c.lang, code); !ok {
+			res[code] = msg
+		}
+	}
+	return res
+}
+
+func jsConditions(conds []Condition) []jsCondition {
+	var res []jsCondition
+	for _, c := range conds {
+		res = append(res, jsCondition{c.Key, c.Values, c.Not})
+	}
+	return res
+}
+
+// jsDateLayouts returns the layouts split by jsDateLayout, with nil for
+// those it can not split.
+func jsDateLayouts(layouts []string) []interface{} {
+	res := make([]interface{}, len(layouts))
+	for idx, layout := range layouts {
+		if chunks, ok := jsDateLayout(layout); ok {
+			res[idx] = chunks
+		}
+	}
+	return res
+}
+
+// jsDateLayout splits a Go time layout into literal and numeric chunks as
+// the time package does, as well as numeric offsets in hours and minutes,
+// returning false if it has any other elements.
+func jsDateLayout(layout string) ([]map[string]string, bool) {
+
+	chunks := []map[string]string{}
+	lit := ""
+	std := func(s string) {
+		if lit != "" {
+			chunks = append(chunks, map[string]string{"lit": lit})
+			lit = ""
+		}
+		chunks = append(chunks, map[string]string{"std": s})
+	}
+	has := func(i int, s string) bool {
+		return strings.HasPrefix(layout[i:], s)
+	}
+
+	for i := 0; i < len(layout); i++ {
+		switch c := layout[i]; {
+		case has(i, "Z07:00:00"), has(i, "Z070000"), has(i, "-07:00:00"),
+			has(i, "-070000"):
+			return nil, false
+		case has(i, "Z07:00"), has(i, "-07:00"):
+			std(layout[i : i+6])
+			i += 5
+		case has(i, "Z0700"), has(i, "-0700"):
+			std(layout[i : i+5])
+			i += 4
+		case has(i, "Jan"), has(i, "Mon"), has(i, "MST"), has(i, "PM"),
+			has(i, "pm"), has(i, "-07"), has(i, "Z07"), has(i, "002"),
+			has(i, "_2") && !has(i, "_2006"):
+			return nil, false
+		case c == '0' && i+1 < len(layout) &&
+			layout[i+1] >= '1' && layout[i+1] <= '6':
+			if layout[i+1] == '3' || layout[i+1] == '6' {
+				return nil, false
+			}
+			std(layout[i : i+2])
+			i++
+		case has(i, "15"):
+			std("15")
+			i++
+		case c == '1' || c == '4' || c == '5':
+			std(string(c))
+		case c == '3':
+			return nil, false
+		case has(i, "2006"):
+			std("2006")
+			i += 3
+		case c == '2':
+			std("2")
+		case (c == '.' || c == ',') && i+1 < len(layout) &&
+			(layout[i+1] == '0' || layout[i+1] == '9'):
+			j := i + 1
+			for j < len(layout) && layout[j] == layout[i+1] {
+				j++
+			}
+			if j == len(layout) || layout[j] < '0' || layout[j] > '9' {
+				return nil, false // fractional seconds
+			}
+			lit += string(c)
+		default:
+			lit += string(c)
+		}
+	}
+	if lit != "" {
+		chunks = append(chunks, map[string]string{"lit": lit})
+	}
+	return chunks, true
+}
+
+// mustMarshalJS returns v as JSON, which is also valid JavaScript.
+func mustMarshalJS(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic("Error marshaling JavaScript data: " + err.Error())
+	}
+	return string(b)
+}
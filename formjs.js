@@ -0,0 +1,685 @@
+// Client-side form validation generated by vebben from a set of FormSpecs.
+//
+// This mirrors the checks made by DecodeForm on the server, with the same
+// error codes and messages; but the server remains the final authority.
+// Files, custom types and custom validators are left to the server.
+//
+// Usage:
+//
+//   import { validate } from "./validator.js";
+//   const errors = validate(form); // HTMLFormElement, FormData, or object
+//   for (const e of errors) console.log(e.key, e.code, e.message);
+
+const specs = /*SPECS*/[];
+const messages = /*MESSAGES*/{};
+const layouts = /*LAYOUTS*/{ date: [], datetime: [] };
+const trimInputs = /*TRIM*/true;
+const localNumbers = /*LOCALNUMBERS*/false;
+
+// Validate returns the errors for the form values in input, which may be a
+// form element, a FormData or URLSearchParams, or an object mapping keys
+// to strings or arrays of strings.  Each error has the key, name, code,
+// params, input and message of the corresponding FieldError.
+export function validate(input) {
+  const form = formValues(input);
+  const errors = [];
+  decodeSpecs(form, specs, "", errors);
+  return errors;
+}
+
+function formValues(input) {
+  const form = { values: new Map(), files: new Map(), keys: [] };
+  const add = (k, v) => {
+    if (!form.values.has(k)) {
+      form.values.set(k, []);
+      form.files.set(k, 0);
+      form.keys.push(k);
+    }
+    if (typeof v === "string") {
+      form.values.get(k).push(v);
+    } else if (v && typeof v === "object" && "name" in v && "size" in v) {
+      if (v.name !== "") form.files.set(k, form.files.get(k) + 1);
+    } else if (v !== null && v !== undefined) {
+      form.values.get(k).push(String(v));
+    }
+  };
+  if (typeof HTMLFormElement !== "undefined" &&
+      input instanceof HTMLFormElement) {
+    input = new FormData(input);
+  }
+  if (input && typeof input.getAll === "function" &&
+      typeof input.entries === "function") {
+    for (const [k, v] of input.entries()) add(k, v);
+  } else {
+    for (const k of Object.keys(input || {})) {
+      const v = input[k];
+      if (Array.isArray(v)) {
+        add(k, null);
+        v.forEach((item) => add(k, item));
+      } else {
+        add(k, v);
+      }
+    }
+  }
+  return form;
+}
+
+// Go's strings.TrimSpace, which trims slightly different characters than
+// String.prototype.trim.
+const goSpace = "\t\n\v\f\r \u0085\u00a0\u1680\u2000-\u200a" +
+  "\u2028\u2029\u202f\u205f\u3000";
+const goTrimRE = new RegExp("^[" + goSpace + "]+|[" + goSpace + "]+$", "g");
+
+function goTrim(s) {
+  return s.replace(goTrimRE, "");
+}
+
+function inputTrim(s) {
+  return trimInputs ? goTrim(s) : s;
+}
+
+function first(form, key) {
+  const values = form.values.get(key);
+  return values && values.length > 0 ? values[0] : "";
+}
+
+function hasValues(form, key) {
+  const values = form.values.get(key) || [];
+  return values.some((v) => goTrim(v) !== "") || form.files.get(key) > 0;
+}
+
+function conditionsHold(form, conds, prefix) {
+  if (!conds || conds.length === 0) return false;
+  return conds.every((c) => conditionHolds(form, c, prefix) !== !!c.not);
+}
+
+function conditionHolds(form, c, prefix) {
+  const key = prefix + c.key;
+  if (!c.values || c.values.length === 0) return hasValues(form, key);
+  const values = form.values.get(key) || [];
+  return values.some((v) => c.values.includes(goTrim(v)));
+}
+
+function decodeSpecs(form, list, prefix, errors) {
+  for (const spec of list) {
+    const key = prefix + spec.key;
+    if (spec.group) {
+      decodeGroup(form, spec, key, errors);
+      continue;
+    }
+    if (conditionsHold(form, spec.forbiddenIf, prefix) &&
+        hasValues(form, key)) {
+      errors.push(fieldError(spec, key, "forbidden", [], ""));
+      continue;
+    }
+    if (spec.file) continue;
+    const required = !!spec.required ||
+      conditionsHold(form, spec.requiredIf, prefix);
+    if (spec.multi) {
+      decodeItems(form, spec, key, required, errors);
+    } else {
+      decodeValue(form, spec, key, required, errors);
+    }
+  }
+}
+
+function decodeValue(form, spec, key, required, errors) {
+  const input = inputTrim(first(form, key));
+  if (required && input === "") {
+    errors.push(fieldError(spec, key, "required", [], ""));
+    return;
+  }
+  const conv = convert(spec, input);
+  if (!conv.ok) {
+    errors.push(conversionError(spec, key, input));
+    return;
+  }
+  if (conv.known) {
+    const err = check(spec, conv.value);
+    if (err) errors.push(fieldError(spec, key, err[0], err[1], input));
+  }
+}
+
+function decodeItems(form, spec, key, required, errors) {
+  const errs = [];
+  let count = 0;
+  for (let input of form.values.get(key) || []) {
+    input = inputTrim(input);
+    if (input === "") continue;
+    const conv = convert(spec, input);
+    if (!conv.ok) {
+      errs.push(conversionError(spec, key, input));
+      continue;
+    }
+    if (conv.known) {
+      const err = check(spec, conv.value);
+      if (err) {
+        errs.push(fieldError(spec, key, err[0], err[1], input));
+        continue;
+      }
+    }
+    count++;
+  }
+  if (errs.length > 0) {
+    errors.push(...errs);
+    return;
+  }
+  const err = checkCount(spec, required, count);
+  if (err) errors.push(fieldError(spec, key, err[0], err[1], ""));
+}
+
+function decodeGroup(form, spec, key, errors) {
+  if (!spec.multi) {
+    decodeSpecs(form, spec.group, key + ".", errors);
+    return;
+  }
+  const rows = groupRows(form, key);
+  const err = checkCount(spec, !!spec.required, rows.length);
+  if (err) {
+    errors.push(fieldError(spec, key, err[0], err[1], ""));
+    return;
+  }
+  for (const row of rows) {
+    decodeSpecs(form, spec.group, key + "[" + row + "].", errors);
+  }
+}
+
+function groupRows(form, key) {
+  const prefix = key + "[";
+  const present = new Set();
+  for (const k of form.keys) {
+    if (!k.startsWith(prefix)) continue;
+    const rest = k.slice(prefix.length);
+    const end = rest.indexOf("]");
+    if (end < 0) continue;
+    if (!/^[+-]?[0-9]+$/.test(rest.slice(0, end))) continue;
+    const row = Number(rest.slice(0, end));
+    if (row < 0 || present.has(row)) continue;
+    const next = rest.slice(end + 1);
+    if (next !== "" && next[0] !== "." && next[0] !== "[") continue;
+    if (hasValues(form, k)) present.add(row);
+  }
+  return [...present].sort((a, b) => a - b);
+}
+
+function checkCount(spec, required, count) {
+  if (required && count === 0) return ["required", []];
+  if (count > 0 && count < (spec.minItems || 0)) {
+    return ["too_few", [intParam("min", spec.minItems),
+      intParam("count", count)]];
+  }
+  if (spec.maxItems > 0 && count > spec.maxItems) {
+    return ["too_many", [intParam("max", spec.maxItems),
+      intParam("count", count)]];
+  }
+  return null;
+}
+
+// Conversion.
+
+const intRE = /^[+-]?[0-9]+$/;
+const floatRE = new RegExp("^[+-]?(?:D(?:\\.(?:D)?)?|\\.D)(?:[eE][+-]?D)?$"
+  .replace(/D/g, "[0-9]+(?:_[0-9]+)*"));
+const specialFloatRE = /^(?:[+-]?(?:inf|infinity)|nan)$/i;
+const localNumberRE = /[\s.,'\u2019\u2212]/;
+
+function convert(spec, input) {
+  if (input === "") return zeroValue(spec);
+  if (localNumbers && (spec.type === "int" || spec.type === "int64" ||
+      spec.type === "float") && localNumberRE.test(input)) {
+    return { ok: true, known: false }; // locale numbers: ask the server.
+  }
+  switch (spec.type) {
+    case "string":
+      return { ok: true, known: true, value: input };
+    case "int":
+    case "int64": {
+      if (!intRE.test(input)) return { ok: false };
+      const v = BigInt(input);
+      const limit = spec.type === "int" ? 1n << 31n : 1n << 63n;
+      if (v < -limit || v >= limit) return { ok: false };
+      return { ok: true, known: true, value: v };
+    }
+    case "float": {
+      let v;
+      if (specialFloatRE.test(input)) {
+        const s = input.toLowerCase();
+        v = s === "nan" ? NaN : s[0] === "-" ? -Infinity : Infinity;
+      } else if (floatRE.test(input)) {
+        v = Number(input.replace(/_/g, ""));
+        if (!Number.isFinite(v)) return { ok: false };
+      } else if (/^[+-]?0[xX]/.test(input)) {
+        return { ok: true, known: false }; // hex floats: ask the server.
+      } else {
+        return { ok: false };
+      }
+      return { ok: true, known: true, value: v };
+    }
+    case "bool":
+      if (input === "true" || input === "false") {
+        return { ok: true, known: true, value: input === "true" };
+      }
+      return { ok: false };
+    case "date":
+      if (isWeekDate(input)) return { ok: true, known: true, value: input };
+      return parseDate(layouts.date, input);
+    case "datetime":
+      return parseDate(layouts.datetime, input);
+    case "dateflex":
+      if (isWeekDate(input)) return { ok: true, known: true, value: input };
+      return parseDate(layouts.date.concat(layouts.datetime), input);
+  }
+  return { ok: true, known: false };
+}
+
+// zeroValue is the value of empty input, which is validated as any other.
+function zeroValue(spec) {
+  switch (spec.type) {
+    case "string":
+      return { ok: true, known: true, value: "" };
+    case "int":
+    case "int64":
+      return { ok: true, known: true, value: 0n };
+    case "float":
+      return { ok: true, known: true, value: 0 };
+    case "bool":
+      return { ok: true, known: true, value: false };
+  }
+  return { ok: true, known: false };
+}
+
+// parseDate tries the layouts, which are Go time layouts split into
+// literal and numeric chunks, or null for those using other chunks.
+function parseDate(list, input) {
+  let complete = true;
+  for (const layout of list) {
+    if (layout === null) {
+      complete = false;
+    } else if (parseLayout(layout, input)) {
+      return { ok: true, known: true, value: input };
+    }
+  }
+  return complete ? { ok: false } : { ok: true, known: false };
+}
+
+const weekDateRE = /^([0-9]{4})(-?)[Ww]([0-9]{2})(?:(-?)([1-7]))?$/;
+
+// isWeekDate returns true for ISO 8601 week dates, e.g. "2023-W14-3".
+function isWeekDate(input) {
+  const m = weekDateRE.exec(input);
+  if (!m || (m[5] !== undefined && m[2] !== m[4])) return false;
+  const week = Number(m[3]);
+  // The year has 53 weeks if it starts on a Thursday, or on a Wednesday
+  // in a leap year.
+  const year = Number(m[1]);
+  const jan1 = new Date(Date.UTC(year, 0, 1)).getUTCDay();
+  const long = jan1 === 4 || (jan1 === 3 && daysIn(2, year) === 29);
+  return week >= 1 && week <= (long ? 53 : 52);
+}
+
+function isDigit(s, i) {
+  return i < s.length && s[i] >= "0" && s[i] <= "9";
+}
+
+// getnum is Go's time.getnum: one or two digits, or exactly two if fixed.
+function getnum(s, fixed) {
+  if (!isDigit(s, 0)) return null;
+  if (!isDigit(s, 1)) {
+    if (fixed) return null;
+    return [Number(s[0]), s.slice(1)];
+  }
+  return [Number(s.slice(0, 2)), s.slice(2)];
+}
+
+// skip is Go's time.skip, where a space matches any number of spaces.
+function skip(value, prefix) {
+  while (prefix.length > 0) {
+    if (prefix[0] === " ") {
+      if (value.length > 0 && value[0] !== " ") return null;
+      prefix = prefix.replace(/^ +/, "");
+      value = value.replace(/^ +/, "");
+      continue;
+    }
+    if (value.length === 0 || value[0] !== prefix[0]) return null;
+    prefix = prefix.slice(1);
+    value = value.slice(1);
+  }
+  return value;
+}
+
+function daysIn(month, year) {
+  if (month === 2) {
+    const leap = year % 4 === 0 && (year % 100 !== 0 || year % 400 === 0);
+    return leap ? 29 : 28;
+  }
+  return [31, 0, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31][month - 1];
+}
+
+function parseLayout(layout, value) {
+  let year = 0;
+  let month = -1;
+  let day = -1;
+  for (const chunk of layout) {
+    if (chunk.lit !== undefined) {
+      value = skip(value, chunk.lit);
+      if (value === null) return false;
+      continue;
+    }
+    let n;
+    switch (chunk.std) {
+      case "2006":
+        if (!/^[0-9]{4}/.test(value)) return false;
+        year = Number(value.slice(0, 4));
+        value = value.slice(4);
+        continue;
+      case "01":
+      case "1":
+        n = getnum(value, chunk.std === "01");
+        if (n === null || n[0] < 1 || n[0] > 12) return false;
+        month = n[0];
+        break;
+      case "02":
+      case "2":
+        n = getnum(value, chunk.std === "02");
+        if (n === null) return false;
+        day = n[0];
+        break;
+      case "15":
+        n = getnum(value, false);
+        if (n === null || n[0] > 23) return false;
+        break;
+      case "04":
+      case "4":
+        n = getnum(value, chunk.std === "04");
+        if (n === null || n[0] > 59) return false;
+        break;
+      case "05":
+      case "5":
+        n = getnum(value, chunk.std === "05");
+        if (n === null || n[0] > 59) return false;
+        // Fractional seconds are accepted without a layout chunk.
+        n[1] = n[1].replace(/^[.,][0-9]+/, "");
+        break;
+      case "Z07:00":
+      case "Z0700":
+        if (value[0] === "Z") {
+          value = value.slice(1);
+          continue;
+        }
+      // fallthrough
+      case "-07:00":
+      case "-0700": {
+        // As in Go, the offset may be up to 24 hours and 60 minutes.
+        const colon = chunk.std.endsWith(":00");
+        const m = (colon ? /^[+-]([0-9]{2}):([0-9]{2})/ :
+          /^[+-]([0-9]{2})([0-9]{2})/).exec(value);
+        if (!m || Number(m[1]) > 24 || Number(m[2]) > 60) return false;
+        value = value.slice(m[0].length);
+        continue;
+      }
+      default:
+        return false;
+    }
+    value = n[1];
+  }
+  if (value !== "") return false;
+  if (month < 0) month = 1;
+  if (day < 0) day = 1;
+  return day >= 1 && day <= daysIn(month, year);
+}
+
+// Validation, as by the standard Validators.
+
+function check(spec, v) {
+  if (!spec.validate) return null;
+  switch (spec.type) {
+    case "string":
+      return checkString(spec, v);
+    case "int":
+    case "int64":
+      return checkInt(spec, v);
+    case "float":
+      return checkFloat(spec, v);
+  }
+  return null;
+}
+
+function checkString(spec, s) {
+  const n = glyphLength(s);
+  for (const l of spec.limits || []) {
+    const err = checkStringLimit(l, s, n);
+    if (err) return err;
+  }
+  return null;
+}
+
+function checkStringLimit(l, s, n) {
+  if (l.length > 0) {
+    if (n !== l.length) {
+      return ["wrong_length", [intParam("length", l.length)]];
+    }
+  } else if (l.range) {
+    const c = compareInt(l.range, BigInt(n));
+    if (c < 0) return ["too_short", rangeParams(l.range, intParam)];
+    if (c > 0) return ["too_long", rangeParams(l.range, intParam)];
+  } else if (l.pattern !== undefined) {
+    const re = regexpFor(l);
+    if (re && !re.test(s)) {
+      return ["bad_format", [["pattern", l.pattern, l.pattern]]];
+    }
+  } else if (l.listString && l.listString.includes(s) === !!l.not) {
+    return [listCode(l), [listParam("list", l.listString)]];
+  }
+  return null;
+}
+
+function checkInt(spec, i) {
+  for (const l of spec.limits || []) {
+    const err = checkIntLimit(l, i);
+    if (err) return err;
+  }
+  return null;
+}
+
+function checkIntLimit(l, i) {
+  if (l.length > 0) {
+    if (i.toString().length !== l.length) {
+      return ["wrong_length", [intParam("length", l.length)]];
+    }
+  } else if (l.range) {
+    const r = l.range;
+    const c = compareInt(r, i);
+    if (c < 0) return ["too_low", rangeParams(r, intParam)];
+    if (c > 0) return ["too_high", rangeParams(r, intParam)];
+    if (r.step !== undefined &&
+        (i - BigInt(r.base)) % BigInt(r.step) !== 0n) {
+      return ["wrong_step", rangeParams(r, intParam)];
+    }
+  } else if (l.listInt &&
+      l.listInt.some((item) => BigInt(item) === i) === !!l.not) {
+    return [listCode(l), [listParam("list", l.listInt.map(Number),
+      l.listInt)]];
+  }
+  return null;
+}
+
+function checkFloat(spec, f) {
+  for (const l of spec.limits || []) {
+    const err = checkFloatLimit(l, f);
+    if (err) return err;
+  }
+  return null;
+}
+
+function checkFloatLimit(l, f) {
+  const r = l.range;
+  if (!r) return null;
+  if (r.min !== undefined && (f < r.min || r.minExcl && f === r.min)) {
+    return ["too_low", rangeParams(r, floatParam)];
+  }
+  if (r.max !== undefined && (f > r.max || r.maxExcl && f === r.max)) {
+    return ["too_high", rangeParams(r, floatParam)];
+  }
+  if (r.step !== undefined) {
+    const q = (f - r.base) / r.step;
+    if (!(Math.abs(q - Math.round(q)) <= 1e-9 * Math.max(1, Math.abs(q)))) {
+      return ["wrong_step", rangeParams(r, floatParam)];
+    }
+  }
+  return null;
+}
+
+function listCode(l) {
+  return l.not ? "not_allowed" : "not_in_list";
+}
+
+// compareInt mirrors numRange.compareInt, for integer bounds.
+function compareInt(r, i) {
+  if (r.min !== undefined && i < BigInt(r.min)) return -1;
+  if (r.max !== undefined && i > BigInt(r.max)) return 1;
+  return 0;
+}
+
+function rangeParams(r, param) {
+  const params = [];
+  if (r.min !== undefined) params.push(param("min", conv(r.min)));
+  if (r.max !== undefined) params.push(param("max", conv(r.max)));
+  if (r.step !== undefined) params.push(param("step", conv(r.step)));
+  return params;
+
+  function conv(v) {
+    return param === intParam ? BigInt(v) : v;
+  }
+}
+
+function regexpFor(l) {
+  if (l.re === undefined) {
+    l.re = null;
+    if (l.jsPattern) {
+      try {
+        l.re = new RegExp(l.pattern, "u");
+      } catch (e) {
+        try {
+          l.re = new RegExp(l.pattern);
+        } catch (e2) {
+          l.re = null;
+        }
+      }
+    }
+  }
+  return l.re;
+}
+
+// glyphLength mirrors GlyphLength: the number of segments of the NFKD
+// form, each a starter and any following non-starters; but precomposed
+// Hangul syllables count as one.
+function glyphLength(s) {
+  let count = 0;
+  let run = "";
+  const flush = () => {
+    let started = false;
+    for (const c of run.normalize("NFKD")) {
+      if (!started || !isNonStarter(c)) count++;
+      started = true;
+    }
+    run = "";
+  };
+  for (const c of s) {
+    const cp = c.codePointAt(0);
+    if (cp >= 0xac00 && cp <= 0xd7a3) {
+      flush();
+      count++;
+    } else {
+      run += c;
+    }
+  }
+  flush();
+  return count;
+}
+
+// isNonStarter reports whether the normalized character c has a nonzero
+// canonical combining class, by checking whether it is reordered.
+function isNonStarter(c) {
+  if (c.codePointAt(0) < 0x300) return false;
+  return (c + "\u0334").normalize("NFD").startsWith("\u0334") ||
+    ("\u0301" + c).normalize("NFD").startsWith(c);
+}
+
+// Errors and messages, with params formatted as by Go's fmt.Sprint.
+
+function intParam(name, v) {
+  return [name, Number(v), v.toString()];
+}
+
+function floatParam(name, v) {
+  return [name, v, goFloat(v)];
+}
+
+function listParam(name, list, texts) {
+  return [name, list, "[" + (texts || list).join(" ") + "]"];
+}
+
+function goFloat(x) {
+  if (Number.isNaN(x)) return "NaN";
+  if (!Number.isFinite(x)) return x > 0 ? "+Inf" : "-Inf";
+  if (x === 0) return Object.is(x, -0) ? "-0" : "0";
+  const [mant, e] = x.toExponential().split("e");
+  const exp = Number(e);
+  if (exp < -4 || exp >= 6) {
+    const digits = String(Math.abs(exp)).padStart(2, "0");
+    return mant + "e" + (exp < 0 ? "-" : "+") + digits;
+  }
+  return String(x);
+}
+
+function conversionError(spec, key, input) {
+  return fieldError(spec, key, "conversion", [["type", spec.type,
+    spec.type]], input);
+}
+
+function fieldError(spec, key, code, params, input) {
+  if (spec.sensitive) input = "";
+  const e = { key: key, name: spec.name, code: code, params: {}, input: input };
+  const texts = {};
+  for (const [name, value, text] of params) {
+    e.params[name] = value;
+    texts[name] = text;
+  }
+  e.message = message(e, texts, spec.messages || {});
+  return e;
+}
+
+function message(e, texts, specMessages) {
+  let msg;
+  if (Object.prototype.hasOwnProperty.call(specMessages, e.code)) {
+    msg = specMessages[e.code];
+  } else if (Object.prototype.hasOwnProperty.call(messages, e.code)) {
+    msg = messages[e.code];
+  } else {
+    return e.name + ": " + e.code;
+  }
+  let out = "";
+  for (;;) {
+    const start = msg.indexOf("{");
+    if (start < 0) break;
+    const end = msg.indexOf("}", start);
+    if (end < 0) break;
+    out += msg.slice(0, start) + placeholder(msg.slice(start + 1, end), e,
+      texts);
+    msg = msg.slice(end + 1);
+  }
+  return out + msg;
+}
+
+function placeholder(name, e, texts) {
+  switch (name) {
+    case "name":
+      return e.name;
+    case "key":
+      return e.key;
+    case "input":
+      return e.input;
+  }
+  if (Object.prototype.hasOwnProperty.call(texts, name)) return texts[name];
+  return "{" + name + "}";
+}
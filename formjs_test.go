@@ -0,0 +1,282 @@
+// formjs_test.go
+// --------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_ValidatorJS(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("name", "string", "2-10", "Name"),
+	}
+	js := vebben.ValidatorJS(specs)
+	assert.Contains(js, "export function validate(input)")
+	assert.Contains(js, `const specs = [{"key":"name","name":"Name",`+
+		`"type":"string","required":true,"validate":true,`+
+		`"limits":[{"range":{"min":"2","max":"10"}}]}];`)
+	assert.Contains(js, `"required":"{name} is required"`)
+	assert.Contains(js, `{"std":"2006"},{"lit":"-"},{"std":"01"}`)
+	assert.NotContains(js, "/*SPECS*/")
+	assert.NotContains(js, "/*MESSAGES*/")
+	assert.NotContains(js, "/*LAYOUTS*/")
+	assert.NotContains(js, "/*TRIM*/")
+	assert.Contains(js, "const localNumbers = false;")
+
+	js = vebben.ValidatorJS(specs, vebben.Language(language.Hungarian))
+	assert.Contains(js, `"required":"{name} megadása kötelező"`)
+
+	// Custom Validators are left to the server.
+	custom := specs[0].Copy("custom", "")
+	custom.SetValidator(func(*vebben.FormSpec, interface{}) error {
+		return nil
+	})
+	js = vebben.ValidatorJS([]*vebben.FormSpec{custom})
+	assert.Contains(js, `"required":true,"limits":`)
+	assert.NotContains(js, `"validate":true`)
+
+}
+
+func Test_Decoder_ValidatorJS(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{vebben.OptionalFormSpec("day", "date")}
+	dec := vebben.NewDecoder()
+	dec.DateFormats = []string{"01/02/2006"}
+	dec.TrimSpace = false
+	dec.NumberLocale = language.Hungarian
+	dec.RelativeDates = true
+
+	js := dec.ValidatorJS(specs)
+	assert.Contains(js, `"date":[[{"std":"01"},{"lit":"/"},{"std":"02"},`+
+		`{"lit":"/"},{"std":"2006"}],null]`)
+	assert.Contains(js, "const trimInputs = false;")
+	assert.Contains(js, "const localNumbers = true;")
+
+	// The package-level settings are unchanged.
+	js = vebben.ValidatorJS(specs)
+	assert.Contains(js, `"date":[[{"std":"2006"},{"lit":". "}`)
+	assert.Contains(js, "const trimInputs = true;")
+	assert.Contains(js, "const localNumbers = false;")
+}
+
+// parityCatalog has messages showing all the params, for all codes.
+func parityCatalog() *vebben.Catalog {
+	c := vebben.NewCatalog()
+	msg := "{name}|{key}|{input}|{min}|{max}|{length}|{list}|{pattern}|" +
+		"{type}|{count}|{step}|{nope}|{unclosed"
+	for _, code := range []string{
+		vebben.CodeRequired, vebben.CodeConversion, vebben.CodeWrongLength,
+		vebben.CodeTooShort, vebben.CodeTooLong, vebben.CodeTooLow,
+		vebben.CodeTooHigh, vebben.CodeBadFormat, vebben.CodeNotInList,
+		vebben.CodeTooFew, vebben.CodeTooMany, vebben.CodeForbidden,
+		vebben.CodeWrongStep, vebben.CodeNotAllowed,
+	} {
+		c.Set(language.English, code, code+": "+msg)
+	}
+	return c
+}
+
+func paritySpecs() []*vebben.FormSpec {
+
+	company := vebben.OptionalFormSpec("company", "string", "", "Company")
+	company.RequiredIf = []vebben.Condition{vebben.When("invoice", "company")}
+	company.ForbiddenIf = []vebben.Condition{vebben.When("invoice", "person")}
+	reason := vebben.OptionalFormSpec("reason", "string")
+	reason.RequiredIf = []vebben.Condition{vebben.Unless("phone")}
+	tags := vebben.OptionalFormSpec("tags", "[]string", "1-5", "Tags")
+	tags.MinItems = 2
+	tags.MaxItems = 3
+	qty := vebben.OptionalFormSpec("qty", "int", "1-10", "Quantity")
+	qty.RequiredIf = []vebben.Condition{vebben.When("sku")}
+	items := vebben.RepeatedFormSpec("items", []*vebben.FormSpec{
+		vebben.OptionalFormSpec("sku", "string", `re:^[A-Z]-\d+$`, "SKU"),
+		qty,
+	})
+	items.MaxItems = 2
+	pin := vebben.OptionalFormSpec("pin", "string", "4", "PIN")
+	pin.Sensitive = true
+	pin.Messages = vebben.NewCatalog()
+	pin.Messages.Set(language.Und, vebben.CodeWrongLength,
+		"{name} needs {length} digits, not {input}")
+
+	return []*vebben.FormSpec{
+		vebben.RequiredFormSpec("name", "string", "2-5", "Name"),
+		vebben.OptionalFormSpec("code", "string", "4", "Code"),
+		vebben.OptionalFormSpec("sku", "string", `re:^[A-Z]\d+$`, "SKU"),
+		vebben.OptionalFormSpec("color", "string", "red,green", "Color"),
+		vebben.OptionalFormSpec("size", "int", "1-4", "Size"),
+		vebben.OptionalFormSpec("zip", "int", "4", "Zip"),
+		vebben.OptionalFormSpec("odd", "int64", "1,3,5", "Odd"),
+		vebben.OptionalFormSpec("big", "int64", "", "Big"),
+		vebben.OptionalFormSpec("strength", "float", "0.5-1.5", "Strength"),
+		vebben.OptionalFormSpec("weight", "float", "1-1000000", "Weight"),
+		vebben.OptionalFormSpec("temp", "int", "-40-50 step=5", "Temp"),
+		vebben.OptionalFormSpec("floor", "int64", ">-3 <100 step=2", "Floor"),
+		vebben.OptionalFormSpec("ratio", "float", ">0 <=1 step=0.1", "Ratio"),
+		vebben.OptionalFormSpec("debt", "float", "<0", "Debt"),
+		vebben.OptionalFormSpec("note", "string", "3-", "Note"),
+		vebben.OptionalFormSpec("user", "string",
+			"len=3-8;re=^[a-z0-9_]+$;not=admin,root", "User"),
+		vebben.OptionalFormSpec("lucky", "int", "not=13,42;range=1-99", "Lucky"),
+		vebben.OptionalFormSpec("ok", "bool", "", "OK"),
+		vebben.OptionalFormSpec("day", "date", "", "Day"),
+		vebben.OptionalFormSpec("at", "datetime", "", "At"),
+		vebben.OptionalFormSpec("flex", "dateflex", "", "Flex"),
+		tags,
+		vebben.OptionalFormSpec("nums", "[]int", "1,2,3", "Numbers"),
+		vebben.OptionalFormSpec("invoice", "string", "person,company"),
+		company,
+		vebben.OptionalFormSpec("phone", "string"),
+		reason,
+		vebben.GroupFormSpec("address", []*vebben.FormSpec{
+			vebben.RequiredFormSpec("city", "string", "", "City"),
+		}),
+		items,
+		pin,
+	}
+}
+
+// parityForms are the shared fixtures; all have the required values.
+var parityForms = []map[string][]string{
+	{},
+	{"name": {"Joe"}, "phone": {"1"}, "address.city": {"X"}},
+	{"name": {"  Joe  "}, "reason": {"x"}, "address.city": {"X"}},
+	{"name": {"J"}, "code": {"abc"}, "sku": {"a1"}, "color": {"blue"}},
+	{"name": {"Joseph"}, "code": {"abcd"}, "sku": {"A1"}, "color": {"red"}},
+	{"name": {"각각"}, "code": {"ﬁﬁ"}},
+	{"name": {"éé"}, "code": {"हिंदी"}},
+	{"name": {"\u0301a"}, "code": {"👨‍👩‍👧"}},
+	{"name": {"\u0085Jo "}, "code": {"\u3000abcd "}},
+	{"name": {"\ufeffJo"}, "size": {"\ufeff1"}},
+	{"size": {"0"}, "zip": {"99999"}, "odd": {"2"}, "big": {"x"}},
+	{"pin": {"123"}},
+	{"size": {"+3"}, "zip": {"-123"}, "odd": {"5"}, "big": {"-9"}},
+	{"size": {"5"}, "zip": {"1234"}, "big": {"9223372036854775807"}},
+	{"size": {"2147483648"}, "big": {"9223372036854775808"}},
+	{"size": {"3.0"}, "zip": {"1_2"}, "big": {" 7 "}},
+	{"strength": {"0.4"}, "weight": {"1000001"}},
+	{"strength": {"1_0.5"}, "weight": {"0.5"}},
+	{"strength": {"nan"}, "weight": {"+Inf"}},
+	{"strength": {"-infinity"}, "weight": {"+nan"}},
+	{"strength": {"1e400"}, "weight": {"1e-400"}},
+	{"strength": {".5"}, "weight": {"5."}},
+	{"strength": {"1e"}, "weight": {"1._0"}},
+	{"strength": {"0x1p-1"}, "weight": {"1e+3"}},
+	{"temp": {"-40"}, "floor": {"-3"}, "ratio": {"0"}, "debt": {"0"}},
+	{"temp": {"-41"}, "floor": {"-2"}, "ratio": {"0.3"}, "debt": {"-0.1"}},
+	{"temp": {"-35"}, "floor": {"-1"}, "ratio": {"1"}, "note": {"ab"}},
+	{"temp": {"51"}, "floor": {"98"}, "ratio": {"0.35"}, "note": {"abc"}},
+	{"temp": {"12"}, "floor": {"100"}, "ratio": {"1.1"}, "debt": {"-1e9"}},
+	{"user": {"ab"}, "lucky": {"13"}},
+	{"user": {"Joe"}, "lucky": {"0"}},
+	{"user": {"root"}, "lucky": {"42"}},
+	{"user": {"joe_1"}, "lucky": {"7"}},
+	{"user": {"a;b"}, "lucky": {"100"}},
+	{"ok": {"true"}}, {"ok": {"yes"}}, {"ok": {"False"}},
+	{"day": {"2023-02-29"}, "at": {"2023-01-02T15:04"}},
+	{"day": {"2024-02-29"}, "at": {"20230102150405.123"}},
+	{"day": {"2023. 1. 2."}, "at": {"2023-01-02 24:00"}},
+	{"day": {"2023   01 02"}, "at": {"2023-01-02 9:05"}},
+	{"day": {"2.1.2023"}, "at": {"2023-01-02 09:5"}},
+	{"day": {"13/01/2023"}, "at": {"2023-1-2 10:10"}},
+	{"day": {"20230102"}, "at": {"2023.01.02.  10:10"}},
+	{"day": {"2023-04-31"}, "flex": {"2023-01-02"}},
+	{"day": {"2023-1-02x"}, "flex": {"2023-01-02 10:10"}},
+	{"day": {"02.01.2023"}, "flex": {"tomorrow"}},
+	{"day": {"2023-W14-3"}, "at": {"2023-04-05T10:30:15"}},
+	{"day": {"2026W537"}, "at": {"2023-04-05T10:30:15.250+02:00"}},
+	{"day": {"2023-W53-1"}, "at": {"2023-04-05T10:30Z"}},
+	{"day": {"2023-W14"}, "at": {"20230405T103015+0200"}},
+	{"day": {"2023-W141"}, "at": {"2023-04-05T10:30:15+25:00"}},
+	{"day": {"2023W00"}, "at": {"2023-04-05 10:30:15Z"}},
+	{"flex": {"2023w14-7"}, "at": {"2023-04-05T10:30:15+0200"}},
+	{"tags": {"a"}, "nums": {"1", "4"}},
+	{"tags": {"a", " ", "b"}, "nums": {"1", "x", "3"}},
+	{"tags": {"a", "b", "c", "d"}, "nums": {"", "2"}},
+	{"tags": {"abcdef", "b"}},
+	{"invoice": {"company"}},
+	{"invoice": {"person"}, "company": {"ACME"}},
+	{"invoice": {"person"}, "company": {" "}, "phone": {"1"}},
+	{"items[0].sku": {"A-1"}, "items[0].qty": {"0"}},
+	{"items[2].sku": {"A-1"}, "items[5].qty": {"3"}, "items[x].qty": {"1"}},
+	{"items[0].sku": {"a"}, "items[1].qty": {"1"}, "items[3].qty": {"2"}},
+	{"items[+1].sku": {"A-1"}, "items[1]x": {"1"}, "items[2].y": {"1"}},
+}
+
+func Test_ValidatorJS_Parity(t *testing.T) {
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+
+	dir := t.TempDir()
+	runner := `import { validate } from "./validator.mjs";
+import { readFileSync } from "node:fs";
+const forms = JSON.parse(readFileSync(0, "utf8"));
+console.log(JSON.stringify(forms.map((f) =>
+  validate(f).map((e) => [e.key, e.code, e.message]))));
+`
+	if err := os.WriteFile(filepath.Join(dir, "runner.mjs"), []byte(runner),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	input, _ := json.Marshal(parityForms)
+
+	specs := paritySpecs()
+	for name, opts := range map[string][]vebben.DecodeOption{
+		"default":   nil,
+		"hungarian": {vebben.Language(language.Hungarian)},
+		"params":    {vebben.WithCatalog(parityCatalog())},
+	} {
+		js := vebben.ValidatorJS(specs, opts...)
+		if err := os.WriteFile(filepath.Join(dir, "validator.mjs"),
+			[]byte(js), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(node, "runner.mjs")
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(string(input))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: node failed: %s\n%s", name, err, out)
+		}
+		var client [][][]string
+		if err := json.Unmarshal(out, &client); err != nil {
+			t.Fatalf("%s: bad output: %s\n%s", name, err, out)
+		}
+
+		for idx, form := range parityForms {
+			server := [][]string{}
+			err := vebben.DecodeForm(vebben.URLValues(form), specs,
+				&map[string]interface{}{}, opts...)
+			if err != nil {
+				for _, fe := range err.(*vebben.MultiError).FieldErrors() {
+					server = append(server, []string{fe.Key, fe.Code,
+						fe.Error()})
+				}
+			}
+			assert.Equal(t, server, client[idx], "%s: %v", name, form)
+		}
+	}
+
+}
@@ -0,0 +1,376 @@
+// formlimits.go -- parsing and checking of FormSpec limits.
+// -------------
+
+package vebben
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+)
+
+// formLimit is one constraint of a Limit.  Exactly one of length, rng, re,
+// schemes, the decimal settings, precision and the lists is set; not marks
+// a list of values that are not allowed.
+type formLimit struct {
+	length     int
+	rng        *numRange
+	re         *regexp.Regexp
+	listString []string
+	listInt    []int64
+	not        bool
+	schemes    []string // for URLs
+	scale      int      // for decimals, if hasScale
+	hasScale   bool
+	rounding   string   // for decimals
+	currencies []string // for money
+	precision  string   // for datetimes
+}
+
+// timePrecisions are the precisions of datetimes, by name.
+var timePrecisions = map[string]time.Duration{
+	"minute":      time.Minute,
+	"second":      time.Second,
+	"millisecond": time.Millisecond,
+}
+
+var formSpecLimitMatchLength = regexp.MustCompile("^[1-9][0-9]*$")
+
+// formSpecLimitClauses are the names of the clauses of composite limits.
+const formSpecLimitClauses = "(len|range|re|in|not|schemes|scale|round|currency|precision)="
+
+var formSpecLimitMatchClauses = regexp.MustCompile("^" + formSpecLimitClauses)
+var formSpecLimitMatchClause = regexp.MustCompile(";" + formSpecLimitClauses)
+var formSpecLimitMatchUnknown = regexp.MustCompile(";([a-z]+)=")
+
+// parseLimits parses the Limit for item type t into its constraints, in
+// order, panicking with a description of any problem.
+func parseLimits(limit, t string) []*formLimit {
+
+	if !formSpecLimitMatchClauses.MatchString(limit) {
+		return []*formLimit{parseLimit(limit, t)}
+	}
+
+	limit = ";" + limit
+	locs := formSpecLimitMatchClause.FindAllStringSubmatchIndex(limit, -1)
+	limits := make([]*formLimit, len(locs))
+	seen := map[string]bool{}
+	for idx, loc := range locs {
+		name := limit[loc[2]:loc[3]]
+		end := len(limit)
+		if idx+1 < len(locs) {
+			end = locs[idx+1][0]
+		}
+		val := limit[loc[1]:end]
+		if seen[name] {
+			panic("Bad limit: more than one " + name + " clause")
+		}
+		seen[name] = true
+		if val == "" {
+			panic("Bad limit: empty " + name + " clause")
+		}
+		// Only regexps may contain other text that looks like a clause.
+		if m := formSpecLimitMatchUnknown.FindStringSubmatch(val); m != nil &&
+			name != "re" {
+			panic("Bad limit: unknown clause " + m[1])
+		}
+		limits[idx] = parseLimitClause(name, val, t)
+	}
+	return limits
+}
+
+// parseLimitClause parses one clause of a composite limit.
+func parseLimitClause(name, val, t string) *formLimit {
+
+	length := isTextType(t) || t == "file"
+	numeric := isNumericType(t)
+	decimal := t == "decimal" || t == "money"
+	switch name {
+	case "len":
+		if formSpecLimitMatchLength.MatchString(val) {
+			return parseLimit(val, t)
+		}
+		if !length {
+			panic("Length range does not apply to " + t)
+		}
+		return &formLimit{rng: parseRangeLimit(val, t)}
+	case "range":
+		if !numeric {
+			panic("Value range does not apply to " + t)
+		}
+		return &formLimit{rng: parseRangeLimit(val, t)}
+	case "re":
+		if !length {
+			panic("Regexp limit does not apply to " + t)
+		}
+		return parseLimit("re:"+val, t)
+	case "in", "not":
+		l := parseListLimit(val, t)
+		l.not = name == "not"
+		return l
+	case "schemes":
+		if t != "url" {
+			panic("Schemes limit does not apply to " + t)
+		}
+		return &formLimit{schemes: strings.Split(strings.ToLower(val), ",")}
+	case "scale":
+		if !decimal {
+			panic("Scale limit does not apply to " + t)
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 || n > maxDecimalScale {
+			panic("Bad scale limit: " + val)
+		}
+		return &formLimit{scale: n, hasScale: true}
+	case "round":
+		if !decimal {
+			panic("Rounding does not apply to " + t)
+		}
+		if !roundingModes[val] {
+			panic("Bad rounding mode: " + val)
+		}
+		return &formLimit{rounding: val}
+	case "currency":
+		if t != "money" {
+			panic("Currency limit does not apply to " + t)
+		}
+		codes := strings.Split(strings.ToUpper(val), ",")
+		for _, code := range codes {
+			if _, err := currency.ParseISO(code); err != nil {
+				panic("Bad currency code: " + code)
+			}
+		}
+		return &formLimit{currencies: codes}
+	case "precision":
+		if t != "datetime" && t != "dateflex" {
+			panic("Precision limit does not apply to " + t)
+		}
+		if _, ok := timePrecisions[val]; !ok {
+			panic("Bad precision limit: " + val)
+		}
+		return &formLimit{precision: val}
+	}
+	panic("Bad limit: unknown clause " + name)
+}
+
+// parseLimit parses a limit with a single constraint.
+func parseLimit(val, t string) *formLimit {
+
+	// Regexp limit (for strings, and file names):
+	if strings.HasPrefix(val, "re:") {
+		re, err := regexp.Compile(strings.TrimPrefix(val, "re:"))
+		if err != nil {
+			panic("Error compiling limit regexp: " + err.Error())
+		}
+		return &formLimit{re: re}
+	}
+
+	// Length limit:
+	if formSpecLimitMatchLength.MatchString(val) {
+
+		// Only useful for strings and int-ies.
+		if !isTextType(t) && t != "int" && t != "int64" && t != "file" {
+			panic("Length limit does not apply to " + t)
+		}
+		i, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			panic("Error parsing int for length limit: " + err.Error())
+		}
+		return &formLimit{length: int(i)}
+	}
+
+	// Range limit (numeric, or for strings and file names, length); numeric
+	// limits other than lists must be ranges:
+	numeric := isNumericType(t)
+	if isRangeLimit(val) || numeric && !strings.Contains(val, ",") {
+		return &formLimit{rng: parseRangeLimit(val, t)}
+	}
+
+	// Set of strings limit:
+	return parseListLimit(val, t)
+}
+
+// parseListLimit parses a comma-separated list of values.
+func parseListLimit(val, t string) *formLimit {
+
+	vals := strings.Split(val, ",")
+	switch {
+	case isTextType(t):
+		return &formLimit{listString: vals}
+	case t == "int" || t == "int64":
+		ints := make([]int64, len(vals))
+		for idx, s := range vals {
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				panic("Bad integer in list: " + err.Error())
+			}
+			ints[idx] = i
+		}
+		return &formLimit{listInt: ints}
+	}
+	panic("Value list not compatible with type " + t)
+}
+
+// isTextType returns true if limits apply to values of type t as they do
+// to strings, i.e. to their string form.
+func isTextType(t string) bool {
+	ft := standardFormSpecTypes[t]
+	return t == "string" || ft != nil && ft.text
+}
+
+// isNumericType returns true if range limits apply to the values of type t.
+func isNumericType(t string) bool {
+	switch t {
+	case "int", "int64", "float", "decimal", "money":
+		return true
+	}
+	return false
+}
+
+// listCode returns the error code for a value not passing a list limit.
+func (l *formLimit) listCode() string {
+	if l.not {
+		return CodeNotAllowed
+	}
+	return CodeNotInList
+}
+
+// checkString checks the string s, of glyph length n.
+func (l *formLimit) checkString(fs *FormSpec, s string, n int) error {
+
+	switch {
+	case l.length > 0:
+		if n != l.length {
+			return fs.FieldError(CodeWrongLength,
+				map[string]interface{}{"length": l.length})
+		}
+	case l.rng != nil:
+		switch l.rng.compareInt(int64(n)) {
+		case -1:
+			return fs.FieldError(CodeTooShort, l.rng.params())
+		case 1:
+			return fs.FieldError(CodeTooLong, l.rng.params())
+		}
+	case l.re != nil:
+		if !l.re.MatchString(s) {
+			return fs.FieldError(CodeBadFormat,
+				map[string]interface{}{"pattern": l.re.String()})
+		}
+	case len(l.listString) > 0:
+		have := false
+		for _, item := range l.listString {
+			if s == item {
+				have = true
+				break
+			}
+		}
+		if have == l.not {
+			return fs.FieldError(l.listCode(),
+				map[string]interface{}{"list": l.listString})
+		}
+	}
+	return nil
+}
+
+// checkInt checks the integer i, for the int and int64 types.
+func (l *formLimit) checkInt(fs *FormSpec, i int64) error {
+
+	switch {
+	case l.length > 0:
+		// can't len(int) so we cheat...
+		if len(fmt.Sprintf("%d", i)) != l.length {
+			return fs.FieldError(CodeWrongLength,
+				map[string]interface{}{"length": l.length})
+		}
+	case l.rng != nil:
+		switch l.rng.compareInt(i) {
+		case -1:
+			return fs.FieldError(CodeTooLow, l.rng.params())
+		case 1:
+			return fs.FieldError(CodeTooHigh, l.rng.params())
+		}
+		if !l.rng.onStepInt(i) {
+			return fs.FieldError(CodeWrongStep, l.rng.params())
+		}
+	case len(l.listInt) > 0:
+		have := false
+		for _, item := range l.listInt {
+			if i == item {
+				have = true
+				break
+			}
+		}
+		if have == l.not {
+			return fs.FieldError(l.listCode(),
+				map[string]interface{}{"list": l.listInt})
+		}
+	}
+	return nil
+}
+
+// checkFloat checks the float f.
+func (l *formLimit) checkFloat(fs *FormSpec, f float64) error {
+
+	if l.rng == nil {
+		return nil
+	}
+	switch l.rng.compareFloat(f) {
+	case -1:
+		return fs.FieldError(CodeTooLow, l.rng.params())
+	case 1:
+		return fs.FieldError(CodeTooHigh, l.rng.params())
+	}
+	if !l.rng.onStepFloat(f) {
+		return fs.FieldError(CodeWrongStep, l.rng.params())
+	}
+	return nil
+}
+
+// checkTime checks the precision of the datetime t.
+func (l *formLimit) checkTime(fs *FormSpec, t time.Time) error {
+
+	if l.precision == "" {
+		return nil
+	}
+	ns := time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+	if ns%timePrecisions[l.precision] != 0 {
+		return fs.FieldError(CodeBadPrecision,
+			map[string]interface{}{"precision": l.precision})
+	}
+	return nil
+}
+
+// timePrecision returns the precision limit of fs, or an empty string.
+func (fs *FormSpec) timePrecision() string {
+	for _, l := range fs.limits {
+		if l.precision != "" {
+			return l.precision
+		}
+	}
+	return ""
+}
+
+// rangeLimit returns the first range constraint of fs, or nil.
+func (fs *FormSpec) rangeLimit() *numRange {
+	for _, l := range fs.limits {
+		if l.rng != nil {
+			return l.rng
+		}
+	}
+	return nil
+}
+
+// listLimit returns the first list of allowed values of fs, or nil.
+func (fs *FormSpec) listLimit() *formLimit {
+	for _, l := range fs.limits {
+		if !l.not && (len(l.listString) > 0 || len(l.listInt) > 0) {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// formlimits_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FormSpec_CombinedLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	user := "len=3-20;re=^[a-z0-9_]+$;not=admin,root"
+	for _, tc := range []struct {
+		typ, limit, input, code string
+		params                  map[string]interface{}
+	}{
+		{"string", user, "joe_1", "", nil},
+		{"string", user, "jo", "too_short",
+			map[string]interface{}{"min": int64(3), "max": int64(20)}},
+		{"string", user, "Joe", "bad_format",
+			map[string]interface{}{"pattern": "^[a-z0-9_]+$"}},
+		{"string", user, "root", "not_allowed",
+			map[string]interface{}{"list": []string{"admin", "root"}}},
+		{"string", "not=x;len=3", "x", "not_allowed",
+			map[string]interface{}{"list": []string{"x"}}},
+		{"string", "len=3;not=x", "x", "wrong_length",
+			map[string]interface{}{"length": 3}},
+		{"string", "in=a,bb,ccc;len=2-", "a", "too_short",
+			map[string]interface{}{"min": int64(2)}},
+		{"string", "re=^a;b=c$", "a;b=c", "", nil},
+		{"string", "re=^a;b=c$", "a", "bad_format",
+			map[string]interface{}{"pattern": "^a;b=c$"}},
+		{"string", "re=^a;b=c+$;len=6", "a;b=cc", "", nil},
+		{"string", "re=^a;b=c+$;len=6", "a;b=c", "wrong_length",
+			map[string]interface{}{"length": 6}},
+		{"string", "re=^a;b$", "a;b", "", nil},
+		{"string", "re=^a;b$", "ab", "bad_format",
+			map[string]interface{}{"pattern": "^a;b$"}},
+		{"int", "range=1-99 step=2;not=13", "13", "not_allowed",
+			map[string]interface{}{"list": []int64{13}}},
+		{"int", "range=1-99 step=2;not=13", "12", "wrong_step",
+			map[string]interface{}{"min": int64(1), "max": int64(99),
+				"step": int64(2)}},
+		{"int64", "len=4;range=>=1000", "0999", "wrong_length",
+			map[string]interface{}{"length": 4}},
+		{"int", "in=1,2,3", "4", "not_in_list",
+			map[string]interface{}{"list": []int64{1, 2, 3}}},
+		{"float", "range=-1-1", "-1.5", "too_low",
+			map[string]interface{}{"min": -1.0, "max": 1.0}},
+	} {
+		desc := fmt.Sprintf("%s %q %q", tc.typ, tc.limit, tc.input)
+		specs := []*vebben.FormSpec{
+			vebben.OptionalFormSpec("x", tc.typ, tc.limit),
+		}
+		err := vebben.DecodeForm(vebben.URLValues{"x": {tc.input}}, specs,
+			&map[string]interface{}{})
+		if tc.code == "" {
+			assert.Nil(err, desc)
+			continue
+		}
+		if !assert.Error(err, desc) {
+			continue
+		}
+		fes := err.(*vebben.MultiError).FieldErrors()
+		if assert.Len(fes, 1, desc) {
+			assert.Equal(tc.code, fes[0].Code, desc)
+			assert.Equal(tc.params, fes[0].Params, desc)
+		}
+	}
+}
+
+func Test_FormSpec_CombinedLimits_Message(t *testing.T) {
+
+	spec := vebben.OptionalFormSpec("user", "string", "len=3-;not=root",
+		"User name")
+	err := vebben.DecodeForm(vebben.URLValues{"user": {"root"}},
+		[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+	assert.EqualError(t, err, "User name has a value that is not allowed")
+}
+
+func Test_FormSpec_CombinedLimits_Panics(t *testing.T) {
+
+	for _, tc := range []struct {
+		typ, limit, msg string
+	}{
+		{"string", "len=3;len=4", "Bad limit: more than one len clause"},
+		{"string", "len=3;re=", "Bad limit: empty re clause"},
+		{"string", "len=3;max=4", "Bad limit: unknown clause max"},
+		{"string", "in=a;b=c", "Bad limit: unknown clause b"},
+		{"int", "len=1-3", "Length range does not apply to int"},
+		{"bool", "len=3", "Length limit does not apply to bool"},
+		{"string", "range=1-3", "Value range does not apply to string"},
+		{"int", "re=^1", "Regexp limit does not apply to int"},
+		{"float", "not=1.5", "Value list not compatible with type float"},
+		{"int", "in=1,x", `Bad integer in list: strconv.ParseInt: parsing "x": invalid syntax`},
+		{"string", "re=(", "Error compiling limit regexp: error parsing regexp: missing closing ): `(`"},
+		{"int", "range=1-10;in=1,2;range=3-", "Bad limit: more than one range clause"},
+	} {
+		spec := &vebben.FormSpec{Key: "x", Type: tc.typ, Limit: tc.limit}
+		testig.AssertPanicsWith(t, func() { spec.Init() }, tc.msg,
+			"panic for "+tc.limit)
+	}
+}
+
+func Test_FormInput_CombinedLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	html, err := vebben.FormInput(vebben.OptionalFormSpec("x", "string",
+		"len=3-20;re=^[a-z]+$;not=admin"), nil, nil)
+	if assert.Nil(err) {
+		assert.Equal(`<input name="x" id="x" type="text" minlength="3" `+
+			`maxlength="20" pattern="^[a-z]+$" value="">`, string(html))
+	}
+
+	html, err = vebben.FormInput(vebben.OptionalFormSpec("x", "int",
+		"not=2;in=1,2,3"), nil, nil)
+	if assert.Nil(err) {
+		assert.Contains(string(html), `<option value="1">1</option>`)
+	}
+}
+
+func Test_JSONSchema_CombinedLimits(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("a", "string", "len=3-20;re=^[a-z]+$;not=admin,root"),
+		vebben.OptionalFormSpec("b", "int64", "len=4;range=>=1000;not=1234"),
+	}
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema",`+
+		`"properties":{`+
+		`"a":{"maxLength":20,"minLength":3,"not":{"enum":["admin","root"]},"pattern":"^[a-z]+$","type":"string"},`+
+		`"b":{"minimum":1000,"not":{"enum":[1234]},"type":"integer"}},`+
+		`"type":"object"}`, schemaJSON(t, specs))
+}
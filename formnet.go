@@ -0,0 +1,219 @@
+// formnet.go -- network identifier FormSpec types.
+// ----------
+
+package vebben
+
+import (
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// URLSchemes are the schemes accepted by the "url" FormSpec type unless its
+// Limit has a schemes clause; if empty, any scheme is accepted.
+var URLSchemes = []string{"http", "https"}
+
+// DomainsToASCII controls whether internationalized domain names in values
+// of the "email", "url" and "hostname" types are converted to their ASCII
+// (punycode) form, as needed by most mail and DNS software, or kept in
+// their normalized Unicode form.  Either way they are validated, without
+// any DNS lookups, and lowercased.
+var DomainsToASCII = true
+
+// LowercaseEmails controls whether the local part of "email" values, i.e.
+// the part before the "@", is lowercased.  The domain is always lowercased;
+// the local part is kept as given by default, as some mail systems are
+// case-sensitive.
+var LowercaseEmails = false
+
+// idnaProfile validates and maps domain names as for lookup, with length
+// limits.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(),
+	idna.VerifyDNSLength(true))
+
+// normalizeDomain returns the validated and normalized form of the domain
+// name s, in ASCII form if toASCII.
+func normalizeDomain(s string, toASCII bool) (string, bool) {
+	ascii, err := idnaProfile.ToASCII(s)
+	if err != nil || ascii == "" || strings.HasSuffix(ascii, ".") {
+		return "", false
+	}
+	if toASCII {
+		return ascii, true
+	}
+	u, err := idnaProfile.ToUnicode(ascii)
+	if err != nil {
+		return "", false
+	}
+	return u, true
+}
+
+// emailConverter accepts a bare address, e.g. "joe@example.com", as a
+// *mail.Address; names and angle brackets are not accepted.
+func emailConverter(d *Decoder, raw string) (interface{}, bool) {
+	if raw == "" {
+		return (*mail.Address)(nil), true
+	}
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Name != "" || addr.Address != raw {
+		return nil, false
+	}
+	at := strings.LastIndex(raw, "@")
+	local, domain := raw[:at], raw[at+1:]
+	domain, ok := normalizeDomain(domain, d.DomainsToASCII)
+	if !ok {
+		return nil, false
+	}
+	if d.LowercaseEmails {
+		local = strings.ToLower(local)
+	}
+	return &mail.Address{Address: local + "@" + domain}, true
+}
+
+// urlConverter accepts an absolute URL, with a host unless it is opaque as
+// e.g. "mailto:joe@example.com", as a *url.URL.  Host names are normalized
+// as domain names unless they are IP addresses.
+func urlConverter(d *Decoder, raw string) (interface{}, bool) {
+	if raw == "" {
+		return (*url.URL)(nil), true
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || (u.Host == "" && u.Opaque == "") {
+		return nil, false
+	}
+	if u.Host != "" {
+		host := u.Hostname()
+		if _, err := netip.ParseAddr(host); err != nil {
+			norm, ok := normalizeDomain(host, d.DomainsToASCII)
+			if !ok {
+				return nil, false
+			}
+			u.Host = strings.Replace(u.Host, host, norm, 1)
+		}
+	}
+	return u, true
+}
+
+// hostnameConverter accepts a domain name as a normalized string.
+func hostnameConverter(d *Decoder, raw string) (interface{}, bool) {
+	if raw == "" {
+		return "", true
+	}
+	return normalizeDomain(raw, d.DomainsToASCII)
+}
+
+// ipConverter returns a converter accepting IP addresses as netip.Addr, of
+// any version or only version 4 or 6.  IPv6 zones, e.g. "fe80::1%eth0",
+// are not accepted, as they are only meaningful on the client's host.
+func ipConverter(version int) func(string) (interface{}, bool) {
+	return func(raw string) (interface{}, bool) {
+		if raw == "" {
+			return netip.Addr{}, true
+		}
+		addr, err := netip.ParseAddr(raw)
+		if err != nil || addr.Zone() != "" || version == 4 && !addr.Is4() ||
+			version == 6 && !addr.Is6() {
+			return nil, false
+		}
+		return addr, true
+	}
+}
+
+// cidrConverter accepts an IP address prefix, e.g. "10.0.0.0/8", as a
+// netip.Prefix.  Host bits need not be zero, and are kept.
+func cidrConverter(raw string) (interface{}, bool) {
+	if raw == "" {
+		return netip.Prefix{}, true
+	}
+	p, err := netip.ParsePrefix(raw)
+	if err != nil {
+		return nil, false
+	}
+	return p, true
+}
+
+// urlFinish limits the scheme of a URL to those of the schemes limit of
+// fs, or else to the URLSchemes of the Decoder.
+func urlFinish(d *Decoder, fs *FormSpec, v interface{}) (interface{},
+	error) {
+
+	u, ok := v.(*url.URL)
+	if !ok || u == nil {
+		return v, nil
+	}
+	schemes := d.URLSchemes
+	for _, l := range fs.limits {
+		if l.schemes != nil {
+			schemes = l.schemes
+		}
+	}
+	if !netSchemeAllowed(u.Scheme, schemes) {
+		return nil, fs.FieldError(CodeBadScheme, map[string]interface{}{
+			"scheme":  u.Scheme,
+			"schemes": schemes,
+		})
+	}
+	return u, nil
+}
+
+// netValidator applies the text limits of the spec to the string form of
+// the value.
+func netValidator(fs *FormSpec, v interface{}) error {
+
+	s, ok := netValueString(v)
+	if !ok {
+		return wrongTypeError(fs, fs.itemType(), v)
+	}
+	slen := GlyphLength(s)
+	for _, l := range fs.limits {
+		if err := l.checkString(fs, s, slen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func netSchemeAllowed(scheme string, schemes []string) bool {
+	if len(schemes) == 0 {
+		return true
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// netValueString returns the string form of a value of a network type, as
+// assigned to string fields.
+func netValueString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case *mail.Address:
+		if v == nil {
+			return "", true
+		}
+		return v.Address, true
+	case *url.URL:
+		if v == nil {
+			return "", true
+		}
+		return v.String(), true
+	case netip.Addr:
+		if !v.IsValid() {
+			return "", true
+		}
+		return v.String(), true
+	case netip.Prefix:
+		if !v.IsValid() {
+			return "", true
+		}
+		return v.String(), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
@@ -0,0 +1,254 @@
+// formnet_test.go
+// ---------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FormSpec_Convert_Network(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		typ, raw, exp string // exp is empty for conversion errors
+	}{
+		{"email", "joe@example.com", "joe@example.com"},
+		{"email", "Joe.X@EXAMPLE.com", "Joe.X@example.com"},
+		{"email", "joe@bücher.example", "joe@xn--bcher-kva.example"},
+		{"email", "joe@localhost", "joe@localhost"},
+		{"email", "Joe <joe@example.com>", ""},
+		{"email", "<joe@example.com>", ""},
+		{"email", "joe", ""},
+		{"email", "joe@", ""},
+		{"email", "joe@exa_mple.com", ""},
+		{"email", "joe@-example.com", ""},
+		{"url", "https://example.com/a?b=c#d", "https://example.com/a?b=c#d"},
+		{"url", "HTTP://WWW.Example.COM:8080/X", "http://www.example.com:8080/X"},
+		{"url", "https://bücher.example/", "https://xn--bcher-kva.example/"},
+		{"url", "http://[::1]:80/", "http://[::1]:80/"},
+		{"url", "http://10.0.0.1/", "http://10.0.0.1/"},
+		{"url", "/relative/path", ""},
+		{"url", "example.com", ""},
+		{"url", "http://", ""},
+		{"url", "http://exa mple.com/", ""},
+		{"url", "http://exa_mple.com/", ""},
+		{"hostname", "Example.COM", "example.com"},
+		{"hostname", "bücher.example", "xn--bcher-kva.example"},
+		{"hostname", "xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"hostname", "a..b", ""},
+		{"hostname", "example.com.", ""},
+		{"hostname", "exa mple.com", ""},
+		{"hostname", "a-.example", ""},
+		{"ip", "10.0.0.1", "10.0.0.1"},
+		{"ip", "2001:DB8::1", "2001:db8::1"},
+		{"ip", "10.0.0", ""},
+		{"ipv4", "192.168.1.1", "192.168.1.1"},
+		{"ipv4", "::1", ""},
+		{"ipv4", "::ffff:10.0.0.1", ""},
+		{"ipv6", "::1", "::1"},
+		{"ipv6", "10.0.0.1", ""},
+		{"ip", "fe80::1%eth0", ""},
+		{"ipv6", "fe80::1%eth0", ""},
+		{"ipv6", "fe80::1", "fe80::1"},
+		{"cidr", "10.0.0.0/8", "10.0.0.0/8"},
+		{"cidr", "10.1.2.3/8", "10.1.2.3/8"},
+		{"cidr", "2001:db8::/32", "2001:db8::/32"},
+		{"cidr", "10.0.0.0", ""},
+		{"cidr", "10.0.0.0/33", ""},
+	} {
+		spec := vebben.OptionalFormSpec("x", tc.typ)
+		target := map[string]string{}
+		err := vebben.DecodeForm(vebben.URLValues{"x": {tc.raw}},
+			[]*vebben.FormSpec{spec}, &target)
+		if tc.exp == "" {
+			if assert.Error(err, tc.raw) {
+				fe := err.(*vebben.MultiError).FieldErrors()[0]
+				assert.Equal(vebben.CodeConversion, fe.Code, tc.raw)
+			}
+			continue
+		}
+		if assert.Nil(err, tc.raw) {
+			assert.Equal(tc.exp, target["x"], tc.raw)
+		}
+	}
+}
+
+func Test_FormSpec_Convert_NetworkOptions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	defer func() {
+		vebben.DomainsToASCII = true
+		vebben.LowercaseEmails = false
+	}()
+
+	email := vebben.OptionalFormSpec("email", "email")
+	host := vebben.OptionalFormSpec("host", "hostname")
+
+	vebben.DomainsToASCII = false
+	vebben.LowercaseEmails = true
+	v, err := email.Convert("Joe@Bücher.Example")
+	if assert.Nil(err) {
+		assert.Equal(&mail.Address{Address: "joe@bücher.example"}, v)
+	}
+	v, err = host.Convert("xn--bcher-kva.example")
+	if assert.Nil(err) {
+		assert.Equal("bücher.example", v)
+	}
+	_, err = host.Convert("exa_mple.com")
+	assert.Error(err, "still validated")
+}
+
+func Test_DecodeForm_NetworkTypes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	type Server struct {
+		Admin   *mail.Address `vebben:",,required"`
+		Home    *url.URL      `vebben:""`
+		Addr    netip.Addr    `vebben:""`
+		Net     netip.Prefix  `vebben:""`
+		Host    string        `vebben:",hostname"`
+		Mirrors []string      `vebben:",[]url,limit=schemes=ftp,https"`
+	}
+	specs := vebben.MustFormSpecsFor(Server{})
+	types := []string{}
+	for _, spec := range specs {
+		types = append(types, spec.Type)
+	}
+	assert.Equal([]string{"email", "url", "ip", "cidr", "hostname", "[]url"},
+		types)
+
+	target := &Server{}
+	err := vebben.DecodeForm(vebben.URLValues{
+		"Admin":   {"root@example.com"},
+		"Home":    {"https://example.com/"},
+		"Addr":    {"10.0.0.1"},
+		"Net":     {"10.0.0.0/8"},
+		"Host":    {"Example.com"},
+		"Mirrors": {"ftp://example.com/pub", "https://example.org/"},
+	}, specs, target)
+	if assert.Nil(err) {
+		assert.Equal("root@example.com", target.Admin.Address)
+		assert.Equal("example.com", target.Home.Host)
+		assert.Equal(netip.MustParseAddr("10.0.0.1"), target.Addr)
+		assert.Equal(netip.MustParsePrefix("10.0.0.0/8"), target.Net)
+		assert.Equal("example.com", target.Host)
+		assert.Equal([]string{"ftp://example.com/pub", "https://example.org/"},
+			target.Mirrors)
+	}
+
+	err = vebben.DecodeForm(vebben.URLValues{
+		"Admin":   {"root@example.com"},
+		"Home":    {"ftp://example.com/"},
+		"Mirrors": {"http://example.com/"},
+	}, specs, &Server{})
+	if assert.Error(err) {
+		fes := err.(*vebben.MultiError).FieldErrors()
+		if assert.Len(fes, 2) {
+			assert.Equal(vebben.CodeBadScheme, fes[0].Code)
+			assert.Equal(map[string]interface{}{"scheme": "ftp",
+				"schemes": vebben.URLSchemes}, fes[0].Params)
+			assert.Equal("Home has a URL scheme that is not allowed",
+				fes[0].Error())
+			assert.Equal(vebben.CodeBadScheme, fes[1].Code)
+		}
+	}
+
+	mailto := vebben.OptionalFormSpec("to", "url", "schemes=mailto")
+	v, err := mailto.Convert("mailto:joe@example.com")
+	if assert.Nil(err) {
+		assert.Nil(mailto.Validator(mailto, v))
+		assert.Equal("joe@example.com", v.(*url.URL).Opaque)
+	}
+}
+
+func Test_FormSpec_NetworkLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	spec := vebben.OptionalFormSpec("email", "email",
+		`len=<=20;re=@example\.com$;not=root@example.com`)
+	for raw, code := range map[string]string{
+		"joe@example.com":             "",
+		"joe@EXAMPLE.com":             "",
+		"joe@example.org":             vebben.CodeBadFormat,
+		"josephine.x@example.com":     vebben.CodeTooLong,
+		"root@example.com":            vebben.CodeNotAllowed,
+		"joe@xn--bcher-kva.example":   vebben.CodeTooLong,
+		"Joe Smith <joe@example.com>": vebben.CodeConversion,
+	} {
+		err := vebben.DecodeForm(vebben.URLValues{"email": {raw}},
+			[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+		if code == "" {
+			assert.Nil(err, raw)
+		} else if assert.Error(err, raw) {
+			fe := err.(*vebben.MultiError).FieldErrors()[0]
+			assert.Equal(code, fe.Code, raw)
+		}
+	}
+
+	assert.Panics(func() {
+		vebben.OptionalFormSpec("x", "email", "schemes=https")
+	}, "schemes only for url")
+	assert.Panics(func() {
+		vebben.OptionalFormSpec("x", "ip", "range=1-10")
+	}, "no value range")
+}
+
+func Test_FormInput_NetworkTypes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	html, _ := vebben.FormInput(vebben.RequiredFormSpec("email", "email",
+		"len=<=60"), &mail.Address{Address: "joe@example.com"}, nil)
+	assert.Equal(`<input name="email" id="email" required type="email" `+
+		`maxlength="60" value="joe@example.com">`, string(html))
+
+	u, _ := url.Parse("https://example.com/x")
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("home", "url"), u, nil)
+	assert.Equal(`<input name="home" id="home" type="url" `+
+		`value="https://example.com/x">`, string(html))
+
+	html, _ = vebben.FormInput(vebben.OptionalFormSpec("ip", "ip"),
+		netip.MustParseAddr("::1"), nil)
+	assert.Equal(`<input name="ip" id="ip" type="text" value="::1">`,
+		string(html))
+}
+
+func Test_JSONSchema_NetworkTypes(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("a", "email"),
+		vebben.OptionalFormSpec("b", "url"),
+		vebben.OptionalFormSpec("c", "hostname"),
+		vebben.OptionalFormSpec("d", "ip"),
+		vebben.OptionalFormSpec("e", "ipv6"),
+		vebben.OptionalFormSpec("f", "cidr", "len=<=43"),
+	}
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema",`+
+		`"properties":{`+
+		`"a":{"format":"email","type":"string"},`+
+		`"b":{"format":"uri","type":"string"},`+
+		`"c":{"format":"hostname","type":"string"},`+
+		`"d":{"anyOf":[{"format":"ipv4"},{"format":"ipv6"}],"type":"string"},`+
+		`"e":{"format":"ipv6","type":"string"},`+
+		`"f":{"maxLength":43,"type":"string"}},`+
+		`"type":"object"}`, schemaJSON(t, specs))
+
+	vebben.DomainsToASCII = false
+	defer func() { vebben.DomainsToASCII = true }()
+	assert.Contains(t, schemaJSON(t, specs), `"a":{"format":"idn-email"`)
+}
@@ -0,0 +1,146 @@
+// formnumbers.go -- locale-aware parsing of numeric form values.
+// --------------
+
+package vebben
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// NumberLocale is the language whose number formats are accepted by the
+// numeric FormSpec types, in addition to the plain ones, e.g. "1 234,56"
+// for language.Hungarian.  The decimal mark is that of the language in
+// CLDR, "," or "."; and digits may be grouped by threes, with spaces
+// (including no-break spaces), apostrophes, or whichever of "." and "," is
+// not the decimal mark.  The other mark is also accepted as a decimal mark
+// if it can not be a group separator, as in "1234.56" or "1.5".  Numbers
+// are only parsed as plain ones, as by package strconv, if NumberLocale is
+// language.Und, as it is by default.  This is the default for the
+// NumberLocale setting of a Decoder.
+var NumberLocale = language.Und
+
+// StrictNumbers controls whether numbers that could be read either with or
+// without grouping, i.e. a "." or "," followed by three digits and nothing
+// else, e.g. "1.500" or "1,500", are rejected when NumberLocale is set.
+// This is the default for the StrictNumbers setting of a Decoder.
+var StrictNumbers = false
+
+// numberGroupSeps are the group separators accepted besides "." and ",".
+const numberGroupSeps = " \u00a0\u202f'\u2019"
+
+var decimalMarks sync.Map // language.Tag -> byte
+
+// decimalMark returns the decimal mark of the language tag, "," or ".".
+func decimalMark(tag language.Tag) byte {
+	if mark, ok := decimalMarks.Load(tag); ok {
+		return mark.(byte)
+	}
+	mark := byte('.')
+	if message.NewPrinter(tag).Sprint(1.5) == "1,5" {
+		mark = ','
+	}
+	decimalMarks.Store(tag, mark)
+	return mark
+}
+
+// localNumber returns the number s, in the format of the Decoder's
+// NumberLocale, in the plain format accepted by package strconv, or false if
+// it is not a valid number.  Numbers without any separators are returned as
+// they are.
+func (d *Decoder) localNumber(s string) (string, bool) {
+
+	if d.NumberLocale == language.Und {
+		return s, true
+	}
+	sign := ""
+	switch {
+	case strings.HasPrefix(s, "-"), strings.HasPrefix(s, "+"):
+		sign, s = s[:1], s[1:]
+	case strings.HasPrefix(s, "\u2212"): // minus sign
+		sign, s = "-", strings.TrimPrefix(s, "\u2212")
+	}
+	if !strings.ContainsAny(s, numberGroupSeps+".,") {
+		return sign + s, true
+	}
+	if d.StrictNumbers && ambiguousNumber(s) {
+		return "", false
+	}
+
+	mark, other := ".", ","
+	if decimalMark(d.NumberLocale) == ',' {
+		mark, other = ",", "."
+	}
+	whole, frac, hasFrac := s, "", false
+	switch {
+	case strings.Count(s, mark) > 1:
+		return "", false
+	case strings.Contains(s, mark):
+		whole, frac, hasFrac = strings.Cut(s, mark)
+	case strings.Count(s, other) == 1:
+		if _, grouped := ungroupDigits(s); !grouped {
+			whole, frac, hasFrac = strings.Cut(s, other)
+		}
+	}
+	if !allDigits(frac) {
+		return "", false
+	}
+	digits, ok := ungroupDigits(whole)
+	if !ok || digits == "" && frac == "" {
+		return "", false
+	}
+	if hasFrac {
+		return sign + digits + "." + frac, true
+	}
+	return sign + digits, true
+}
+
+// ungroupDigits returns the digits of s, which must be digits grouped by
+// threes with a single kind of separator, or have no separators at all.
+func ungroupDigits(s string) (string, bool) {
+
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		return s, true
+	}
+	sep := []rune(s[i:])[0]
+	if !strings.ContainsRune(numberGroupSeps+".,", sep) {
+		return "", false
+	}
+	groups := strings.Split(s, string(sep))
+	first := groups[0]
+	if len(first) == 0 || len(first) > 3 || first[0] == '0' ||
+		!allDigits(first) {
+		return "", false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 || !allDigits(g) {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), true
+}
+
+// ambiguousNumber returns true if s, without a sign, is a number with a
+// single separator that may be either a group separator or a decimal mark,
+// e.g. "1.500".
+func ambiguousNumber(s string) bool {
+	i := strings.IndexAny(s, ".,")
+	if i < 0 || strings.ContainsAny(s[i+1:], numberGroupSeps+".,") {
+		return false
+	}
+	_, grouped := ungroupDigits(s)
+	return grouped
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+// formnumbers_test.go
+// -------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FormSpec_Convert_LocalNumbers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	defer func() {
+		vebben.NumberLocale = language.Und
+		vebben.StrictNumbers = false
+	}()
+
+	for _, tc := range []struct {
+		locale language.Tag
+		strict bool
+		typ    string
+		in     string
+		out    string // empty for conversion errors
+	}{
+		{language.Und, false, "float", "1234.5", "1234.5"},
+		{language.Und, false, "float", "1 234,5", ""},
+		{language.Und, false, "int", "12 000", ""},
+		{language.Hungarian, false, "float", "1 234,56", "1234.56"},
+		{language.Hungarian, false, "float", "1\u00a0234,56", "1234.56"},
+		{language.Hungarian, false, "float", "1\u202f234,56", "1234.56"},
+		{language.Hungarian, false, "float", "1\u202f234\u202f567,5", "1.2345675e+06"},
+		{language.Hungarian, false, "float", "1.234.567,5", "1.2345675e+06"},
+		{language.Hungarian, false, "float", "−2,5", "-2.5"},
+		{language.Hungarian, false, "float", "1234.56", "1234.56"},
+		{language.Hungarian, false, "float", "1.5", "1.5"},
+		{language.Hungarian, false, "float", "0.500", "0.5"},
+		{language.Hungarian, false, "float", "1.500", "1500"},
+		{language.Hungarian, false, "float", "1,500", "1.5"},
+		{language.Hungarian, false, "float", "1e3", "1000"},
+		{language.Hungarian, false, "float", ",5", "0.5"},
+		{language.Hungarian, false, "float", "1,5,0", ""},
+		{language.Hungarian, false, "float", "1 23,5", ""},
+		{language.Hungarian, false, "float", "1 234.567,5", ""},
+		{language.Hungarian, false, "float", "01 234", ""},
+		{language.Hungarian, false, "float", "1,5e3", ""},
+		{language.Hungarian, false, "float", "- 5", ""},
+		{language.Hungarian, false, "int", "12 000", "12000"},
+		{language.Hungarian, false, "int", "-12'000", "-12000"},
+		{language.Hungarian, false, "int", "12,5", ""},
+		{language.Hungarian, false, "int64", "9 223 372 036 854 775 807", "9223372036854775807"},
+		{language.Hungarian, false, "decimal", "1 234,50", "1234.50"},
+		{language.Hungarian, false, "money", "1 234,50 EUR", "1234.50 EUR"},
+		{language.Hungarian, false, "money", "HUF 12 000", "12000.00 HUF"},
+		{language.English, false, "float", "1,234.5", "1234.5"},
+		{language.English, false, "float", "1,5", "1.5"},
+		{language.English, false, "float", "1,500", "1500"},
+		{language.English, false, "float", "1.500", "1.5"},
+		{language.MustParse("de-CH"), false, "float", "12’345.5", "12345.5"},
+		{language.Hungarian, true, "float", "1.500", ""},
+		{language.Hungarian, true, "float", "1,500", ""},
+		{language.Hungarian, true, "int", "12.000", ""},
+		{language.Hungarian, true, "float", "1.500,0", "1500"},
+		{language.Hungarian, true, "float", "1 500", "1500"},
+		{language.Hungarian, true, "float", "1,50", "1.5"},
+		{language.Hungarian, true, "float", "0,500", "0.5"},
+		{language.Hungarian, true, "float", "1500", "1500"},
+		{language.English, true, "float", "1,500", ""},
+		{language.English, true, "float", "1,500.25", "1500.25"},
+	} {
+		vebben.NumberLocale = tc.locale
+		vebben.StrictNumbers = tc.strict
+		desc := fmt.Sprintf("%v %v %s %q", tc.locale, tc.strict, tc.typ, tc.in)
+		v, err := vebben.OptionalFormSpec("x", tc.typ).Convert(tc.in)
+		if tc.out == "" {
+			if assert.Error(err, desc) {
+				assert.Equal(vebben.CodeConversion,
+					err.(*vebben.FieldError).Code, desc)
+			}
+			continue
+		}
+		if assert.Nil(err, desc) {
+			assert.Equal(tc.out, fmt.Sprint(v), desc)
+		}
+	}
+}
+
+func Test_DecodeForm_LocalNumbers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	vebben.NumberLocale = language.Hungarian
+	defer func() { vebben.NumberLocale = language.Und }()
+
+	type Order struct {
+		Qty   int     `vebben:",,limit=1-10000"`
+		Price float64 `vebben:""`
+	}
+	target := &Order{}
+	err := vebben.DecodeForm(vebben.URLValues{
+		"Qty":   {"1 000"},
+		"Price": {"12 345,67"},
+	}, vebben.MustFormSpecsFor(Order{}), target)
+	if assert.Nil(err) {
+		assert.Equal(&Order{Qty: 1000, Price: 12345.67}, target)
+	}
+
+	err = vebben.DecodeForm(vebben.URLValues{"Qty": {"12 000"}},
+		vebben.MustFormSpecsFor(Order{}), &Order{})
+	if assert.Error(err) {
+		fe := err.(*vebben.MultiError).FieldErrors()[0]
+		assert.Equal(vebben.CodeTooHigh, fe.Code)
+		assert.Equal("12 000", fe.Input)
+	}
+
+	js := vebben.ValidatorJS(vebben.MustFormSpecsFor(Order{}))
+	assert.Contains(js, "const localNumbers = true;")
+}
@@ -0,0 +1,74 @@
+// formoptions.go -- options for DecodeForm.
+// --------------
+
+package vebben
+
+import (
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// DecodeOption configures a single call to DecodeForm.
+type DecodeOption func(*decodeConfig)
+
+// decodeConfig holds the settings for a single call to DecodeForm.
+type decodeConfig struct {
+	catalog        *Catalog
+	lang           language.Tag
+	acceptLanguage string
+	rules          []Rule
+	csrf           *CSRF
+	csrfRequest    *http.Request
+	state          *FormState
+}
+
+// newDecodeConfig returns the decodeConfig resulting from opts.
+func newDecodeConfig(opts []DecodeOption) *decodeConfig {
+	c := &decodeConfig{catalog: DefaultCatalog, lang: language.English}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.acceptLanguage != "" {
+		c.lang = c.catalog.Match(c.acceptLanguage)
+	}
+	return c
+}
+
+// localize sets the language and Catalog of any FieldErrors in errs.
+func (c *decodeConfig) localize(errs []error) {
+	for _, err := range errs {
+		if fe, ok := err.(*FieldError); ok {
+			fe.Lang = c.lang
+			fe.catalog = c.catalog
+		}
+	}
+}
+
+// Language sets the language of FieldError messages to tag.
+func Language(tag language.Tag) DecodeOption {
+	return func(c *decodeConfig) {
+		c.lang = tag
+		c.acceptLanguage = ""
+	}
+}
+
+// AcceptLanguage sets the language of FieldError messages to the best match
+// for an Accept-Language header in the Catalog, e.g.:
+//
+//   err := vebben.DecodeForm(r, specs, target,
+//       vebben.AcceptLanguage(r.Header.Get("Accept-Language")))
+//
+// If the header is empty, the language is not changed.
+func AcceptLanguage(header string) DecodeOption {
+	return func(c *decodeConfig) {
+		c.acceptLanguage = header
+	}
+}
+
+// WithCatalog sets the Catalog used for FieldError messages.
+func WithCatalog(cat *Catalog) DecodeOption {
+	return func(c *decodeConfig) {
+		c.catalog = cat
+	}
+}
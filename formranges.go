@@ -0,0 +1,340 @@
+// formranges.go -- numeric range limits.
+// -------------
+
+package vebben
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// numRange is a parsed range limit.  Integer ranges, for the int types and
+// for string lengths, hold inclusive bounds in the int fields; float and
+// decimal ranges hold possibly exclusive bounds in the float or decimal
+// fields.  The step is zero if there is none, and its base is the lower
+// bound as given, or zero.
+type numRange struct {
+	float            bool
+	dec              bool
+	hasMin, hasMax   bool
+	minExcl, maxExcl bool // floats and decimals only
+
+	minInt, maxInt, stepInt, baseInt         int64
+	minFloat, maxFloat, stepFloat, baseFloat float64
+	minDec, maxDec, stepDec, baseDec         Decimal
+}
+
+const rangeNumber = `([-+]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)`
+
+var rangeClauseMatchSpan = regexp.MustCompile("^" + rangeNumber + "-" + rangeNumber + "?$")
+var rangeClauseMatchBound = regexp.MustCompile("^(>=|>|<=|<)" + rangeNumber + "$")
+var rangeClauseMatchStep = regexp.MustCompile("^step=" + rangeNumber + "$")
+
+// isRangeLimit returns true if the limit uses the range limit syntax, i.e.
+// consists of range, bound and step clauses separated by spaces.
+func isRangeLimit(limit string) bool {
+	clauses := strings.Fields(limit)
+	if len(clauses) == 0 {
+		return false
+	}
+	for _, c := range clauses {
+		if !rangeClauseMatchSpan.MatchString(c) &&
+			!rangeClauseMatchBound.MatchString(c) &&
+			!rangeClauseMatchStep.MatchString(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRangeLimit parses a range limit for item type t, panicking with a
+// description of any problem.
+func parseRangeLimit(limit, t string) *numRange {
+
+	r := &numRange{float: t == "float", dec: t == "decimal" || t == "money"}
+	length := isTextType(t) || t == "file"
+	bits := 32
+	switch {
+	case t == "float", t == "int", r.dec, length:
+	case t == "int64":
+		bits = 64
+	default:
+		panic("Range limit does not apply to " + t)
+	}
+
+	for _, c := range strings.Fields(limit) {
+		if m := rangeClauseMatchStep.FindStringSubmatch(c); m != nil {
+			if length {
+				panic("Bad range limit: step does not apply to " + t)
+			}
+			if r.hasStep() {
+				panic("Bad range limit: more than one step")
+			}
+			switch {
+			case r.float:
+				r.stepFloat = parseRangeFloat(m[1])
+			case r.dec:
+				r.stepDec = parseRangeDecimal(m[1])
+			default:
+				r.stepInt = parseRangeInt(m[1], t, bits)
+			}
+			if r.stepInt < 0 || r.stepFloat < 0 || r.stepDec.Units < 0 ||
+				!r.hasStep() {
+				panic("Bad range limit: step must be positive")
+			}
+			continue
+		}
+		var lower, upper string
+		lowerExcl, upperExcl := false, false
+		if m := rangeClauseMatchSpan.FindStringSubmatch(c); m != nil {
+			lower, upper = m[1], m[2]
+		} else if m := rangeClauseMatchBound.FindStringSubmatch(c); m != nil {
+			switch m[1] {
+			case ">", ">=":
+				lower, lowerExcl = m[2], m[1] == ">"
+			default:
+				upper, upperExcl = m[2], m[1] == "<"
+			}
+		} else {
+			panic("Bad range limit: bad clause " + strconv.Quote(c))
+		}
+		if lower != "" {
+			if r.hasMin {
+				panic("Bad range limit: more than one lower bound")
+			}
+			r.hasMin = true
+			r.setBound(lower, lowerExcl, true, t, bits)
+		}
+		if upper != "" {
+			if r.hasMax {
+				panic("Bad range limit: more than one upper bound")
+			}
+			r.hasMax = true
+			r.setBound(upper, upperExcl, false, t, bits)
+		}
+	}
+
+	if length && ((r.hasMin && r.minInt < 0) || (r.hasMax && r.maxInt < 0)) {
+		panic("Bad range limit: negative length")
+	}
+	if r.hasMin && r.hasMax {
+		cmp := r.minDec.Cmp(r.maxDec)
+		if r.float && r.maxFloat < r.minFloat ||
+			!r.float && !r.dec && r.maxInt < r.minInt || r.dec && cmp > 0 {
+			panic("Bad range limit: upper < lower")
+		}
+		if (r.float && r.minFloat == r.maxFloat || r.dec && cmp == 0) &&
+			(r.minExcl || r.maxExcl) {
+			panic("Bad range limit: empty range")
+		}
+	}
+	return r
+}
+
+// setBound sets the lower or upper bound from the number s.  Exclusive
+// integer bounds are made inclusive.
+func (r *numRange) setBound(s string, excl, lower bool, t string, bits int) {
+
+	if r.float {
+		f := parseRangeFloat(s)
+		if lower {
+			r.minFloat, r.minExcl, r.baseFloat = f, excl, f
+		} else {
+			r.maxFloat, r.maxExcl = f, excl
+		}
+		return
+	}
+	if r.dec {
+		d := parseRangeDecimal(s)
+		if lower {
+			r.minDec, r.minExcl, r.baseDec = d, excl, d
+		} else {
+			r.maxDec, r.maxExcl = d, excl
+		}
+		return
+	}
+	i := parseRangeInt(s, t, bits)
+	if lower {
+		r.baseInt = i
+	}
+	if excl {
+		limit := int64(math.MaxInt64)
+		if bits == 32 {
+			limit = math.MaxInt32
+		}
+		if lower && i == limit || !lower && i == -limit-1 {
+			panic("Bad range limit: empty range")
+		}
+		if lower {
+			i++
+		} else {
+			i--
+		}
+	}
+	if lower {
+		r.minInt = i
+	} else {
+		r.maxInt = i
+	}
+}
+
+func parseRangeFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic("Bad range limit: " + err.Error())
+	}
+	return f
+}
+
+func parseRangeDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic("Bad range limit: " + s + " is not a decimal: " + err.Error())
+	}
+	return d
+}
+
+func parseRangeInt(s, t string, bits int) int64 {
+	i, err := strconv.ParseInt(s, 10, bits)
+	if err == nil {
+		return i
+	}
+	if _, ferr := strconv.ParseFloat(s, 64); ferr == nil &&
+		err.(*strconv.NumError).Err == strconv.ErrSyntax {
+		panic("Bad range limit: " + s + " is not an integer, as " + t +
+			" requires")
+	}
+	panic("Bad range limit: " + s + " is out of range for " + t)
+}
+
+// compareInt returns -1 if i is below the range, 1 if it is above, and
+// otherwise 0.
+func (r *numRange) compareInt(i int64) int {
+	switch {
+	case r.hasMin && i < r.minInt:
+		return -1
+	case r.hasMax && i > r.maxInt:
+		return 1
+	}
+	return 0
+}
+
+// compareFloat returns -1 if f is below the range, 1 if it is above, and
+// otherwise 0.
+func (r *numRange) compareFloat(f float64) int {
+	switch {
+	case r.hasMin && (f < r.minFloat || r.minExcl && f == r.minFloat):
+		return -1
+	case r.hasMax && (f > r.maxFloat || r.maxExcl && f == r.maxFloat):
+		return 1
+	}
+	return 0
+}
+
+// compareDecimal returns -1 if d is below the range, 1 if it is above, and
+// otherwise 0.
+func (r *numRange) compareDecimal(d Decimal) int {
+	switch {
+	case r.hasMin && (d.Cmp(r.minDec) < 0 || r.minExcl && d.Cmp(r.minDec) == 0):
+		return -1
+	case r.hasMax && (d.Cmp(r.maxDec) > 0 || r.maxExcl && d.Cmp(r.maxDec) == 0):
+		return 1
+	}
+	return 0
+}
+
+// hasStep returns true if the range has a step.
+func (r *numRange) hasStep() bool {
+	return r.stepInt != 0 || r.stepFloat != 0 || r.stepDec.Units != 0
+}
+
+// onStepInt returns true if i is a whole number of steps from the base.
+func (r *numRange) onStepInt(i int64) bool {
+	if r.stepInt == 0 {
+		return true
+	}
+	d := new(big.Int).Sub(big.NewInt(i), big.NewInt(r.baseInt))
+	return d.Rem(d, big.NewInt(r.stepInt)).Sign() == 0
+}
+
+// onStepFloat returns true if f is a whole number of steps from the base,
+// allowing for rounding errors.
+func (r *numRange) onStepFloat(f float64) bool {
+	if r.stepFloat == 0 {
+		return true
+	}
+	q := (f - r.baseFloat) / r.stepFloat
+	return math.Abs(q-math.Round(q)) <= 1e-9*math.Max(1, math.Abs(q))
+}
+
+// onStepDecimal returns true if d is exactly a whole number of steps from
+// the base.
+func (r *numRange) onStepDecimal(d Decimal) bool {
+	if r.stepDec.Units == 0 {
+		return true
+	}
+	q := new(big.Rat).Sub(d.rat(), r.baseDec.rat())
+	return q.Quo(q, r.stepDec.rat()).IsInt()
+}
+
+// params returns the FieldError params for the range: "min", "max" and
+// "step" as they are set, as int64, float64 or Decimal.
+func (r *numRange) params() map[string]interface{} {
+	params := map[string]interface{}{}
+	if r.dec {
+		if r.hasMin {
+			params["min"] = r.minDec
+		}
+		if r.hasMax {
+			params["max"] = r.maxDec
+		}
+		if r.stepDec.Units != 0 {
+			params["step"] = r.stepDec
+		}
+		return params
+	}
+	if r.float {
+		if r.hasMin {
+			params["min"] = r.minFloat
+		}
+		if r.hasMax {
+			params["max"] = r.maxFloat
+		}
+		if r.stepFloat != 0 {
+			params["step"] = r.stepFloat
+		}
+		return params
+	}
+	if r.hasMin {
+		params["min"] = r.minInt
+	}
+	if r.hasMax {
+		params["max"] = r.maxInt
+	}
+	if r.stepInt != 0 {
+		params["step"] = r.stepInt
+	}
+	return params
+}
+
+// formatNumbers returns the bounds and step as strings, empty if unset,
+// e.g. for HTML attributes.
+func (r *numRange) formatNumbers() (min, max, step string) {
+	format := func(has bool, i int64, f float64, d Decimal) string {
+		switch {
+		case !has:
+			return ""
+		case r.float:
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		case r.dec:
+			return d.String()
+		}
+		return strconv.FormatInt(i, 10)
+	}
+	return format(r.hasMin, r.minInt, r.minFloat, r.minDec),
+		format(r.hasMax, r.maxInt, r.maxFloat, r.maxDec),
+		format(r.hasStep(), r.stepInt, r.stepFloat, r.stepDec)
+}
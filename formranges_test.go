@@ -0,0 +1,176 @@
+// formranges_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_FormSpec_RangeLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		typ, limit, input, code string
+		params                  map[string]interface{}
+	}{
+		{"int", "-10-10", "-10", "", nil},
+		{"int", "-10-10", "-11", "too_low",
+			map[string]interface{}{"min": int64(-10), "max": int64(10)}},
+		{"int", "-20--10", "-5", "too_high",
+			map[string]interface{}{"min": int64(-20), "max": int64(-10)}},
+		{"int", "5-", "1000000", "", nil},
+		{"int", "5-", "4", "too_low", map[string]interface{}{"min": int64(5)}},
+		{"int", ">=0", "0", "", nil},
+		{"int", ">0", "0", "too_low", map[string]interface{}{"min": int64(1)}},
+		{"int", "<100", "99", "", nil},
+		{"int", "<100", "100", "too_high",
+			map[string]interface{}{"max": int64(99)}},
+		{"int", "+1-+3", "2", "", nil},
+		{"int", "0-100 step=5", "35", "", nil},
+		{"int", "0-100 step=5", "36", "wrong_step", map[string]interface{}{
+			"min": int64(0), "max": int64(100), "step": int64(5)}},
+		{"int", "step=2 1-", "7", "", nil},
+		{"int", "step=2 1-", "8", "wrong_step",
+			map[string]interface{}{"min": int64(1), "step": int64(2)}},
+		{"int", "step=3", "-9", "", nil},
+		{"int64", ">-9223372036854775808", "-9223372036854775807", "", nil},
+		{"int64", ">=-9223372036854775808 step=5", "9223372036854775807",
+			"", nil},
+		{"float", "0-2.5", "2.5", "", nil},
+		{"float", "0-2.5", "2.51", "too_high",
+			map[string]interface{}{"min": 0.0, "max": 2.5}},
+		{"float", ">0 <=1", "0", "too_low",
+			map[string]interface{}{"min": 0.0, "max": 1.0}},
+		{"float", ">0 <=1", "1e-300", "", nil},
+		{"float", ">0 <1", "1", "too_high",
+			map[string]interface{}{"min": 0.0, "max": 1.0}},
+		{"float", "<-0.5", "-0.5", "too_high",
+			map[string]interface{}{"max": -0.5}},
+		{"float", "-1.5-", "-1.5", "", nil},
+		{"float", "0-1 step=0.1", "0.3", "", nil},
+		{"float", "0-1 step=0.1", "0.35", "wrong_step",
+			map[string]interface{}{"min": 0.0, "max": 1.0, "step": 0.1}},
+		{"float", "0.25- step=0.5", "1.75", "", nil},
+		{"float", "0.25- step=0.5", "1.5", "wrong_step",
+			map[string]interface{}{"min": 0.25, "step": 0.5}},
+		{"float", ">=1e-3", ".001", "", nil},
+		{"string", "3-", "abc", "", nil},
+		{"string", "3-", "ab", "too_short",
+			map[string]interface{}{"min": int64(3)}},
+		{"string", "<=3", "abcd", "too_long",
+			map[string]interface{}{"max": int64(3)}},
+		{"string", ">2 <5", "abcde", "too_long",
+			map[string]interface{}{"min": int64(3), "max": int64(4)}},
+	} {
+		desc := fmt.Sprintf("%s %q %q", tc.typ, tc.limit, tc.input)
+		specs := []*vebben.FormSpec{
+			vebben.OptionalFormSpec("x", tc.typ, tc.limit),
+		}
+		err := vebben.DecodeForm(vebben.URLValues{"x": {tc.input}}, specs,
+			&map[string]interface{}{})
+		if tc.code == "" {
+			assert.Nil(err, desc)
+			continue
+		}
+		if !assert.Error(err, desc) {
+			continue
+		}
+		fes := err.(*vebben.MultiError).FieldErrors()
+		if assert.Len(fes, 1, desc) {
+			assert.Equal(tc.code, fes[0].Code, desc)
+			assert.Equal(tc.params, fes[0].Params, desc)
+		}
+	}
+}
+
+func Test_FormSpec_RangeLimits_Message(t *testing.T) {
+
+	spec := vebben.OptionalFormSpec("qty", "int", "0- step=6", "Quantity")
+	err := vebben.DecodeForm(vebben.URLValues{"qty": {"8"}},
+		[]*vebben.FormSpec{spec}, &map[string]interface{}{})
+	assert.EqualError(t, err, "Quantity must be in steps of 6")
+}
+
+func Test_FormSpec_RangeLimits_Panics(t *testing.T) {
+
+	for _, tc := range []struct {
+		typ, limit, msg string
+	}{
+		{"int", "1.5-10", "Bad range limit: 1.5 is not an integer, as int requires"},
+		{"int64", "1e3-", "Bad range limit: 1e3 is not an integer, as int64 requires"},
+		{"int", "0-10 step=0.5", "Bad range limit: 0.5 is not an integer, as int requires"},
+		{"int", "0-3000000000", "Bad range limit: 3000000000 is out of range for int"},
+		{"int64", "<9223372036854775808", "Bad range limit: 9223372036854775808 is out of range for int64"},
+		{"int", "10-1", "Bad range limit: upper < lower"},
+		{"float", ">1 <1", "Bad range limit: empty range"},
+		{"int", ">2147483647", "Bad range limit: empty range"},
+		{"int", "1- >=2", "Bad range limit: more than one lower bound"},
+		{"float", "<1 -5-5", "Bad range limit: more than one upper bound"},
+		{"int", "step=1 step=2", "Bad range limit: more than one step"},
+		{"int", "step=0", "Bad range limit: step must be positive"},
+		{"float", "step=-0.5", "Bad range limit: step must be positive"},
+		{"string", "-1-5", "Bad range limit: negative length"},
+		{"string", "1-5 step=2", "Bad range limit: step does not apply to string"},
+		{"bool", "1-5", "Range limit does not apply to bool"},
+		{"int", "1-5 x", `Bad range limit: bad clause "x"`},
+		{"float", "=>5", `Bad range limit: bad clause "=>5"`},
+		{"float", "1..5", `Bad range limit: bad clause "1..5"`},
+	} {
+		spec := &vebben.FormSpec{Key: "x", Type: tc.typ, Limit: tc.limit}
+		testig.AssertPanicsWith(t, func() { spec.Init() }, tc.msg,
+			"panic for "+tc.limit)
+	}
+}
+
+func Test_FormInput_RangeLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		typ, limit, exp string
+	}{
+		{"int", "-10-10", `type="number" step="1" min="-10" max="10"`},
+		{"int", ">0", `type="number" step="1" min="1"`},
+		{"int64", "0-100 step=5", `type="number" step="5" min="0" max="100"`},
+		{"float", "<1e-3", `type="number" step="any" max="0.001"`},
+		{"float", ">0 <=1 step=0.1", `type="number" step="0.1" min="0" max="1"`},
+		{"string", "3-", `type="text" minlength="3"`},
+	} {
+		html, err := vebben.FormInput(
+			vebben.OptionalFormSpec("x", tc.typ, tc.limit), nil, nil)
+		if assert.Nil(err, tc.limit) {
+			assert.Equal(`<input name="x" id="x" `+tc.exp+` value="">`,
+				string(html), tc.limit)
+		}
+	}
+}
+
+func Test_JSONSchema_RangeLimits(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("a", "int", "-10-10 step=5"),
+		vebben.OptionalFormSpec("b", "int", ">0 step=2"),
+		vebben.OptionalFormSpec("c", "float", ">0 <=1 step=0.25"),
+		vebben.OptionalFormSpec("d", "float", "0.1- step=0.25"),
+		vebben.OptionalFormSpec("e", "string", "<10"),
+	}
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema",`+
+		`"properties":{`+
+		`"a":{"maximum":10,"minimum":-10,"multipleOf":5,"type":"integer"},`+
+		`"b":{"minimum":1,"multipleOf":2,"type":"integer"},`+
+		`"c":{"exclusiveMinimum":0,"maximum":1,"multipleOf":0.25,"type":"number"},`+
+		`"d":{"minimum":0.1,"type":"number"},`+
+		`"e":{"maxLength":9,"type":"string"}},`+
+		`"type":"object"}`, schemaJSON(t, specs))
+}
@@ -0,0 +1,198 @@
+// formrelative.go -- relative and natural-language dates.
+// ---------------
+
+package vebben
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RelativeDates controls whether the "date", "datetime" and "dateflex"
+// FormSpec types also accept dates relative to the current day, in English
+// or Hungarian, if the input matches none of their formats.  This is the
+// default for the RelativeDates setting of a Decoder.
+//
+// Relative dates include:
+//
+//   "today", "ma"                    // the current day
+//   "tomorrow", "holnap"             // and "day after tomorrow", "holnapután"
+//   "yesterday", "tegnap"            // and "day before yesterday", "tegnapelőtt"
+//   "friday", "péntek", "pénteken"   // the next Friday, or today if Friday
+//   "next friday", "jövő péntek"     // the next Friday after today
+//   "last friday", "múlt péntek"     // the last Friday before today
+//   "+3d", "-2w", "+1m", "+1y"       // days, weeks, months or years from today
+//   "in 3 days", "3 days ago"        // also weeks, months and years
+//   "3 nap múlva", "2 héttel ezelőtt" // also "hónap" and "év"
+//
+// Weekdays may also be abbreviated in English, e.g. "fri".  Months and
+// years are added to the day of the month, or to the last day of the month
+// if it is shorter, so a month after January 31 is the end of February.
+//
+// Dates are at midnight in the location of the Decoder; a time may follow
+// them for "datetime" and "dateflex", e.g. "tomorrow 10:30" or
+// "holnap 9:00".  Case and extra spaces are ignored.
+var RelativeDates = false
+
+// relativeDays are the relative dates that are a fixed number of days from
+// today.
+var relativeDays = map[string]int{
+	"today":                0,
+	"ma":                   0,
+	"tomorrow":             1,
+	"holnap":               1,
+	"day after tomorrow":   2,
+	"holnapután":           2,
+	"yesterday":            -1,
+	"tegnap":               -1,
+	"day before yesterday": -2,
+	"tegnapelőtt":          -2,
+}
+
+// relativeWeekdays are the names of the weekdays, with the Hungarian ones
+// also in the "on Monday" form.
+var relativeWeekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"hétfő": time.Monday, "hétfőn": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"kedd": time.Tuesday, "kedden": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"szerda": time.Wednesday, "szerdán": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"csütörtök": time.Thursday, "csütörtökön": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"péntek": time.Friday, "pénteken": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"szombat": time.Saturday, "szombaton": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"vasárnap": time.Sunday,
+}
+
+// relativeUnits are the units of relative offsets, as a number of days, or
+// of months if negative.
+var relativeUnits = map[string]int{
+	"d": 1, "day": 1, "days": 1, "nap": 1, "nappal": 1,
+	"w": 7, "week": 7, "weeks": 7, "hét": 7, "héttel": 7,
+	"m": -1, "month": -1, "months": -1, "hónap": -1, "hónappal": -1,
+	"y": -12, "year": -12, "years": -12, "év": -12, "évvel": -12,
+}
+
+var relativeSignedOffset = regexp.MustCompile(`^([+-]) ?(\d{1,4}) ?(\pL+)$`)
+var relativeWordOffset = regexp.MustCompile(
+	`^(in )?(\d{1,4}) (\pL+)( ago| múlva| ezelőtt)?$`)
+
+// now returns the current time from the Decoder's Clock, or time.Now.
+func (d *Decoder) now() time.Time {
+	if d.Clock == nil {
+		return time.Now()
+	}
+	return d.Clock()
+}
+
+// parseRelative returns raw as a relative date in the Decoder's location,
+// which may have a time if dateTime and must have one unless date, or
+// false if it is not one.
+func (d *Decoder) parseRelative(raw string, date, dateTime bool) (time.Time,
+	bool) {
+
+	fields := strings.Fields(strings.ToLower(raw))
+	hour, min := 0, 0
+	hasTime := false
+	if dateTime && len(fields) > 1 {
+		if t, err := time.Parse("15:04", fields[len(fields)-1]); err == nil {
+			hour, min = t.Hour(), t.Minute()
+			hasTime = true
+			fields = fields[:len(fields)-1]
+		}
+	}
+	if !hasTime && !date {
+		return time.Time{}, false
+	}
+
+	loc := d.location()
+	y, m, day := d.now().In(loc).Date()
+	today := time.Date(y, m, day, 0, 0, 0, 0, loc)
+	res, ok := relativeDay(strings.Join(fields, " "), today)
+	if !ok {
+		return time.Time{}, false
+	}
+	y, m, day = res.Date()
+	return time.Date(y, m, day, hour, min, 0, 0, loc), true
+}
+
+// relativeDay returns the day described by s relative to today.
+func relativeDay(s string, today time.Time) (time.Time, bool) {
+
+	if days, ok := relativeDays[s]; ok {
+		return today.AddDate(0, 0, days), true
+	}
+
+	// Weekdays:
+	dir := 0
+	name := s
+	for _, prefix := range []string{"next ", "jövő "} {
+		if strings.HasPrefix(s, prefix) {
+			dir, name = 1, s[len(prefix):]
+		}
+	}
+	for _, prefix := range []string{"last ", "múlt "} {
+		if strings.HasPrefix(s, prefix) {
+			dir, name = -1, s[len(prefix):]
+		}
+	}
+	if wd, ok := relativeWeekdays[name]; ok {
+		diff := (int(wd) - int(today.Weekday()) + 7) % 7
+		switch {
+		case dir > 0 && diff == 0:
+			diff = 7
+		case dir < 0:
+			diff -= 7
+		}
+		return today.AddDate(0, 0, diff), true
+	}
+
+	// Offsets:
+	var sign, num, unit string
+	if m := relativeSignedOffset.FindStringSubmatch(s); m != nil {
+		sign, num, unit = m[1], m[2], m[3]
+	} else if m := relativeWordOffset.FindStringSubmatch(s); m != nil {
+		switch {
+		case m[1] != "" && m[4] == "":
+			sign = "+"
+		case m[1] == "" && m[4] == " múlva":
+			sign = "+"
+		case m[1] == "" && m[4] != "":
+			sign = "-"
+		default:
+			return time.Time{}, false
+		}
+		num, unit = m[2], m[3]
+	} else {
+		return time.Time{}, false
+	}
+	size, ok := relativeUnits[unit]
+	if !ok {
+		return time.Time{}, false
+	}
+	n, _ := strconv.Atoi(num)
+	if sign == "-" {
+		n = -n
+	}
+	if size > 0 {
+		return today.AddDate(0, 0, n*size), true
+	}
+	return addMonths(today, -n*size), true
+}
+
+// addMonths returns t plus n months, on the last day of the month if it is
+// shorter than the day of the month of t.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
@@ -0,0 +1,150 @@
+// formrelative_test.go
+// --------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"testing"
+	"time"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func Test_Decoder_RelativeDates(t *testing.T) {
+
+	assert := assert.New(t)
+
+	loc := time.FixedZone("CEST", 2*3600)
+	dec := vebben.NewDecoder()
+	dec.Location = loc
+	dec.RelativeDates = true
+	// Wednesday, already Thursday in the Decoder's location:
+	dec.Clock = func() time.Time {
+		return time.Date(2023, 4, 5, 23, 30, 0, 0, time.UTC)
+	}
+	day := func(m time.Month, d int) string {
+		return time.Date(2023, m, d, 0, 0, 0, 0, loc).String()
+	}
+	at := func(m time.Month, d, h, min int) string {
+		return time.Date(2023, m, d, h, min, 0, 0, loc).String()
+	}
+
+	for _, tc := range []struct {
+		typ string
+		in  string
+		out string // empty for conversion errors
+	}{
+		{"date", "today", day(4, 6)},
+		{"date", "Ma", day(4, 6)},
+		{"date", "  TOMORROW ", day(4, 7)},
+		{"date", "holnap", day(4, 7)},
+		{"date", "day  after tomorrow", day(4, 8)},
+		{"date", "holnapután", day(4, 8)},
+		{"date", "yesterday", day(4, 5)},
+		{"date", "tegnap", day(4, 5)},
+		{"date", "tegnapelőtt", day(4, 4)},
+		{"date", "thursday", day(4, 6)},
+		{"date", "friday", day(4, 7)},
+		{"date", "Fri", day(4, 7)},
+		{"date", "péntek", day(4, 7)},
+		{"date", "szerdán", day(4, 12)},
+		{"date", "next friday", day(4, 7)},
+		{"date", "next thursday", day(4, 13)},
+		{"date", "jövő csütörtök", day(4, 13)},
+		{"date", "last thursday", day(3, 30)},
+		{"date", "last friday", day(3, 31)},
+		{"date", "múlt hétfő", day(4, 3)},
+		{"date", "+3d", day(4, 9)},
+		{"date", "+ 3 days", day(4, 9)},
+		{"date", "-2w", day(3, 23)},
+		{"date", "+1m", day(5, 6)},
+		{"date", "-1y", "2022-04-06 00:00:00 +0200 CEST"},
+		{"date", "in 3 days", day(4, 9)},
+		{"date", "in 1 week", day(4, 13)},
+		{"date", "2 weeks ago", day(3, 23)},
+		{"date", "3 nap múlva", day(4, 9)},
+		{"date", "2 héttel ezelőtt", day(3, 23)},
+		{"date", "1 hónap múlva", day(5, 6)},
+		{"date", "2023-04-01", day(4, 1)},
+		{"date", "tomorrow 10:30", ""},
+		{"date", "3 days", ""},
+		{"date", "in 3 days ago", ""},
+		{"date", "+3x", ""},
+		{"date", "next", ""},
+		{"date", "+12345d", ""},
+		{"datetime", "tomorrow 10:30", at(4, 7, 10, 30)},
+		{"datetime", "holnap 9:05", at(4, 7, 9, 5)},
+		{"datetime", "+1w 23:59", at(4, 13, 23, 59)},
+		{"datetime", "tomorrow", ""},
+		{"datetime", "tomorrow 24:00", ""},
+		{"datetime", "10:30", ""},
+		{"dateflex", "tomorrow", day(4, 7)},
+		{"dateflex", "next monday 8:00", at(4, 10, 8, 0)},
+	} {
+		desc := fmt.Sprintf("%s %q", tc.typ, tc.in)
+		v, err := dec.Convert(vebben.OptionalFormSpec("x", tc.typ), tc.in)
+		if tc.out == "" {
+			assert.Error(err, desc)
+			continue
+		}
+		if assert.Nil(err, desc) {
+			assert.Equal(tc.out, fmt.Sprint(v), desc)
+		}
+	}
+}
+
+func Test_Decoder_RelativeDates_EndOfMonth(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dec := vebben.NewDecoder()
+	dec.Location = time.UTC
+	dec.RelativeDates = true
+	dec.Clock = func() time.Time {
+		return time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	}
+	spec := vebben.OptionalFormSpec("x", "date")
+	for in, out := range map[string]time.Time{
+		"+1m":  time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"+2m":  time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		"-2m":  time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC),
+		"+13m": time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+	} {
+		v, err := dec.Convert(spec, in)
+		if assert.Nil(err, in) {
+			assert.Equal(out, v, in)
+		}
+	}
+}
+
+func Test_DecodeForm_RelativeDates_OptIn(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{vebben.RequiredFormSpec("day", "date")}
+	form := vebben.URLValues{"day": {"today"}}
+
+	err := vebben.DecodeForm(form, specs, &map[string]interface{}{})
+	if assert.Error(err) {
+		assert.Equal(vebben.CodeConversion,
+			err.(*vebben.MultiError).FieldErrors()[0].Code)
+	}
+	assert.Contains(vebben.ValidatorJS(specs), `"date":[[`)
+
+	vebben.RelativeDates = true
+	defer func() { vebben.RelativeDates = false }()
+
+	target := map[string]interface{}{}
+	if assert.Nil(vebben.DecodeForm(form, specs, &target)) {
+		y, m, d := time.Now().In(vebben.FormValueTimeLocation).Date()
+		assert.Equal(time.Date(y, m, d, 0, 0, 0, 0,
+			vebben.FormValueTimeLocation), target["day"])
+	}
+	assert.Contains(vebben.ValidatorJS(specs), `,null]`)
+}
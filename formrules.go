@@ -0,0 +1,207 @@
+// formrules.go -- cross-field validation rules.
+// ------------
+
+package vebben
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Rule is a form-level validation rule, for checks involving more than one
+// value, such as a password confirmation.  Rules are given to DecodeForm
+// with the WithRules option, and are run in order after all values have
+// been converted and validated; they report failures with the Fail method
+// of the RuleContext, e.g.:
+//
+//   noAdmin := func(rc *vebben.RuleContext) {
+//       if rc.Value("user") == "admin" && rc.Empty("token") {
+//           rc.Fail("admin_token", nil, "token")
+//       }
+//   }
+//   err := vebben.DecodeForm(r, specs, target, vebben.WithRules(noAdmin))
+//
+// The resulting FieldErrors are added to the MultiError after those of the
+// individual values.
+type Rule func(rc *RuleContext)
+
+// RuleContext gives a Rule access to the decoded values, by their full
+// form keys, and collects its failures.
+type RuleContext struct {
+	d      *formDecoding
+	values map[string]interface{}
+	keys   []string
+}
+
+// WithRules adds rules to be run by DecodeForm.
+func WithRules(rules ...Rule) DecodeOption {
+	return func(c *decodeConfig) {
+		c.rules = append(c.rules, rules...)
+	}
+}
+
+// runRules runs the configured rules on the decoded results.
+func (d *formDecoding) runRules() {
+
+	if len(d.config.rules) == 0 {
+		return
+	}
+	rc := &RuleContext{d: d, values: map[string]interface{}{}}
+	for _, res := range d.results {
+		rc.values[res.key] = res.value
+		rc.keys = append(rc.keys, res.key)
+	}
+	for _, rule := range d.config.rules {
+		rule(rc)
+	}
+}
+
+// Keys returns the keys of all successfully decoded values, in spec order.
+func (rc *RuleContext) Keys() []string {
+	return append([]string{}, rc.keys...)
+}
+
+// Lookup returns the value for key, and whether it was successfully
+// decoded.  Values that failed conversion or validation are not available
+// to rules, nor are those of unknown keys.
+func (rc *RuleContext) Lookup(key string) (interface{}, bool) {
+	v, ok := rc.values[key]
+	return v, ok
+}
+
+// Value returns the value for key, or nil if it is not available.
+func (rc *RuleContext) Value(key string) interface{} {
+	return rc.values[key]
+}
+
+// Empty returns true if the value for key is not available or is the zero
+// value of its type, as it is for missing optional inputs.
+func (rc *RuleContext) Empty(key string) bool {
+	v, ok := rc.values[key]
+	return !ok || v == nil || reflect.ValueOf(v).IsZero()
+}
+
+// Fail adds a FieldError with code and params, which may be nil, for each
+// of the keys.  The Name of each FieldError is that of the FormSpec for its
+// key.  If no keys are given, a single FieldError with an empty Key is
+// added, which applies to the form as a whole.
+func (rc *RuleContext) Fail(code string, params map[string]interface{},
+	keys ...string) {
+
+	if len(keys) == 0 {
+		keys = []string{""}
+	}
+	for _, key := range keys {
+		rc.d.errors = append(rc.d.errors, &FieldError{
+			Key:    key,
+			Name:   rc.Name(key),
+			Code:   code,
+			Params: params,
+		})
+	}
+}
+
+// Name returns the Name of the FormSpec for key, or the key itself if
+// there is none.
+func (rc *RuleContext) Name(key string) string {
+	if spec := rc.d.specs[key]; spec != nil && spec.Name != "" {
+		return spec.Name
+	}
+	return key
+}
+
+// SameAs returns a Rule requiring the value for key to equal that for
+// other, e.g. for password confirmations.  The failure has CodeMismatch and
+// is attached to key, with the Name of other as the "other" param.  The
+// rule does not apply if either value is unavailable.
+func SameAs(key, other string) Rule {
+	return func(rc *RuleContext) {
+		a, ok1 := rc.Lookup(key)
+		b, ok2 := rc.Lookup(other)
+		if !ok1 || !ok2 || reflect.DeepEqual(a, b) {
+			return
+		}
+		rc.Fail(CodeMismatch, map[string]interface{}{
+			"other": rc.Name(other),
+		}, key)
+	}
+}
+
+// After returns a Rule requiring the value for key to be later than that
+// for other if both are times, or greater if both are numbers of the same
+// type, e.g. for date ranges.  The failure has CodeNotAfter and is attached
+// to key, with the Name of other as the "other" param.  The rule does not
+// apply if either value is unavailable or empty.
+func After(key, other string) Rule {
+	return func(rc *RuleContext) {
+		if rc.Empty(key) || rc.Empty(other) {
+			return
+		}
+		cmp, ok := compareValues(rc.Value(key), rc.Value(other))
+		if !ok || cmp > 0 {
+			return
+		}
+		rc.Fail(CodeNotAfter, map[string]interface{}{
+			"other": rc.Name(other),
+		}, key)
+	}
+}
+
+// AtLeastOne returns a Rule requiring at least one of the values for keys
+// to be non-empty, e.g. for alternative contact details.  The failure has
+// CodeOneRequired and is attached to the first key, with the Names of all
+// the keys joined by commas as the "names" param.
+func AtLeastOne(keys ...string) Rule {
+	return func(rc *RuleContext) {
+		if len(keys) == 0 {
+			return
+		}
+		names := make([]string, len(keys))
+		for idx, key := range keys {
+			if !rc.Empty(key) {
+				return
+			}
+			names[idx] = rc.Name(key)
+		}
+		rc.Fail(CodeOneRequired, map[string]interface{}{
+			"names": strings.Join(names, ", "),
+		}, keys[0])
+	}
+}
+
+// compareValues compares a and b if they are times or numbers of the same
+// type, returning -1, 0 or 1 and true; or false if they can not be
+// compared.
+func compareValues(a, b interface{}) (int, bool) {
+
+	switch av := a.(type) {
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return compareOrdered(av.Before(bv), av.After(bv)), true
+		}
+	case int:
+		if bv, ok := b.(int); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	case int64:
+		if bv, ok := b.(int64); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return compareOrdered(av < bv, av > bv), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered(less, more bool) int {
+	switch {
+	case less:
+		return -1
+	case more:
+		return 1
+	}
+	return 0
+}
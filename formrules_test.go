@@ -0,0 +1,183 @@
+// formrules_test.go
+// -----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"testing"
+
+	// Third-party:
+	"golang.org/x/text/language"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+var ruleSpecs = []*vebben.FormSpec{
+	vebben.RequiredFormSpec("password", "string", "", "Password"),
+	vebben.RequiredFormSpec("confirm", "string", "", "Confirmation"),
+	vebben.OptionalFormSpec("start", "date", "", "Start"),
+	vebben.OptionalFormSpec("end", "date", "", "End"),
+	vebben.OptionalFormSpec("phone", "string", "", "Phone"),
+	vebben.OptionalFormSpec("email", "string", "", "Email"),
+	vebben.OptionalFormSpec("min", "int", "1-10", "Min"),
+	vebben.OptionalFormSpec("max", "int", "", "Max"),
+}
+
+var standardRules = vebben.WithRules(
+	vebben.SameAs("confirm", "password"),
+	vebben.After("end", "start"),
+	vebben.AtLeastOne("phone", "email"),
+	vebben.After("max", "min"),
+)
+
+func Test_DecodeForm_Rules_Success(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"password": {"sekrit"},
+		"confirm":  {"sekrit"},
+		"start":    {"2023-01-01"},
+		"end":      {"2023-01-02"},
+		"email":    {"a@example.com"},
+		"min":      {"2"},
+		"max":      {"3"},
+	}
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(f, ruleSpecs, &target, standardRules)
+	assert.Nil(err)
+	assert.Equal("sekrit", target["confirm"])
+
+}
+
+func Test_DecodeForm_Rules_Failures(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"password": {"sekrit"},
+		"confirm":  {"secret"},
+		"start":    {"2023-01-02"},
+		"end":      {"2023-01-02"},
+		"min":      {"5"},
+		"max":      {"4"},
+	}
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(f, ruleSpecs, &target, standardRules)
+	if !assert.Error(err) {
+		return
+	}
+	assert.Empty(target, "nothing assigned")
+	me := err.(*vebben.MultiError)
+	assert.Equal([]string{"confirm", "end", "phone", "max"}, me.Keys())
+	assert.Equal("Confirmation does not match Password\n"+
+		"End must be after Start\n"+
+		"At least one of Phone, Email is required\n"+
+		"Max must be after Min", err.Error())
+	fe := me.ByKey("phone")[0]
+	assert.Equal(vebben.CodeOneRequired, fe.Code)
+	assert.Equal("Phone, Email", fe.Params["names"])
+
+	err = vebben.DecodeForm(f, ruleSpecs, &target, standardRules,
+		vebben.Language(language.Hungarian))
+	if assert.Error(err) {
+		assert.Equal("Confirmation nem egyezik: Password\n"+
+			"End nem későbbi, mint Start\n"+
+			"Legalább egy megadása kötelező: Phone, Email\n"+
+			"Max nem későbbi, mint Min", err.Error())
+	}
+
+}
+
+func Test_DecodeForm_Rules_SkipFailedValues(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"confirm": {"secret"},
+		"phone":   {"123"},
+		"start":   {"nope"},
+		"end":     {"2023-01-02"},
+		"min":     {"11"},
+		"max":     {"4"},
+	}
+	err := vebben.DecodeForm(f, ruleSpecs, &map[string]interface{}{},
+		standardRules)
+	if assert.Error(err) {
+		assert.Equal([]string{"password", "start", "min"},
+			err.(*vebben.MultiError).Keys(), "only field errors")
+	}
+
+}
+
+func Test_DecodeForm_Rules_Custom(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var keys []string
+	rule := func(rc *vebben.RuleContext) {
+		keys = rc.Keys()
+		if v, ok := rc.Lookup("nope"); ok || v != nil {
+			t.Fatal("unknown key found")
+		}
+		if rc.Value("phone") == "123" && rc.Empty("email") {
+			rc.Fail("phone_only", map[string]interface{}{"x": 1},
+				"phone", "email")
+			rc.Fail("form_level", nil)
+		}
+	}
+	cat := vebben.NewCatalog()
+	cat.Set(language.English, "phone_only", "{name}: no phone-only ({x})")
+	cat.Set(language.English, "form_level", "The form is bad")
+
+	f := vebben.URLValues{
+		"password": {"sekrit"},
+		"confirm":  {"sekrit"},
+		"phone":    {"123"},
+		"min":      {"1"},
+	}
+	err := vebben.DecodeForm(f, ruleSpecs, &map[string]interface{}{},
+		vebben.WithRules(rule), vebben.WithCatalog(cat))
+	if assert.Error(err) {
+		assert.Equal([]string{"phone", "email", ""},
+			err.(*vebben.MultiError).Keys())
+		assert.Equal("Phone: no phone-only (1)\nEmail: no phone-only (1)\n"+
+			"The form is bad", err.Error())
+	}
+	assert.Equal([]string{"password", "confirm", "start", "end", "phone",
+		"email", "min", "max"}, keys)
+
+}
+
+func Test_DecodeForm_Rules_Groups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{
+		vebben.RepeatedFormSpec("rows", []*vebben.FormSpec{
+			vebben.OptionalFormSpec("from", "int", "", "From"),
+			vebben.OptionalFormSpec("to", "int", "", "To"),
+		}),
+	}
+	f := vebben.URLValues{
+		"rows[0].from": {"1"},
+		"rows[0].to":   {"2"},
+		"rows[4].from": {"3"},
+		"rows[4].to":   {"3"},
+	}
+	err := vebben.DecodeForm(f, specs, &map[string]interface{}{},
+		vebben.WithRules(
+			vebben.After("rows[0].to", "rows[0].from"),
+			vebben.After("rows[4].to", "rows[4].from"),
+		))
+	if assert.Error(err) {
+		assert.Equal([]string{"rows[4].to"}, err.(*vebben.MultiError).Keys())
+		assert.Equal("To must be after From", err.Error())
+	}
+
+}
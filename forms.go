@@ -4,18 +4,21 @@
 package vebben
 
 import (
-	"encoding/json"
 	"fmt"
-	"regexp"
+	"net/http"
+	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// By default, trim space from form values.
+// By default, trim space from form values.  This is the default for the
+// TrimSpace setting of a Decoder; see there.
 var DecodeFormTrimSpace = true
 
-// FormValueTimeLocation is the location (time zone) used for all form input.
+// FormValueTimeLocation is the location (time zone) used for all form input,
+// and the default for the Location setting of a Decoder.
 var FormValueTimeLocation, _ = time.LoadLocation("CET")
 
 // DateFormats holds the date formats we accept in forms (note: not times,
@@ -42,26 +45,40 @@ var DateFormats = []string{
 }
 
 // DateTimeFormats holds the datetime formats we accept in forms (note: all
-// require times, and only to minute precision; this might change in a
-// general library).
+// require times, to the minute or the second).  Seconds may have a
+// fraction, e.g. "10:30:15.250", in any format with seconds; and values
+// with an offset, e.g. "2023-04-05T10:30:15+02:00" (RFC 3339), keep it
+// instead of being in FormValueTimeLocation.
 var DateTimeFormats = []string{
 	"2006. 01. 02. 15:04",
+	"2006. 01. 02. 15:04:05",
 	"2006. 01. 02 15:04",
 	"2006. 1. 2. 15:04",
 	"2006. 1. 2 15:04",
 	"2006.01.02. 15:04",
+	"2006.01.02. 15:04:05",
 	"2006.01.02 15:04",
 	"2006.1.2. 15:04",
 	"2006.1.2 15:04",
 	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
 	"2006-1-2 15:04",
+	"2006-01-02T15:04",    // HTML datetime-local input
+	"2006-01-02T15:04:05", // the same, with seconds
 	"2006 01 02 15:04",
 	"2006 1 2 15:04",
 	"20060102150405",
+	"20060102T150405",
 	"02.01.2006 15:04",
 	"2.1.2006 15:04",
 	"01/02/2006 15:04",
 	"1/2/2006 15:04",
+	// ISO 8601 and RFC 3339, with offsets or "Z" for UTC:
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"20060102T150405Z0700",
 	// etc as needed
 }
 
@@ -72,6 +89,45 @@ type FormValuer interface {
 	FormValue(string) string
 }
 
+// MultiFormValuer is a FormValuer that can also return every value submitted
+// for a key, as is needed for checkbox groups and multiple selects.  The
+// http.Request does not implement it, but is handled specially by DecodeForm,
+// which reads all values from its parsed Form.
+type MultiFormValuer interface {
+	FormValuer
+	FormValues(string) []string
+}
+
+// URLValues adapts url.Values, e.g. the Form of a parsed http.Request, to
+// the MultiFormValuer interface.
+type URLValues url.Values
+
+// FormValue returns the first value for key k, or an empty string.
+func (v URLValues) FormValue(k string) string {
+	return url.Values(v).Get(k)
+}
+
+// FormValues returns all values for key k.
+func (v URLValues) FormValues(k string) []string {
+	return v[k]
+}
+
+// formValues returns all the values available from f for key k.  If f can
+// only provide single values, a missing or empty value results in nil.
+func formValues(f FormValuer, k string) []string {
+	switch v := f.(type) {
+	case MultiFormValuer:
+		return v.FormValues(k)
+	case *http.Request:
+		v.FormValue(k) // parses the form if that is not yet done.
+		return v.Form[k]
+	}
+	if s := f.FormValue(k); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
 // MultiError is a type of error that contains a slice of errors.  In the
 // standard Error method they are joined with a newline, but if cast to
 // type the errors may be examined (or formatted) individually.
@@ -90,62 +146,155 @@ func (e *MultiError) Error() string {
 }
 
 // AddFormSpecType adds or replaces FormSpec type t with converter function
-// cf and optional default validator vf.  The converter must return a type
-// that survives JSON marshaling and unmarshaling or runtime errors will
-// occur in DecodeForm; its bool return value is the success or failure of
-// the conversion.  Note that in many cases this is unnecessary, as the
-// struct's final type will unmarshal from a simple string.
+// cf and optional default validator vf in DefaultTypes.  The converter may
+// return any value that DecodeForm can assign to the target field; its bool
+// return value is the success or failure of the conversion.  Note that in
+// many cases a string is enough, as the struct's final type will unmarshal
+// from it via encoding.TextUnmarshaler.
+//
+// Custom validators should return FieldErrors, for which messages may be
+// added to the DefaultCatalog (or any other Catalog) in all languages
+// needed, e.g.:
+//
+//   vebben.DefaultCatalog.Set(language.Hungarian, "bad_sku",
+//       "{name}: érvénytelen cikkszám")
+//
+// The JSON Schema for the type may be set with SetFormSpecTypeSchema.  To
+// add types without affecting other users of DefaultTypes, use a
+// TypeRegistry of your own.
 func AddFormSpecType(t string, cf func(string) (interface{}, bool),
 	vf func(*FormSpec, interface{}) error) {
 
-	formSpecTypeMap[t] = &formSpecType{
-		converter: cf,
-		validator: vf,
-		custom:    true,
-	}
+	DefaultTypes.Add(t, cf, vf)
 }
 
 type formSpecType struct {
 	converter func(string) (interface{}, bool)
 	validator func(*FormSpec, interface{}) error
 	custom    bool
+	file      bool
+	group     bool
+	schema    func(*FormSpec) map[string]interface{}
+	text      bool // limits apply to the string form as for strings
+
+	// decode replaces the converter for types depending on the settings of
+	// the Decoder, e.g. dates and numbers.
+	decode func(*Decoder, string) (interface{}, bool)
+
+	// finish completes the conversion of non-empty input with the limits
+	// of the spec and the settings of the Decoder, if set.
+	finish func(*Decoder, *FormSpec, interface{}) (interface{}, error)
 }
 
-var formSpecTypeMap = map[string]*formSpecType{
+// standardFormSpecTypes are the types of every new TypeRegistry.  They are
+// never changed, as registries replace their entries instead.
+var standardFormSpecTypes = map[string]*formSpecType{
 	"bool":     &formSpecType{converter: boolConverter},
-	"date":     &formSpecType{converter: dateConverter},
-	"dateflex": &formSpecType{converter: dateFlexConverter},
-	"datetime": &formSpecType{converter: dateTimeConverter},
-	"float":    &formSpecType{converter: floatConverter, validator: floatValidator},
-	"int":      &formSpecType{converter: intConverter, validator: intValidator},
-	"int64":    &formSpecType{converter: int64Converter, validator: int64Validator},
+	"cidr":     &formSpecType{converter: cidrConverter, validator: netValidator, text: true},
+	"date":     &formSpecType{decode: dateConverter},
+	"dateflex": &formSpecType{decode: dateFlexConverter, validator: dateTimeValidator},
+	"datetime": &formSpecType{decode: dateTimeConverter, validator: dateTimeValidator},
+	"decimal":  &formSpecType{decode: decimalConverter, validator: decimalValidator, finish: decimalFinish},
+	"email":    &formSpecType{decode: emailConverter, validator: netValidator, text: true},
+	"file":     &formSpecType{converter: fileConverter, validator: fileValidator, file: true},
+	"float":    &formSpecType{decode: floatConverter, validator: floatValidator},
+	"group":    &formSpecType{converter: groupConverter, group: true},
+	"hostname": &formSpecType{decode: hostnameConverter, validator: netValidator, text: true},
+	"int":      &formSpecType{decode: intConverter, validator: intValidator},
+	"int64":    &formSpecType{decode: int64Converter, validator: int64Validator},
+	"ip":       &formSpecType{converter: ipConverter(0), validator: netValidator, text: true},
+	"ipv4":     &formSpecType{converter: ipConverter(4), validator: netValidator, text: true},
+	"ipv6":     &formSpecType{converter: ipConverter(6), validator: netValidator, text: true},
+	"money":    &formSpecType{decode: moneyConverter, validator: decimalValidator, finish: decimalFinish},
 	"string":   &formSpecType{converter: stringConverter, validator: stringValidator},
+	"url":      &formSpecType{decode: urlConverter, validator: netValidator, finish: urlFinish, text: true},
 }
 
 // FormSpec defines a single specification item for validating a form
 // value corresponding to Key.  It is used by DecodeForm.
 //
+// The Key may be nested, with dots for struct fields and map keys and with
+// brackets for slice indexes and map keys, e.g. "address.city",
+// "items[2].qty" or "meta[color]".  For repeated groups of values see
+// RepeatedFormSpec.
+//
 // Valid Type values include:
 //
 //   "string"       // plain string
 //   "int"          // int, max 32 bits large
 //   "int64"        // int64
 //   "float"        // float64
+//   "decimal"      // exact decimal number (Decimal); see below.
+//   "money"        // exact amount of money (Money); see below.
 //   "bool"         // bool: input must be "true" or "false" if required
 //   "date"         // date, without time part; see below.
 //   "datetime"     // date, with time part; see below.
 //   "dateflex"     // date, with or without time part; see below.
+//   "file"         // uploaded file (*multipart.FileHeader); see below.
+//   "group"        // group of FormSpecs; see GroupFormSpec.
+//   "email"        // email address (*mail.Address); see below.
+//   "url"          // absolute URL (*url.URL); see below.
+//   "hostname"     // domain name (string); see below.
+//   "ip"           // IPv4 or IPv6 address (netip.Addr)
+//   "ipv4"         // IPv4 address (netip.Addr)
+//   "ipv6"         // IPv6 address (netip.Addr)
+//   "cidr"         // IP address prefix, e.g. "10.0.0.0/8" (netip.Prefix)
+//
+// This list can be extended using the AddFormSpecType function, or for
+// the FormSpecs of a single TypeRegistry, set as their Types, with its Add
+// method.
 //
-// This list can be extended using the AddFormSpecType function.
+// Any type may also be given as a slice type by prefixing it with "[]", e.g.
+// "[]string" or "[]date", for multi-value fields such as checkbox groups and
+// multiple selects.  Each submitted value is converted and validated as an
+// item of the base type, and empty values are skipped.  The MinItems and
+// MaxItems properties limit the number of items, if nonzero; note that a
+// Required slice must always have at least one item.
 //
 // The Limit describes a validation check, and may be left as an empty
 // string.  Limits include:
 //
-//   "123"          // length (strings, int, int64)
-//   "1-10"         // allowed range of value (numeric) or length (string)
+//   "123"          // length (strings, int, int64, file names)
+//   "1-10"         // range of value (numeric) or length (string, file)
 //   "a,b,c"        // list of simple string values accepted
 //   "1,3,5"        // list of simple numeric values accepted
-//   "re:^\w\d+$"   // regular expression (strings only)
+//   "re:^\w\d+$"   // regular expression (strings and file names only)
+//
+// Ranges are made of clauses separated by spaces, which may be combined:
+//
+//   "-10-10"       // lower and upper bounds, inclusive; signs allowed
+//   "5-"           // lower bound only
+//   ">=0"          // lower bound only, inclusive
+//   "<100"         // upper bound only, exclusive
+//   ">0 <=1"       // exclusive lower and inclusive upper bound
+//   "0-100 step=5" // value must be a whole number of steps from the lower
+//                  // bound, or from zero if there is none (numeric only)
+//
+// Bounds and steps of int and int64 ranges must be integers in the range of
+// the type, while float ranges accept any number, e.g. "0-2.5" or
+// ">=1e-3", and decimal and money ranges any decimal without an exponent,
+// which they compare exactly.  A value outside a range is too low or high,
+// or too short or long for lengths; a value off its step has the
+// CodeWrongStep error.
+//
+// Several limits may be combined as clauses separated by semicolons, each
+// naming its kind:
+//
+//   "len=3-20;re=^[a-z0-9_]+$;not=admin,root"
+//
+//   len=4          // length, or length range for strings and file names
+//   range=0-100    // range of value (numeric)
+//   re=^\w+$       // regular expression (strings and file names)
+//   in=a,b,c       // list of values accepted
+//   not=a,b,c      // list of values not accepted (CodeNotAllowed)
+//   scale=2        // decimal places (decimal and money)
+//   round=half-up  // rounding to the scale (decimal and money)
+//   currency=EUR   // currencies accepted, the first as default (money)
+//   precision=second // precision of datetimes (datetime and dateflex)
+//
+// Each kind may be given once, and the clauses are checked in order, the
+// first one failing giving the error.  A regular expression may contain
+// semicolons unless followed by a clause name and "=".
 //
 // Note that the Limit is only processed during the Init phase.  If Init is
 // not called, the Validator should enforce any custom limits.
@@ -153,12 +302,71 @@ var formSpecTypeMap = map[string]*formSpecType{
 // The optional Name is used in formatting error messages that may be shown to
 // the user, e.g. "<Name> is out of range."
 //
-// Dates are valid in any format listed under DateFormats; DateTimes use
-// those in DateTimeFormat; DateFlex use both.
+// Numbers of the int, int64, float, decimal and money types may also be
+// given in the format of NumberLocale, e.g. "1 234,56"; see there and
+// StrictNumbers.
+//
+// Dates are valid in any format listed under DateFormats, or as ISO 8601
+// week dates, e.g. "2023-W14-3", "2023W143", or "2023-W14" for the Monday
+// of the week as submitted by week inputs; DateTimes use those in
+// DateTimeFormats; DateFlex use both.  All may also accept relative dates
+// such as "tomorrow 10:30"; see RelativeDates.  The Limit of DateTimes and
+// DateFlex may have a precision clause, "precision=minute", "second" or
+// "millisecond", rejecting more precise values with the CodeBadPrecision
+// error, e.g. "10:30:15" for "minute".
+//
+// Files are read from a multipart form (see FileFormValuer), and their
+// Limit applies to the file name.  Files larger than MaxSize bytes are
+// rejected if it is nonzero, as are those whose content type, as detected
+// from the content itself by http.DetectContentType, does not match one of
+// the Accept types if there are any.  An Accept type may end in "/*" to
+// match all subtypes, e.g. "image/*".  File names containing path
+// separators or control characters are always rejected.
+//
+// Emails are bare addresses such as "joe@example.com", without names or
+// angle brackets.  URLs must be absolute, and their schemes are limited to
+// URLSchemes, or to those of a "schemes=" Limit clause, e.g.
+// "schemes=https,ftp", with the CodeBadScheme error.  Domain names in
+// emails, URLs and hostnames are validated and normalized offline, without
+// DNS lookups; see DomainsToASCII and LowercaseEmails.  Values of these
+// types, and of the IP address types, may be assigned to string fields as
+// well, and length, regexp and list limits apply to their string forms.
+//
+// Decimals are numbers such as "-12.30", without exponents or digit
+// separators, converted exactly to a Decimal that keeps the scale of the
+// input unless the Limit has a scale clause.  Money is an amount with an
+// ISO 4217 currency code before or after it, e.g. "12.30 EUR" or
+// "EUR 12.30", converted to Money; the code may be omitted if the Limit
+// has a currency clause, which also limits the currencies accepted, with
+// the CodeBadCurrency error.  The scale of money is that of the scale
+// clause, or else the standard one of its currency, e.g. 2 for EUR and 0
+// for JPY.  Input with more decimal places than the scale has the
+// CodeTooPrecise error, unless the Limit has a round clause with one of
+// the modes "half-up", "half-even", "up" (away from zero) or "down"
+// (toward zero); input with fewer is padded, so "12.3" is 12.30 with a
+// scale of 2.  Both may be assigned to Decimal, Money and string fields,
+// and their amounts to integer fields, as the Units (i.e. minor units such
+// as cents for money), or approximately to float fields.
+//
+// A FormSpec that is not Required is treated as such if all its
+// RequiredIf Conditions hold, as determined from the raw input before any
+// other processing; and if all its ForbiddenIf Conditions hold, any input
+// for it is an error.  For example, to require a company name for company
+// invoices:
+//
+//   spec := vebben.OptionalFormSpec("company_name", "string")
+//   spec.RequiredIf = []vebben.Condition{vebben.When("invoice_type", "company")}
+//
+// Sensitive values such as passwords are never kept in FormState, rendered
+// by FormInput, or included as the Input of FieldErrors.
 //
 // The Validator function is called with the FormSpec itself and the
 // type-converted value (cf. Convert). Standard Validator functions are set
-// by Init if no Validator exists when it is called.
+// by Init if no Validator exists when it is called.  For slice types it is
+// called once for each item.  Validators should return a FieldError (see
+// the FieldError method) so the failure can be examined by Code.  The
+// Validator of an initialized FormSpec should be replaced with
+// SetValidator, so it is no longer taken for the standard one.
 type FormSpec struct {
 	Key       string
 	Type      string
@@ -166,14 +374,22 @@ type FormSpec struct {
 	Limit     string
 	Name      string
 	Validator func(*FormSpec, interface{}) error
+	MinItems  int
+	MaxItems  int
+	MaxSize   int64
+	Accept    []string
+	Group     []*FormSpec
+	Sensitive bool          // e.g. passwords, never echoed back; see FormState
+	Messages  *Catalog      // messages overriding the Catalog, if any
+	Types     *TypeRegistry // registry of the Type; DefaultTypes if nil
+
+	// Conditions for Required, and for the input to be forbidden:
+	RequiredIf  []Condition
+	ForbiddenIf []Condition
 
 	// Helpers for standard validators:
-	limitLength     int
-	limitRangeInt   []int64
-	limitRangeFloat []float64
-	limitListString []string
-	limitListInt    []int64
-	limitRegexp     *regexp.Regexp
+	limits            []*formLimit
+	standardValidator bool // Validator was set by Init for the type
 }
 
 // Init validates the FormSpec and prepares it for use.  This should
@@ -182,32 +398,94 @@ type FormSpec struct {
 // programmer error, failures result in panic.
 func (fs *FormSpec) Init() {
 
-	t := formSpecTypeMap[fs.Type]
+	t := fs.specType()
 	if t == nil {
 		panic("Unsupported FormSpec type: " + fs.Type)
 	}
+	if fs.MinItems != 0 || fs.MaxItems != 0 {
+		if !fs.isMulti() {
+			panic("Item count limits do not apply to " + fs.Type)
+		}
+		if fs.MinItems < 0 || fs.MaxItems < 0 {
+			panic("Bad item count limit: negative count")
+		}
+		if fs.MaxItems > 0 && fs.MaxItems < fs.MinItems {
+			panic("Bad item count limit: MaxItems < MinItems")
+		}
+	}
+	if (fs.MaxSize != 0 || len(fs.Accept) > 0) && !t.file {
+		panic("File limits do not apply to " + fs.Type)
+	}
+	if fs.MaxSize < 0 {
+		panic("Bad file size limit: negative size")
+	}
+	if t.group {
+		if len(fs.Group) == 0 {
+			panic("Group FormSpec requires Group specs")
+		}
+		if fs.Limit != "" {
+			panic("Limit does not apply to " + fs.Type)
+		}
+	} else if len(fs.Group) > 0 {
+		panic("Group specs do not apply to " + fs.Type)
+	}
+	if _, err := parseFormKey(fs.Key); err != nil {
+		panic("Bad FormSpec key: " + err.Error())
+	}
+	fs.initConditions()
 	// Parse the Limit only for standard types.
 	if !t.custom {
 		fs.initLimit()
 	}
 	if fs.Validator == nil {
 		fs.Validator = t.validator
+		fs.standardValidator = t.validator != nil
 	}
 
 }
 
-// Convert converts raw to the type indicated in the FormSpec's Type property,
-// returning an error if it can not be converted.  If there is no error then
-// the returned value is safe to pass to a standard Validator function.
-func (fs *FormSpec) Convert(raw string) (interface{}, error) {
-	val, ok := formSpecTypeMap[fs.Type].converter(raw)
-	if !ok {
-		// TODO: consider bubbling up errors for things like int out of range.
-		return nil, fmt.Errorf("%s could not be converted to %s",
-			fs.Name, fs.Type)
+// SetValidator replaces the Validator of an initialized FormSpec with vf,
+// which is then no longer taken for the standard Validator of the type,
+// e.g. by ValidatorJS.
+func (fs *FormSpec) SetValidator(vf func(*FormSpec, interface{}) error) {
+	fs.Validator = vf
+	fs.standardValidator = false
+}
+
+// isMulti returns true if the FormSpec has a slice type.
+func (fs *FormSpec) isMulti() bool {
+	return strings.HasPrefix(fs.Type, "[]")
+}
+
+// isFile returns true if the FormSpec has a file type or slice thereof.
+func (fs *FormSpec) isFile() bool {
+	t := fs.specType()
+	return t != nil && t.file
+}
+
+// specType returns the item type of the FormSpec from its registry, or nil
+// if the registry has no such type.
+func (fs *FormSpec) specType() *formSpecType {
+	if fs.Types == nil {
+		return DefaultTypes.lookup(fs.itemType())
 	}
-	return val, nil
+	return fs.Types.lookup(fs.itemType())
+}
 
+// itemType returns the base type of a slice type, or the type itself.
+func (fs *FormSpec) itemType() string {
+	return strings.TrimPrefix(fs.Type, "[]")
+}
+
+// Convert converts raw to the type indicated in the FormSpec's Type property,
+// returning a FieldError if it can not be converted.  If there is no error then
+// the returned value is safe to pass to a standard Validator function.  For
+// slice types, raw is converted to a single item of the base type.
+//
+// Dates and numbers are converted with the package-level settings; see
+// Decoder.Convert for other settings.
+func (fs *FormSpec) Convert(raw string) (interface{}, error) {
+	return packageDecoder().Convert(fs, raw)
 }
 
 // Copy returns a copy of the FormSpec with a new Key and Name.  The Key
@@ -233,120 +511,54 @@ func (fs *FormSpec) Copy(key, name string) *FormSpec {
 		Limit:     fs.Limit,
 		Name:      name,
 		Validator: fs.Validator,
+		MinItems:  fs.MinItems,
+		MaxItems:  fs.MaxItems,
+		MaxSize:   fs.MaxSize,
+		Accept:    fs.Accept,
+		Group:     fs.Group,
+		Sensitive: fs.Sensitive,
+		Messages:  fs.Messages,
+		Types:     fs.Types,
+
+		RequiredIf:  fs.RequiredIf,
+		ForbiddenIf: fs.ForbiddenIf,
 
 		// And:
-		limitLength:     fs.limitLength,
-		limitRangeInt:   fs.limitRangeInt,
-		limitRangeFloat: fs.limitRangeFloat,
-		limitListString: fs.limitListString,
-		limitListInt:    fs.limitListInt,
-		limitRegexp:     fs.limitRegexp,
+		limits:            fs.limits,
+		standardValidator: fs.standardValidator,
 	}
 
 }
 
-var formSpecLimitMatchLength = regexp.MustCompile("^[1-9][0-9]*$")
-var formSpecLimitMatchRangeInt = regexp.MustCompile("^([0-9]+)-([0-9]+)$")
-var formSpecLimitMatchRangeFloat = regexp.MustCompile("^([0-9]*[.][0-9]+)-([0-9]*[.][0-9]+)$")
-
-func (fs *FormSpec) initLimit() {
-
-	val := fs.Limit
-	if val == "" {
-		return
+// withTypes returns a copy of fs, and of its Group specs without Types,
+// initialized with the types of r.  Its Validator is the standard one of
+// its type in r unless it has another.
+func (fs *FormSpec) withTypes(r *TypeRegistry) *FormSpec {
+	c := *fs
+	c.Types = r
+	c.limits = nil
+	if c.standardValidator {
+		c.Validator, c.standardValidator = nil, false
 	}
-
-	// Regexp limit:
-	if strings.HasPrefix(val, "re:") {
-		re, err := regexp.Compile(strings.TrimPrefix(val, "re:"))
-		if err != nil {
-			panic("Error compiling limit regexp: " + err.Error())
-		}
-		fs.limitRegexp = re
-		return
-	}
-
-	// Length limit:
-	if formSpecLimitMatchLength.MatchString(val) {
-
-		// Only useful for strings and int-ies.
-		if fs.Type != "string" && fs.Type != "int" && fs.Type != "int64" {
-			panic("Length limit does not apply to " + fs.Type)
-		}
-		i, err := strconv.ParseInt(val, 10, 32)
-		if err != nil {
-			panic("Error parsing int for length limit: " + err.Error())
-		}
-		fs.limitLength = int(i)
-		return
-	}
-
-	// Range limit (numeric or for strings, length) as integers:
-	if m := formSpecLimitMatchRangeInt.FindStringSubmatch((val)); len(m) == 3 {
-		bits := 32
-		if fs.Type == "int64" || fs.Type == "float" {
-			bits = 64
-		}
-		lower, err := strconv.ParseInt(m[1], 10, bits)
-		if err != nil {
-			panic("Error parsing int for range limit: " + err.Error())
-		}
-		upper, err := strconv.ParseInt(m[2], 10, bits)
-		if err != nil {
-			panic("Error parsing int for range limit: " + err.Error())
-		}
-		if upper < lower {
-			panic("Bad range limit: upper < lower")
-		}
-		fs.limitRangeInt = []int64{lower, upper}
-		return
-	}
-
-	// Range limit as floats (only for float values):
-	if m := formSpecLimitMatchRangeFloat.FindStringSubmatch((val)); len(m) == 3 {
-		bits := 64 // always, for now.
-		if fs.Type != "float" {
-			panic("Float limit requires float type, not " + fs.Type)
-		}
-		lower, err := strconv.ParseFloat(m[1], bits)
-		if err != nil {
-			panic("Error parsing float for range limit: " + err.Error())
-		}
-		upper, err := strconv.ParseFloat(m[2], bits)
-		if err != nil {
-			panic("Error parsing float for range limit: " + err.Error())
-		}
-		if upper < lower {
-			panic("Bad range limit: upper < lower")
+	if len(fs.Group) > 0 {
+		c.Group = make([]*FormSpec, len(fs.Group))
+		for idx, sub := range fs.Group {
+			if sub.Types == nil {
+				sub = sub.withTypes(r)
+			}
+			c.Group[idx] = sub
 		}
-		fs.limitRangeFloat = []float64{lower, upper}
-		return
 	}
+	c.Init()
+	return &c
+}
 
-	// Set of strings limit:
-	if vals := strings.Split(val, ","); len(vals) > 0 {
-		switch fs.Type {
-		case "string":
-			fs.limitListString = vals
-		case "int", "int64":
-			ints := make([]int64, len(vals))
-			for idx, s := range vals {
-				i, err := strconv.ParseInt(s, 10, 64)
-				if err != nil {
-					panic("Bad integer in list: " + err.Error())
-				}
-				ints[idx] = i
-			}
-		default:
-			panic("Value list not compatible with type " + fs.Type)
+func (fs *FormSpec) initLimit() {
 
-		}
+	if fs.Limit == "" {
 		return
 	}
-
-	// etc. as new ones come online.
-
-	panic("Unknown limit: " + val)
+	fs.limits = parseLimits(fs.Limit, fs.itemType())
 }
 
 // NewFormSpec returns a pointer to an initialized FormSpec that is ready for
@@ -354,6 +566,13 @@ func (fs *FormSpec) initLimit() {
 // limit and name, may be omitted. If the spec is not understood, the
 // function panics.
 func NewFormSpec(r bool, k, t string, limitAndName ...string) *FormSpec {
+	return newFormSpec(nil, r, k, t, limitAndName)
+}
+
+// newFormSpec returns an initialized FormSpec as NewFormSpec does, with
+// Types set to reg.
+func newFormSpec(reg *TypeRegistry, r bool, k, t string,
+	limitAndName []string) *FormSpec {
 
 	k = strings.TrimSpace(k)
 	if k == "" {
@@ -377,6 +596,7 @@ func NewFormSpec(r bool, k, t string, limitAndName ...string) *FormSpec {
 		Required: r,
 		Limit:    l,
 		Name:     n,
+		Types:    reg,
 	}
 	f.Init()
 
@@ -400,7 +620,10 @@ func RequiredFormSpec(k, t string, limitAndName ...string) *FormSpec {
 
 // DecodeForm populates the target structure from the values of a submitted
 // form (or any other FormValuer). On failure, returns an error which may be
-// cast as a MultiError for formatting.
+// cast as a MultiError for formatting; its Errors are FieldErrors, with the
+// errors returned by custom Validators wrapped as CodeInvalid unless they
+// are FieldErrors already.  The language of their messages may be set with
+// the Language or AcceptLanguage options; it is English by default.
 //
 //   Q: Why this and not one of the introspection-based libraries?
 //   A: None of those examined yet would work without major changes:
@@ -409,94 +632,231 @@ func RequiredFormSpec(k, t string, limitAndName ...string) *FormSpec {
 //   * vala (with form) would almost work but is stubbornly non-idiomatic
 //
 // Values are whitespace-trimmed before any processing occurs, unless
-// DecodeFormTrimSpace is set to false.
+// DecodeFormTrimSpace is set to false.  This and the other package-level
+// settings, e.g. FormValueTimeLocation and DateFormats, are read when
+// DecodeForm is called; to decode with other settings, or to change them
+// while forms may be decoded concurrently, use a Decoder.
+//
+// Slice types are decoded from all values submitted for the key, provided
+// f is an http.Request or a MultiFormValuer; other FormValuers supply at
+// most one value.
 //
 // Missing form fields are treated as the zero value unless they are required.
 // Unhandled fields are ignored.  Bad spec entries result in a panic.
 //
 // Optional empty fields are converted to the zero value for the type.
 //
+// The target must be a pointer to a struct, or to a map with string keys.
+// Values are assigned to the struct fields whose FormTag key or json name
+// matches the spec Key, or failing that whose json name or field name
+// matches it case-insensitively.  Converted values are assigned directly if
+// possible, or converted to numeric, string and bool types of the same kind,
+// pointers or slices thereof; otherwise they are passed to the field's
+// UnmarshalText method if they are strings, or to its UnmarshalJSON method.
+// Values that can not be assigned result in an error in the MultiError.
+// Nested keys are followed through structs, maps (which are created as
+// needed, with map[string]interface{} used for interface values) and
+// slices (which are extended as needed).
+//
+// Checks involving more than one value may be added with the WithRules
+// option; see Rule.  Forms may be protected against cross-site request
+// forgery with the WithCSRF option; see CSRF.  The WithFormState option
+// keeps the input and errors for re-rendering the form; see FormState.
+//
 // Yes, this is messy, but whatchagonnado?
-func DecodeForm(f FormValuer, specs []*FormSpec, target interface{}) error {
+func DecodeForm(f FormValuer, specs []*FormSpec, target interface{},
+	opts ...DecodeOption) error {
 
-	errors := []error{}
-	values := map[string]interface{}{}
+	return packageDecoder().DecodeForm(f, specs, target, opts...)
+}
+
+// formDecoding holds the state of a single DecodeForm call.
+type formDecoding struct {
+	dec     *Decoder
+	f       FormValuer
+	config  *decodeConfig
+	errors  []error
+	results []*formResult
+	specs   map[string]*FormSpec // by full form key
+}
+
+// formResult is a successfully decoded and validated value.
+type formResult struct {
+	spec  *FormSpec
+	key   string // full form key
+	path  string // full target key, with group rows renumbered
+	value interface{}
+}
+
+// decodeSpecs decodes the values for specs, whose keys are prefixed with
+// keyPrefix in the form and pathPrefix in the target.
+func (d *formDecoding) decodeSpecs(specs []*FormSpec, keyPrefix,
+	pathPrefix string) {
 
 	for _, spec := range specs {
-		input := f.FormValue(spec.Key)
-		if DecodeFormTrimSpace {
+		spec = d.dec.spec(spec)
+		key := keyPrefix + spec.Key
+		path := pathPrefix + spec.Key
+		d.specs[key] = spec
+		if spec.isGroup() {
+			d.decodeGroup(spec, key, path)
+			continue
+		}
+		spec, ok := d.conditionalSpec(spec, key, keyPrefix)
+		if !ok {
+			d.addErrors(spec, key,
+				[]error{spec.FieldError(CodeForbidden, nil)})
+			continue
+		}
+		val, errs := d.decodeSpec(spec, key)
+		if len(errs) > 0 {
+			d.addErrors(spec, key, errs)
+			continue
+		}
+		d.results = append(d.results, &formResult{spec, key, path, val})
+	}
+}
+
+// decodeSpec decodes the value for spec from the form value(s) for key.
+func (d *formDecoding) decodeSpec(spec *FormSpec, key string) (interface{},
+	[]error) {
+
+	switch {
+	case spec.isFile():
+		return d.dec.decodeFiles(spec, formFiles(d.f, key))
+	case spec.isMulti():
+		return d.dec.decodeItems(spec, formValues(d.f, key))
+	}
+	return d.dec.decodeValue(spec, d.f.FormValue(key))
+}
+
+// addErrors adds the errors for spec, setting the keys of FieldErrors to
+// the full form key, and removing their Input if spec is Sensitive.
+func (d *formDecoding) addErrors(spec *FormSpec, key string, errs []error) {
+	for _, err := range errs {
+		if fe, ok := err.(*FieldError); ok {
+			if fe.Key == spec.Key {
+				fe.Key = key
+			}
+			if spec.Sensitive {
+				fe.Input = ""
+			}
+		}
+		d.errors = append(d.errors, err)
+	}
+}
+
+// decodeValue converts and validates the input for a single-value FormSpec.
+func (d *Decoder) decodeValue(fs *FormSpec, input string) (interface{},
+	[]error) {
+
+	if d.TrimSpace {
+		input = strings.TrimSpace(input)
+	}
+	if fs.Required && input == "" {
+		return nil, []error{fs.FieldError(CodeRequired, nil)}
+	}
+	// Convert and validate!
+	val, err := d.Convert(fs, input)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if fs.Validator != nil {
+		if err := fs.Validator(fs, val); err != nil {
+			return nil, []error{fs.asFieldError(err, input)}
+		}
+	}
+	return val, nil
+}
+
+// decodeItems converts and validates the inputs of a slice-type FormSpec,
+// returning a slice of the converted type, or nil if there are no items.
+func (d *Decoder) decodeItems(fs *FormSpec, inputs []string) (interface{},
+	[]error) {
+
+	items := make([]interface{}, 0, len(inputs))
+	errors := []error{}
+	for _, input := range inputs {
+		if d.TrimSpace {
 			input = strings.TrimSpace(input)
 		}
-		if spec.Required && input == "" {
-			errors = append(errors, fmt.Errorf("%s is required", spec.Name))
+		if input == "" {
 			continue
 		}
-		// Convert and validate!
-		val, err := spec.Convert(input)
+		val, err := d.Convert(fs, input)
 		if err != nil {
 			errors = append(errors, err)
 			continue
 		}
-		if spec.Validator != nil {
-			if err := spec.Validator(spec, val); err != nil {
-				errors = append(errors, err)
+		if fs.Validator != nil {
+			if err := fs.Validator(fs, val); err != nil {
+				errors = append(errors, fs.asFieldError(err, input))
 				continue
 			}
 		}
-
-		// All passed for this item.
-		values[spec.Key] = val
-
+		items = append(items, val)
 	}
-
 	if len(errors) > 0 {
-		return &MultiError{errors}
+		return nil, errors
 	}
+	return fs.itemSlice(items)
+}
 
-	// Hmm, there must be a nice generic way to do this round-trip...
-	jsonB, err := json.Marshal(values)
-	if err != nil {
-		panic("Could not marshal values to JSON: " + err.Error())
+// checkCount checks the number of items (or group rows) against the
+// FormSpec's limits.
+func (fs *FormSpec) checkCount(count int) error {
+	if fs.Required && count == 0 {
+		return fs.FieldError(CodeRequired, nil)
 	}
-	if err := json.Unmarshal(jsonB, target); err != nil {
-		panic("Could not unmarshal JSON string: " + err.Error())
+	if count > 0 && count < fs.MinItems {
+		return fs.FieldError(CodeTooFew,
+			map[string]interface{}{"min": fs.MinItems, "count": count})
+	}
+	if fs.MaxItems > 0 && count > fs.MaxItems {
+		return fs.FieldError(CodeTooMany,
+			map[string]interface{}{"max": fs.MaxItems, "count": count})
 	}
-
 	return nil
 }
 
+// itemSlice checks the number of items against the FormSpec's limits, and
+// returns a slice of the actual item type, or nil if there are no items.
+func (fs *FormSpec) itemSlice(items []interface{}) (interface{}, []error) {
+
+	count := len(items)
+	if err := fs.checkCount(count); err != nil {
+		return nil, []error{err}
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	res := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(items[0])),
+		count, count)
+	for idx, item := range items {
+		res.Index(idx).Set(reflect.ValueOf(item))
+	}
+	return res.Interface(), nil
+}
+
+func wrongTypeError(fs *FormSpec, t string, v interface{}) error {
+	return fs.FieldError(CodeWrongType, map[string]interface{}{
+		"type": t,
+		"got":  fmt.Sprintf("%T", v),
+	})
+}
+
 func stringValidator(fs *FormSpec, v interface{}) error {
 
 	s, ok := v.(string)
 	if !ok {
-		return fmt.Errorf("%s (%T) is not a string", fs.Name, v)
+		return wrongTypeError(fs, "string", v)
 	}
 
 	slen := GlyphLength(s)
-	if fs.limitLength > 0 && slen != fs.limitLength {
-		return fmt.Errorf("%s has the wrong length", fs.Name)
-	}
-	if len(fs.limitRangeInt) == 2 {
-		if int64(slen) < fs.limitRangeInt[0] {
-			return fmt.Errorf("%s is too short", fs.Name)
-		}
-		if int64(slen) > fs.limitRangeInt[1] {
-			return fmt.Errorf("%s is too long", fs.Name)
-		}
-	}
-	if fs.limitRegexp != nil && !fs.limitRegexp.MatchString(s) {
-		return fmt.Errorf("%s has the wrong format", fs.Name)
-	}
-	if len(fs.limitListString) > 0 {
-		have := false
-		for _, item := range fs.limitListString {
-			if s == item {
-				have = true
-				break
-			}
-		}
-		if !have {
-			return fmt.Errorf("%s has the wrong value", fs.Name)
+	for _, l := range fs.limits {
+		if err := l.checkString(fs, s, slen); err != nil {
+			return err
 		}
 	}
 
@@ -506,99 +866,57 @@ func stringValidator(fs *FormSpec, v interface{}) error {
 func intValidator(fs *FormSpec, v interface{}) error {
 
 	i, ok := v.(int)
-	if !ok && fs.Type == "int" {
-		return fmt.Errorf("%s (%T) is not an integer", fs.Name, v)
+	if !ok {
+		return wrongTypeError(fs, "int", v)
 	}
 	// Everything else is the same for int and int64.
 	return int64Validator(fs, int64(i))
+}
 
-	// can't len(int) so we cheat...
-	if fs.limitLength > 0 && len(fmt.Sprintf("%d", i)) != fs.limitLength {
-		return fmt.Errorf("%s has the wrong length", fs.Name)
-	}
-	if len(fs.limitRangeInt) == 2 {
-		if int64(i) < fs.limitRangeInt[0] {
-			return fmt.Errorf("%s is too short", fs.Name)
-		}
-		if int64(i) > fs.limitRangeInt[1] {
-			return fmt.Errorf("%s is too long", fs.Name)
-		}
+func int64Validator(fs *FormSpec, v interface{}) error {
+
+	i, ok := v.(int64)
+	if !ok {
+		return wrongTypeError(fs, "int64", v)
 	}
 
 	// TODO: consider applying regexep to stringified numbers.  Why not?
 	//       OTOH, why? (Zip codes? Phone numbers? Order numbers?)
-	if len(fs.limitListInt) > 0 {
-		have := false
-		for _, item := range fs.limitListInt {
-			if int64(i) == item {
-				have = true
-				break
-			}
-		}
-		if !have {
-			return fmt.Errorf("%s has the wrong value", fs.Name)
+	for _, l := range fs.limits {
+		if err := l.checkInt(fs, i); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func int64Validator(fs *FormSpec, v interface{}) error {
-
-	i, ok := v.(int64)
-	if !ok && fs.Type == "int" {
-		return fmt.Errorf("%s (%T) is not a 64-bit integer", fs.Name, v)
-	}
+func floatValidator(fs *FormSpec, v interface{}) error {
 
-	// can't len(int) so we cheat...
-	if fs.limitLength > 0 && len(fmt.Sprintf("%d", i)) != fs.limitLength {
-		return fmt.Errorf("%s has the wrong length", fs.Name)
-	}
-	if len(fs.limitRangeInt) == 2 {
-		if i < fs.limitRangeInt[0] {
-			return fmt.Errorf("%s is too low", fs.Name)
-		}
-		if i > fs.limitRangeInt[1] {
-			return fmt.Errorf("%s is too high", fs.Name)
-		}
+	f, ok := v.(float64)
+	if !ok {
+		return wrongTypeError(fs, "float", v)
 	}
 
-	// TODO: consider applying regexep to stringified numbers.  Why not?
-	//       OTOH, why? (Zip codes? Phone numbers? Order numbers?)
-	if len(fs.limitListInt) > 0 {
-		have := false
-		for _, item := range fs.limitListInt {
-			if i == item {
-				have = true
-				break
-			}
-		}
-		if !have {
-			return fmt.Errorf("%s has the wrong value", fs.Name)
+	for _, l := range fs.limits {
+		if err := l.checkFloat(fs, f); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func floatValidator(fs *FormSpec, v interface{}) error {
+func dateTimeValidator(fs *FormSpec, v interface{}) error {
 
-	f, ok := v.(float64)
+	t, ok := v.(time.Time)
 	if !ok {
-		return fmt.Errorf(
-			"%s is not a 64-bit floating point number, but a %T",
-			fs.Name, v)
+		return wrongTypeError(fs, "datetime", v)
 	}
 
-	// "int" range limit works for floats too, except perhaps at the extremes
-	// (wait for the bug on that one...)
-	// TODO: rethink the whole range limit idea... maybe stricter typing?
-	if len(fs.limitRangeFloat) == 2 {
-		if f < fs.limitRangeFloat[0] {
-			return fmt.Errorf("%s is too low", fs.Name)
-		}
-		if f > fs.limitRangeFloat[1] {
-			return fmt.Errorf("%s is too high", fs.Name)
+	for _, l := range fs.limits {
+		if err := l.checkTime(fs, t); err != nil {
+			return err
 		}
 	}
 
@@ -617,10 +935,14 @@ func boolConverter(raw string) (interface{}, bool) {
 
 func stringConverter(raw string) (interface{}, bool) { return raw, true }
 
-func intConverter(raw string) (interface{}, bool) {
+func intConverter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return int(0), true
 	}
+	raw, ok := d.localNumber(raw)
+	if !ok {
+		return nil, false
+	}
 	i64, err := strconv.ParseInt(raw, 10, 32)
 	if err != nil {
 		return nil, false
@@ -628,10 +950,14 @@ func intConverter(raw string) (interface{}, bool) {
 	return int(i64), true
 }
 
-func int64Converter(raw string) (interface{}, bool) {
+func int64Converter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return int64(0), true
 	}
+	raw, ok := d.localNumber(raw)
+	if !ok {
+		return nil, false
+	}
 	i64, err := strconv.ParseInt(raw, 10, 64)
 	if err != nil {
 		return nil, false
@@ -639,10 +965,14 @@ func int64Converter(raw string) (interface{}, bool) {
 	return i64, true
 }
 
-func floatConverter(raw string) (interface{}, bool) {
+func floatConverter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return float64(0), true
 	}
+	raw, ok := d.localNumber(raw)
+	if !ok {
+		return nil, false
+	}
 	f, err := strconv.ParseFloat(raw, 64)
 	if err != nil {
 		return nil, false
@@ -650,47 +980,55 @@ func floatConverter(raw string) (interface{}, bool) {
 	return f, true
 }
 
-func dateConverter(raw string) (interface{}, bool) {
+func dateConverter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return time.Time{}, true
 	}
-	for _, layout := range DateFormats {
-		d, err := time.ParseInLocation(layout, raw, FormValueTimeLocation)
-		if err == nil {
-			return d, true
-		}
+	if t, ok := d.parseTime(raw, d.DateFormats, d.dateMatcher()); ok {
+		return t, true
 	}
-	return nil, false
+	if t, ok := parseWeekDate(raw, d.location()); ok {
+		return t, true
+	}
+	return d.relativeConverter(raw, true, false)
 }
 
-func dateFlexConverter(raw string) (interface{}, bool) {
+func dateFlexConverter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return time.Time{}, true
 	}
-	for _, layout := range DateFormats {
-		d, err := time.ParseInLocation(layout, raw, FormValueTimeLocation)
-		if err == nil {
-			return d, true
-		}
+	if t, ok := d.parseTime(raw, d.DateFormats, d.dateMatcher()); ok {
+		return t, true
 	}
-	for _, layout := range DateTimeFormats {
-		d, err := time.ParseInLocation(layout, raw, FormValueTimeLocation)
-		if err == nil {
-			return d, true
-		}
+	if t, ok := d.parseTime(raw, d.DateTimeFormats, d.dateTimeMatcher()); ok {
+		return t, true
 	}
-	return nil, false
+	if t, ok := parseWeekDate(raw, d.location()); ok {
+		return t, true
+	}
+	return d.relativeConverter(raw, true, true)
 }
 
-func dateTimeConverter(raw string) (interface{}, bool) {
+func dateTimeConverter(d *Decoder, raw string) (interface{}, bool) {
 	if raw == "" {
 		return time.Time{}, true
 	}
-	for _, layout := range DateTimeFormats {
-		d, err := time.ParseInLocation(layout, raw, FormValueTimeLocation)
-		if err == nil {
-			return d, true
-		}
+	if t, ok := d.parseTime(raw, d.DateTimeFormats, d.dateTimeMatcher()); ok {
+		return t, true
+	}
+	return d.relativeConverter(raw, false, true)
+}
+
+// relativeConverter converts raw as a relative date, if the Decoder accepts
+// them.
+func (d *Decoder) relativeConverter(raw string, date, dateTime bool) (
+	interface{}, bool) {
+
+	if !d.RelativeDates {
+		return nil, false
+	}
+	if t, ok := d.parseRelative(raw, date, dateTime); ok {
+		return t, true
 	}
 	return nil, false
 }
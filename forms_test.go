@@ -11,6 +11,7 @@ import (
 	// Standard:
 	"encoding/json"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
@@ -315,3 +316,165 @@ func Test_DecodeForm_Success(t *testing.T) {
 		"target struct filled to expectation, at least per the JSON")
 
 }
+
+type MultiType struct {
+	Tags  []string    `json:"tags"`
+	Sizes []int       `json:"sizes"`
+	Dates []time.Time `json:"dates"`
+}
+
+func Test_FormSpec_Init_ItemCountsRequireSlice(t *testing.T) {
+
+	spec := &vebben.FormSpec{
+		Key:      "foo",
+		Type:     "string",
+		MaxItems: 3,
+	}
+	willPanic := func() { spec.Init() }
+
+	testig.AssertPanicsWith(t, willPanic,
+		"Item count limits do not apply to string",
+		"got expected panic")
+
+}
+
+func Test_FormSpec_Init_BadItemCountsPanic(t *testing.T) {
+
+	spec := &vebben.FormSpec{
+		Key:      "foo",
+		Type:     "[]string",
+		MinItems: 3,
+		MaxItems: 2,
+	}
+	willPanic := func() { spec.Init() }
+
+	testig.AssertPanicsWith(t, willPanic,
+		"Bad item count limit: MaxItems < MinItems",
+		"got expected panic")
+
+}
+
+func Test_FormSpec_Init_BadSliceTypePanics(t *testing.T) {
+
+	spec := &vebben.FormSpec{Key: "foo", Type: "[][]string"}
+	willPanic := func() { spec.Init() }
+
+	testig.AssertPanicsWith(t, willPanic,
+		"Unsupported FormSpec type: [][]string",
+		"got expected panic")
+
+}
+
+func Test_DecodeForm_MultiValues(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"tags":  []string{" foo", "", "bar "},
+		"sizes": []string{"1", "3"},
+		"dates": []string{"2017.02.25"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("tags", "[]string", "3"),
+		vebben.OptionalFormSpec("sizes", "[]int", "1-4"),
+		vebben.OptionalFormSpec("dates", "[]date"),
+	}
+
+	target := &MultiType{}
+	err := vebben.DecodeForm(f, specs, target)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d, _ := time.ParseInLocation("2006.01.02", "2017.02.25",
+		vebben.FormValueTimeLocation)
+	assert.Equal([]string{"foo", "bar"}, target.Tags, "tags set, trimmed")
+	assert.Equal([]int{1, 3}, target.Sizes, "sizes set")
+	if assert.Equal(1, len(target.Dates), "one date set") {
+		assert.True(d.Equal(target.Dates[0]), "date is correct")
+	}
+
+}
+
+func Test_DecodeForm_MultiValues_Request(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r, _ := http.NewRequest("GET", "/?tags=a&tags=b&sizes=2", nil)
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("tags", "[]string"),
+		vebben.OptionalFormSpec("sizes", "[]int"),
+	}
+
+	target := &MultiType{}
+	err := vebben.DecodeForm(r, specs, target)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Equal([]string{"a", "b"}, target.Tags, "tags set")
+	assert.Equal([]int{2}, target.Sizes, "sizes set")
+	assert.Nil(target.Dates, "dates not set")
+
+}
+
+func Test_DecodeForm_MultiValues_SingleValuer(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := &TestFormValuer{map[string]string{"tags": "only"}}
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("tags", "[]string"),
+		vebben.OptionalFormSpec("sizes", "[]int"),
+	}
+
+	target := &MultiType{}
+	err := vebben.DecodeForm(f, specs, target)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Equal([]string{"only"}, target.Tags, "tags set")
+	assert.Nil(target.Sizes, "sizes not set")
+
+}
+
+func Test_DecodeForm_MultiValues_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	few := &vebben.FormSpec{
+		Key:      "few",
+		Name:     "few",
+		Type:     "[]string",
+		MinItems: 2,
+	}
+	few.Init()
+	many := &vebben.FormSpec{
+		Key:      "many",
+		Name:     "many",
+		Type:     "[]int",
+		MaxItems: 2,
+	}
+	many.Init()
+
+	f := vebben.URLValues{
+		"sizes": []string{"1", "x", "9"},
+		"few":   []string{"a"},
+		"many":  []string{"1", "2", "3"},
+	}
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("tags", "[]string"),
+		vebben.OptionalFormSpec("sizes", "[]int", "1-4"),
+		few,
+		many,
+	}
+
+	err := vebben.DecodeForm(f, specs, &MultiType{})
+	if assert.Error(err, "error returned") {
+		assert.Equal("tags is required\n"+
+			"sizes could not be converted to int\n"+
+			"sizes is too high\n"+
+			"few has too few items\n"+
+			"many has too many items",
+			err.Error(), "stringified error as expected")
+	}
+
+}
@@ -0,0 +1,405 @@
+// formschema.go -- JSON Schema documents for FormSpecs.
+// -------------
+
+package vebben
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema draft used by JSONSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document describing the
+// values of specs, suitable for json.Marshal, so that JSON APIs accepting
+// the same data as a form can share its specs.  The document describes an
+// object, with a property for each spec; nested keys result in nested
+// objects, and indexed keys in arrays.  Types are mapped as follows:
+//
+//   string         // string, with minLength, maxLength and pattern
+//   int, int64     // integer, with minimum, maximum and multipleOf
+//   float          // number, with minimum or exclusiveMinimum, maximum
+//                  // or exclusiveMaximum, and multipleOf
+//   decimal        // number, as for float, with multipleOf for the scale
+//   money          // as decimal if it has a single currency, or string
+//   bool           // boolean
+//   date           // string with format "date"
+//   datetime       // string with format "date-time"
+//   dateflex       // string with format "date" or "date-time"
+//   email, url     // string with format "email" or "uri", or "idn-email"
+//                  // or "iri" unless DomainsToASCII
+//   hostname       // string with format "hostname", or "idn-hostname"
+//   ip             // string with format "ipv4" or "ipv6"
+//   ipv4, ipv6     // string with format "ipv4" or "ipv6"
+//   cidr           // string
+//   file           // base64-encoded string, with contentMediaType if
+//                  // there is a single Accept type
+//   group          // object with the Group properties
+//   list limits    // enum
+//
+// Slice types are arrays of their item types, with minItems and maxItems.
+// Required keys are listed in the required array of their object, as are
+// the parents of nested keys; RequiredIf and ForbiddenIf Conditions are
+// expressed with if/then, for keys in the same object.  The Name of a spec
+// is its title, and Sensitive specs are writeOnly.
+//
+// Range steps are given as multipleOf if zero is on a step, and otherwise
+// omitted.  Regexp limits are given as patterns if they are compatible with
+// the ECMA-262 dialect used by JSON Schema, and otherwise omitted.  Custom
+// types have an empty schema, allowing any value, unless a schema function
+// is set with SetFormSpecTypeSchema.
+func JSONSchema(specs []*FormSpec) map[string]interface{} {
+	doc := newObjectSchema()
+	for _, spec := range specs {
+		addSchemaProperty(doc, spec)
+	}
+	finishObjectSchema(doc)
+	doc["$schema"] = JSONSchemaDialect
+	return doc
+}
+
+// SetFormSpecTypeSchema sets the function returning the JSON Schema for a
+// single value of FormSpec type t, which must exist; e.g. for a custom
+// "sku" type:
+//
+//   vebben.SetFormSpecTypeSchema("sku",
+//       func(*vebben.FormSpec) map[string]interface{} {
+//           return map[string]interface{}{
+//               "type":    "string",
+//               "pattern": "^[A-Z]{3}-\\d{4}$",
+//           }
+//       })
+//
+// The function is called for the item type of slices, and its result is
+// copied, so it may return the same map each time.  The type is that of
+// DefaultTypes; see TypeRegistry.SetSchema for other registries.
+func SetFormSpecTypeSchema(t string, sf func(*FormSpec) map[string]interface{}) {
+	DefaultTypes.SetSchema(t, sf)
+}
+
+func newObjectSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+// finishObjectSchema removes empty lists from an object schema.
+func finishObjectSchema(obj map[string]interface{}) {
+	if req, ok := obj["required"].([]string); ok && len(req) == 0 {
+		delete(obj, "required")
+	}
+}
+
+// addSchemaProperty adds the schema for spec to the object schema obj,
+// creating the objects and arrays for nested keys as needed.
+func addSchemaProperty(obj map[string]interface{}, spec *FormSpec) {
+
+	segs, err := parseFormKey(spec.Key)
+	if err != nil {
+		return // Init would have panicked.
+	}
+	required := spec.Required
+	node := obj
+	for idx, seg := range segs {
+		if seg.index >= 0 {
+			continue // handled with the preceding name
+		}
+		last := idx == len(segs)-1
+		array := !last && segs[idx+1].index >= 0
+		if required {
+			addSchemaRequired(node, seg.name)
+		}
+		props := node["properties"].(map[string]interface{})
+		if last {
+			props[seg.name] = spec.schema()
+			break
+		}
+		child, _ := props[seg.name].(map[string]interface{})
+		if array {
+			if child == nil || child["type"] != "array" {
+				child = map[string]interface{}{
+					"type":  "array",
+					"items": newObjectSchema(),
+				}
+				props[seg.name] = child
+			}
+			child = child["items"].(map[string]interface{})
+		} else if child == nil || child["type"] != "object" {
+			child = newObjectSchema()
+			props[seg.name] = child
+		}
+		node = child
+	}
+	if len(segs) == 1 {
+		addSchemaConditions(obj, spec)
+	}
+}
+
+// addSchemaRequired adds name to the required list of obj.
+func addSchemaRequired(obj map[string]interface{}, name string) {
+	req, _ := obj["required"].([]string)
+	for _, r := range req {
+		if r == name {
+			return
+		}
+	}
+	obj["required"] = append(req, name)
+}
+
+// addSchemaConditions adds if/then schemas for the conditions of spec,
+// provided they refer to keys in the same object.
+func addSchemaConditions(obj map[string]interface{}, spec *FormSpec) {
+
+	add := func(conds []Condition, then map[string]interface{}) {
+		cond := conditionsSchema(conds)
+		if cond == nil {
+			return
+		}
+		all, _ := obj["allOf"].([]interface{})
+		obj["allOf"] = append(all, map[string]interface{}{
+			"if":   cond,
+			"then": then,
+		})
+	}
+	add(spec.RequiredIf, map[string]interface{}{
+		"required": []string{spec.Key},
+	})
+	add(spec.ForbiddenIf, map[string]interface{}{
+		"not": map[string]interface{}{"required": []string{spec.Key}},
+	})
+}
+
+// conditionsSchema returns the schema matching all conds, or nil if there
+// are none or they can not be expressed.
+func conditionsSchema(conds []Condition) map[string]interface{} {
+
+	if len(conds) == 0 {
+		return nil
+	}
+	all := []interface{}{}
+	for _, c := range conds {
+		if strings.ContainsAny(c.Key, ".[]") {
+			return nil
+		}
+		s := map[string]interface{}{"required": []string{c.Key}}
+		if len(c.Values) > 0 {
+			s["properties"] = map[string]interface{}{
+				c.Key: map[string]interface{}{"enum": c.Values},
+			}
+		}
+		if c.Not {
+			s = map[string]interface{}{"not": s}
+		}
+		all = append(all, s)
+	}
+	if len(all) == 1 {
+		return all[0].(map[string]interface{})
+	}
+	return map[string]interface{}{"allOf": all}
+}
+
+// schema returns the JSON Schema for values of the FormSpec.
+func (fs *FormSpec) schema() map[string]interface{} {
+
+	var s map[string]interface{}
+	switch {
+	case fs.isGroup():
+		s = newObjectSchema()
+		for _, sub := range fs.Group {
+			addSchemaProperty(s, sub)
+		}
+		finishObjectSchema(s)
+	default:
+		s = fs.itemSchema()
+	}
+
+	if fs.isMulti() {
+		s = map[string]interface{}{"type": "array", "items": s}
+		minItems := fs.MinItems
+		if fs.Required && minItems < 1 {
+			minItems = 1
+		}
+		if minItems > 0 {
+			s["minItems"] = minItems
+		}
+		if fs.MaxItems > 0 {
+			s["maxItems"] = fs.MaxItems
+		}
+	}
+	if fs.Name != "" && fs.Name != fs.Key {
+		s["title"] = fs.Name
+	}
+	if fs.Sensitive {
+		s["writeOnly"] = true
+	}
+	return s
+}
+
+// itemSchema returns the JSON Schema for a single value of the item type.
+func (fs *FormSpec) itemSchema() map[string]interface{} {
+
+	t := fs.itemType()
+	if ft := fs.specType(); ft != nil && ft.schema != nil {
+		s := map[string]interface{}{}
+		for k, v := range ft.schema(fs) {
+			s[k] = v
+		}
+		return s
+	}
+
+	s := map[string]interface{}{}
+	switch t {
+	case "string":
+		s["type"] = "string"
+		for _, l := range fs.limits {
+			l.addSchema(s)
+		}
+	case "int", "int64", "float":
+		s["type"] = "integer"
+		if t == "float" {
+			s["type"] = "number"
+		}
+		for _, l := range fs.limits {
+			if l.length == 0 {
+				l.addSchema(s)
+			}
+		}
+	case "decimal", "money":
+		f := fs.decimalFormat()
+		if t == "money" && f.fixedCurrency() == "" {
+			s["type"] = "string"
+			break
+		}
+		s["type"] = "number"
+		for _, l := range fs.limits {
+			l.addSchema(s)
+		}
+		scale, ok := f.scaleFor(f.fixedCurrency())
+		if _, have := s["multipleOf"]; ok && !have {
+			s["multipleOf"] = json.Number(Decimal{Units: 1, Scale: scale}.String())
+		}
+	case "bool":
+		s["type"] = "boolean"
+	case "date":
+		s["type"] = "string"
+		s["format"] = "date"
+	case "datetime":
+		s["type"] = "string"
+		s["format"] = "date-time"
+	case "dateflex":
+		s["type"] = "string"
+		s["anyOf"] = []interface{}{
+			map[string]interface{}{"format": "date"},
+			map[string]interface{}{"format": "date-time"},
+		}
+	case "email", "url", "hostname", "ip", "ipv4", "ipv6", "cidr":
+		s["type"] = "string"
+		switch format := netSchemaFormats[t]; {
+		case t == "ip":
+			s["anyOf"] = []interface{}{
+				map[string]interface{}{"format": "ipv4"},
+				map[string]interface{}{"format": "ipv6"},
+			}
+		case netSchemaUnicode[format] != "" && !DomainsToASCII:
+			s["format"] = netSchemaUnicode[format]
+		case format != "":
+			s["format"] = format
+		}
+		for _, l := range fs.limits {
+			l.addSchema(s)
+		}
+	case "file":
+		s["type"] = "string"
+		s["contentEncoding"] = "base64"
+		if len(fs.Accept) == 1 && !strings.HasSuffix(fs.Accept[0], "/*") {
+			s["contentMediaType"] = fs.Accept[0]
+		}
+	}
+	return s
+}
+
+var netSchemaFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+}
+
+// netSchemaUnicode maps formats to those allowing Unicode domain names.
+var netSchemaUnicode = map[string]string{
+	"email":    "idn-email",
+	"uri":      "iri",
+	"hostname": "idn-hostname",
+}
+
+// addSchema adds the keywords for the limit to s.  Lengths and ranges
+// apply to string lengths for strings, and lists of values not allowed are
+// given with not.
+func (l *formLimit) addSchema(s map[string]interface{}) {
+
+	switch {
+	case l.length > 0:
+		s["minLength"] = l.length
+		s["maxLength"] = l.length
+	case l.rng != nil && s["type"] == "string":
+		if l.rng.hasMin {
+			s["minLength"] = l.rng.minInt
+		}
+		if l.rng.hasMax {
+			s["maxLength"] = l.rng.maxInt
+		}
+	case l.rng != nil:
+		l.rng.addSchema(s)
+	case l.re != nil:
+		if re := l.re.String(); !jsIncompatible.MatchString(re) {
+			s["pattern"] = re
+		}
+	case l.schemes != nil:
+		// Not expressible, except as a pattern.
+	case l.hasScale, l.rounding != "", l.currencies != nil:
+		// Applied by itemSchema.
+	default:
+		var list interface{} = l.listString
+		if len(l.listInt) > 0 {
+			list = l.listInt
+		}
+		if l.not {
+			s["not"] = map[string]interface{}{"enum": list}
+		} else {
+			s["enum"] = list
+		}
+	}
+}
+
+// addSchema adds the keywords for the numeric range to s.
+func (r *numRange) addSchema(s map[string]interface{}) {
+
+	var min, max, step interface{} = r.minInt, r.maxInt, r.stepInt
+	if r.float {
+		min, max, step = r.minFloat, r.maxFloat, r.stepFloat
+	}
+	if r.dec {
+		min, max = json.Number(r.minDec.String()), json.Number(r.maxDec.String())
+		step = json.Number(r.stepDec.String())
+	}
+	switch {
+	case r.hasMin && r.minExcl:
+		s["exclusiveMinimum"] = min
+	case r.hasMin:
+		s["minimum"] = min
+	}
+	switch {
+	case r.hasMax && r.maxExcl:
+		s["exclusiveMaximum"] = max
+	case r.hasMax:
+		s["maximum"] = max
+	}
+	if r.float && r.stepFloat != 0 && r.onStepFloat(0) ||
+		r.dec && r.stepDec.Units != 0 && r.onStepDecimal(Decimal{}) ||
+		!r.float && !r.dec && r.stepInt != 0 && r.onStepInt(0) {
+		s["multipleOf"] = step
+	}
+}
@@ -0,0 +1,226 @@
+// formschema_test.go
+// ------------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"encoding/json"
+	"strings"
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func schemaJSON(t *testing.T, specs []*vebben.FormSpec) string {
+	b, err := json.Marshal(vebben.JSONSchema(specs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func Test_JSONSchema_Types(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("name", "string", "2-20", "Your Name"),
+		vebben.OptionalFormSpec("code", "string", "4"),
+		vebben.OptionalFormSpec("sku", "string", `re:^\w\d+$`),
+		vebben.OptionalFormSpec("goonly", "string", `re:(?i)x`),
+		vebben.OptionalFormSpec("color", "string", "red,green"),
+		vebben.RequiredFormSpec("size", "int", "1-4"),
+		vebben.OptionalFormSpec("odd", "int64", "1,3,5"),
+		vebben.OptionalFormSpec("strength", "float", "0.5-1.5"),
+		vebben.OptionalFormSpec("weight", "float", "1-9"),
+		vebben.OptionalFormSpec("ok", "bool"),
+		vebben.OptionalFormSpec("day", "date"),
+		vebben.OptionalFormSpec("at", "datetime"),
+		vebben.OptionalFormSpec("flex", "dateflex"),
+		vebben.RequiredFormSpec("tags", "[]string"),
+		{Key: "pic", Type: "file", Accept: []string{"image/png"}},
+		{Key: "docs", Type: "[]file", Accept: []string{"image/*"},
+			MinItems: 2, MaxItems: 3},
+	}
+	for _, spec := range specs[14:] {
+		spec.Init()
+	}
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 20,
+				"title": "Your Name"},
+			"code": {"type": "string", "minLength": 4, "maxLength": 4},
+			"sku": {"type": "string", "pattern": "^\\w\\d+$"},
+			"goonly": {"type": "string"},
+			"color": {"type": "string", "enum": ["red", "green"]},
+			"size": {"type": "integer", "minimum": 1, "maximum": 4},
+			"odd": {"type": "integer", "enum": [1, 3, 5]},
+			"strength": {"type": "number", "minimum": 0.5, "maximum": 1.5},
+			"weight": {"type": "number", "minimum": 1, "maximum": 9},
+			"ok": {"type": "boolean"},
+			"day": {"type": "string", "format": "date"},
+			"at": {"type": "string", "format": "date-time"},
+			"flex": {"type": "string",
+				"anyOf": [{"format": "date"}, {"format": "date-time"}]},
+			"tags": {"type": "array", "items": {"type": "string"},
+				"minItems": 1},
+			"pic": {"type": "string", "contentEncoding": "base64",
+				"contentMediaType": "image/png"},
+			"docs": {"type": "array", "minItems": 2, "maxItems": 3,
+				"items": {"type": "string", "contentEncoding": "base64"}}
+		},
+		"required": ["name", "size", "tags"]
+	}`, schemaJSON(t, specs))
+
+}
+
+func Test_JSONSchema_Nested(t *testing.T) {
+
+	specs := []*vebben.FormSpec{
+		vebben.RequiredFormSpec("address.city", "string"),
+		vebben.OptionalFormSpec("address.zip", "int"),
+		vebben.OptionalFormSpec("meta[color]", "string"),
+		vebben.RequiredFormSpec("lines[0].text", "string"),
+		vebben.GroupFormSpec("billing", []*vebben.FormSpec{
+			vebben.RequiredFormSpec("name", "string"),
+		}),
+		vebben.RepeatedFormSpec("items", []*vebben.FormSpec{
+			vebben.RequiredFormSpec("sku", "string"),
+			vebben.OptionalFormSpec("qty", "int", "1-10"),
+		}),
+	}
+	specs[5].MaxItems = 5
+
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"address": {"type": "object", "required": ["city"],
+				"properties": {
+					"city": {"type": "string"},
+					"zip": {"type": "integer"}
+				}},
+			"meta": {"type": "object",
+				"properties": {"color": {"type": "string"}}},
+			"lines": {"type": "array", "items": {"type": "object",
+				"properties": {"text": {"type": "string"}},
+				"required": ["text"]}},
+			"billing": {"type": "object",
+				"properties": {"name": {"type": "string"}},
+				"required": ["name"]},
+			"items": {"type": "array", "maxItems": 5,
+				"items": {"type": "object",
+				"properties": {
+					"sku": {"type": "string"},
+					"qty": {"type": "integer", "minimum": 1, "maximum": 10}
+				},
+				"required": ["sku"]}}
+		},
+		"required": ["address", "lines"]
+	}`, schemaJSON(t, specs))
+
+}
+
+func Test_JSONSchema_Conditions(t *testing.T) {
+
+	specs := vebben.MustFormSpecsFor(Booking{})
+
+	js := schemaJSON(t, specs)
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(js), &doc); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := json.Marshal(doc["allOf"])
+	assert.JSONEq(t, `[
+		{"if": {"required": ["invoice_type"],
+			"properties": {"invoice_type": {"enum": ["company"]}}},
+		 "then": {"required": ["company_name"]}},
+		{"if": {"required": ["invoice_type"],
+			"properties": {"invoice_type": {"enum": ["person"]}}},
+		 "then": {"not": {"required": ["company_name"]}}},
+		{"if": {"required": ["status"],
+			"properties": {"status": {"enum": ["cancelled", "void"]}}},
+		 "then": {"required": ["reason"]}},
+		{"if": {"not": {"required": ["email"]}},
+		 "then": {"required": ["phone"]}},
+		{"if": {"required": ["status"],
+			"properties": {"status": {"enum": ["deluxe"]}}},
+		 "then": {"required": ["extras"]}},
+		{"if": {"not": {"required": ["status"]}},
+		 "then": {"not": {"required": ["extras"]}}}
+	]`, string(all))
+
+	spec := vebben.OptionalFormSpec("x", "string")
+	spec.RequiredIf = []vebben.Condition{
+		vebben.When("a", "1"), vebben.When("b.c", "2"),
+	}
+	js = schemaJSON(t, []*vebben.FormSpec{spec})
+	assert.False(t, strings.Contains(js, "allOf"), "nested keys skipped")
+
+	spec.RequiredIf = []vebben.Condition{vebben.When("a"), vebben.When("b")}
+	js = schemaJSON(t, []*vebben.FormSpec{spec})
+	assert.Contains(t, js, `"if":{"allOf":[{"required":["a"]},`+
+		`{"required":["b"]}]}`)
+
+}
+
+func Test_JSONSchema_CustomType(t *testing.T) {
+
+	vebben.AddFormSpecType("schemasku",
+		func(s string) (interface{}, bool) { return s, true }, nil)
+	specs := []*vebben.FormSpec{
+		vebben.OptionalFormSpec("plain", "schemasku", "", "Plain"),
+	}
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"plain": {"title": "Plain"}}
+	}`, schemaJSON(t, specs))
+
+	fragment := map[string]interface{}{
+		"type":    "string",
+		"pattern": "^[A-Z]{3}-\\d{4}$",
+	}
+	vebben.SetFormSpecTypeSchema("schemasku",
+		func(*vebben.FormSpec) map[string]interface{} { return fragment })
+	specs = append(specs, vebben.OptionalFormSpec("skus", "[]schemasku"))
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"plain": {"title": "Plain", "type": "string",
+				"pattern": "^[A-Z]{3}-\\d{4}$"},
+			"skus": {"type": "array", "items": {"type": "string",
+				"pattern": "^[A-Z]{3}-\\d{4}$"}}
+		}
+	}`, schemaJSON(t, specs))
+	assert.Equal(t, 2, len(fragment), "fragment not modified")
+
+	testig.AssertPanicsWith(t, func() {
+		vebben.SetFormSpecTypeSchema("nonesuch", nil)
+	}, "Unsupported FormSpec type: nonesuch", "unknown type")
+
+}
+
+func Test_JSONSchema_Sensitive(t *testing.T) {
+
+	spec := vebben.RequiredFormSpec("password", "string", "8-64", "Password")
+	spec.Sensitive = true
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"password": {"type": "string", "minLength": 8, "maxLength": 64,
+				"title": "Password", "writeOnly": true}
+		},
+		"required": ["password"]
+	}`, schemaJSON(t, []*vebben.FormSpec{spec}))
+
+}
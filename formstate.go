@@ -0,0 +1,127 @@
+// formstate.go -- form state for re-rendering after validation failure.
+// ------------
+
+package vebben
+
+// FormState holds the state of a form after DecodeForm, so that a form that
+// failed validation can be rendered again with the input the user gave and
+// the errors found.  It is filled by DecodeForm when given the WithFormState
+// option, whether or not there are errors:
+//
+//   state := &vebben.FormState{}
+//   err := vebben.DecodeForm(r, specs, &order, vebben.WithFormState(state))
+//   if err != nil {
+//       tmpl.Execute(w, map[string]interface{}{"State": state})
+//       return
+//   }
+//
+// The template may then use the functions value, haserror, errorfor,
+// checked and selected from NewFuncMap, which are the methods of the same
+// names, e.g.:
+//
+//   <input name="email" value="{{ value .State "email" }}">
+//   {{ if haserror .State "email" }}{{ errorfor .State "email" }}{{ end }}
+//   <option value="red" {{ if selected .State "color" "red" }}selected{{ end }}>
+//
+// All keys are full form keys, e.g. "items[0].qty" for a group.  Input and
+// values of Sensitive FormSpecs are never kept.  The methods may be called
+// on a nil FormState, e.g. for a fresh form, and behave as for empty input.
+type FormState struct {
+	Input  map[string][]string    // raw input for each key with any
+	Values map[string]interface{} // converted values for valid keys
+	Errors []*FieldError          // errors, as returned by DecodeForm
+}
+
+// WithFormState makes DecodeForm fill state with the input, values and
+// errors of the form.  Any previous contents of state are replaced.
+func WithFormState(state *FormState) DecodeOption {
+	return func(c *decodeConfig) {
+		c.state = state
+	}
+}
+
+// fillState fills the FormState if the WithFormState option was given.
+func (d *formDecoding) fillState() {
+
+	s := d.config.state
+	if s == nil {
+		return
+	}
+	s.Input = map[string][]string{}
+	s.Values = map[string]interface{}{}
+	s.Errors = nil
+	for key, spec := range d.specs {
+		if spec.isGroup() || spec.isFile() || spec.Sensitive {
+			continue
+		}
+		if values := formValues(d.f, key); len(values) > 0 {
+			s.Input[key] = values
+		}
+	}
+	for _, res := range d.results {
+		if !res.spec.Sensitive {
+			s.Values[res.key] = res.value
+		}
+	}
+	for _, err := range d.errors {
+		if fe, ok := err.(*FieldError); ok {
+			s.Errors = append(s.Errors, fe)
+		}
+	}
+}
+
+// Value returns the first input for key, or an empty string if there is
+// none.
+func (s *FormState) Value(key string) string {
+	if s == nil || len(s.Input[key]) == 0 {
+		return ""
+	}
+	return s.Input[key][0]
+}
+
+// HasError returns true if there is a FieldError for key.
+func (s *FormState) HasError(key string) bool {
+	return s.firstError(key) != nil
+}
+
+// ErrorFor returns the message of the first FieldError for key, in the
+// language set by the DecodeForm options; or an empty string if there is
+// none.
+func (s *FormState) ErrorFor(key string) string {
+	if fe := s.firstError(key); fe != nil {
+		return fe.Error()
+	}
+	return ""
+}
+
+func (s *FormState) firstError(key string) *FieldError {
+	if s == nil {
+		return nil
+	}
+	for _, fe := range s.Errors {
+		if fe.Key == key {
+			return fe
+		}
+	}
+	return nil
+}
+
+// Checked returns true if value is one of the inputs for key, for marking
+// checkboxes and radio buttons as checked.
+func (s *FormState) Checked(key, value string) bool {
+	if s == nil {
+		return false
+	}
+	for _, v := range s.Input[key] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Selected returns true if value is one of the inputs for key, for marking
+// options as selected; it is the same as Checked.
+func (s *FormState) Selected(key, value string) bool {
+	return s.Checked(key, value)
+}
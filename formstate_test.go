@@ -0,0 +1,175 @@
+// formstate_test.go
+// -----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"bytes"
+	"html/template"
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+type SignupType struct {
+	Email    string   `json:"email" vebben:",,required,limit=re:@,name=Email"`
+	Password string   `json:"password" vebben:",,required,sensitive,limit=8-64,name=Password"`
+	Color    string   `json:"color" vebben:",,limit=red,green,blue"`
+	Tags     []string `json:"tags" vebben:""`
+	Terms    bool     `json:"terms" vebben:""`
+}
+
+func Test_DecodeForm_WithFormState(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := vebben.MustFormSpecsFor(SignupType{})
+	form := vebben.URLValues{
+		"email":    {" joe.example.com "},
+		"password": {"secret"},
+		"color":    {"green"},
+		"tags":     {"a", "b"},
+		"terms":    {"true"},
+		"other":    {"ignored"},
+	}
+	state := &vebben.FormState{Values: map[string]interface{}{"old": 1}}
+	target := &SignupType{}
+	err := vebben.DecodeForm(form, specs, target,
+		vebben.WithFormState(state), vebben.Language(language.Hungarian))
+	if !assert.Error(err) {
+		return
+	}
+
+	assert.Equal(map[string][]string{
+		"email": {" joe.example.com "},
+		"color": {"green"},
+		"tags":  {"a", "b"},
+		"terms": {"true"},
+	}, state.Input, "Input")
+	assert.Equal(map[string]interface{}{
+		"color": "green",
+		"tags":  []string{"a", "b"},
+		"terms": true,
+	}, state.Values, "Values")
+	if assert.Len(state.Errors, 2) {
+		assert.Equal("", state.Errors[1].Input, "no sensitive Input")
+	}
+
+	assert.Equal(" joe.example.com ", state.Value("email"))
+	assert.Equal("", state.Value("password"), "sensitive")
+	assert.Equal("a", state.Value("tags"))
+	assert.Equal("", state.Value("nope"))
+	assert.True(state.HasError("email"))
+	assert.True(state.HasError("password"))
+	assert.False(state.HasError("color"))
+	assert.Equal("Email formátuma nem megfelelő", state.ErrorFor("email"))
+	assert.Equal("", state.ErrorFor("color"))
+	assert.True(state.Checked("terms", "true"))
+	assert.False(state.Checked("terms", "false"))
+	assert.True(state.Selected("color", "green"))
+	assert.True(state.Selected("tags", "b"))
+	assert.False(state.Selected("color", "red"))
+
+	// The MultiError has the same FieldErrors:
+	me := err.(*vebben.MultiError)
+	assert.Equal(me.FieldErrors(), state.Errors)
+
+	// Success also fills the state:
+	form["email"] = []string{"joe@example.com"}
+	form["password"] = []string{"much secret"}
+	err = vebben.DecodeForm(form, specs, target, vebben.WithFormState(state))
+	assert.Nil(err)
+	assert.Empty(state.Errors)
+	assert.Equal("joe@example.com", state.Values["email"])
+	assert.NotContains(state.Values, "password")
+	assert.Equal("much secret", target.Password)
+}
+
+func Test_DecodeForm_WithFormState_Group(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := []*vebben.FormSpec{
+		{Key: "items", Type: "[]group", Group: []*vebben.FormSpec{
+			vebben.RequiredFormSpec("qty", "int", "1-10", "Quantity"),
+		}},
+	}
+	specs[0].Init()
+	form := vebben.URLValues{
+		"items[0].qty": {"2"},
+		"items[3].qty": {"20"},
+	}
+	state := &vebben.FormState{}
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(form, specs, &target, vebben.WithFormState(state))
+	assert.Error(err)
+	assert.Equal("20", state.Value("items[3].qty"))
+	assert.Equal(2, state.Values["items[0].qty"])
+	assert.True(state.HasError("items[3].qty"))
+	assert.False(state.HasError("items[0].qty"))
+}
+
+func Test_FormState_Nil(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var state *vebben.FormState
+	assert.Equal("", state.Value("x"))
+	assert.False(state.HasError("x"))
+	assert.Equal("", state.ErrorFor("x"))
+	assert.False(state.Checked("x", "y"))
+	assert.False(state.Selected("x", "y"))
+}
+
+func Test_FormState_Template(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs := vebben.MustFormSpecsFor(SignupType{})
+	tmpl := template.Must(template.New("").Funcs(vebben.NewFuncMap()).Parse(
+		`<input name="email" value="{{ value .State "email" }}">` +
+			`{{ if haserror .State "email" }}<p>{{ errorfor .State "email" }}</p>{{ end }}` +
+			`<option value="red"{{ if selected .State "color" "red" }} selected{{ end }}>` +
+			`<option value="blue"{{ if selected .State "color" "blue" }} selected{{ end }}>` +
+			`<input type="checkbox" name="terms"{{ if checked .State "terms" "true" }} checked{{ end }}>` +
+			`{{ forminput .Specs.password .State .State }}`))
+
+	render := func(data map[string]interface{}) string {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	specMap := map[string]*vebben.FormSpec{"password": specs[1]}
+
+	// Fresh form, no state:
+	assert.Equal(`<input name="email" value="">`+
+		`<option value="red">`+
+		`<option value="blue">`+
+		`<input type="checkbox" name="terms">`+
+		`<input name="password" id="password" required type="password" minlength="8" maxlength="64" value="">`,
+		render(map[string]interface{}{"Specs": specMap}))
+
+	form := vebben.URLValues{
+		"email":    {`joe"x`},
+		"password": {"short"},
+		"color":    {"blue"},
+		"terms":    {"true"},
+	}
+	state := &vebben.FormState{}
+	vebben.DecodeForm(form, specs, &SignupType{}, vebben.WithFormState(state))
+	assert.Equal(`<input name="email" value="joe&#34;x">`+
+		`<p>Email has the wrong format</p>`+
+		`<option value="red">`+
+		`<option value="blue" selected>`+
+		`<input type="checkbox" name="terms" checked>`+
+		`<input name="password" id="password" required class="error" aria-invalid="true" type="password" minlength="8" maxlength="64" value="">`,
+		render(map[string]interface{}{"Specs": specMap, "State": state}))
+}
@@ -0,0 +1,340 @@
+// formtags.go -- FormSpecs derived from struct tags.
+// -----------
+
+package vebben
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FormTag is the struct tag key read by FormSpecsFor.
+const FormTag = "vebben"
+
+// FormTagError describes a problem with the FormTag of a struct field.
+type FormTagError struct {
+	Struct reflect.Type
+	Field  string
+	Tag    string
+	Reason string
+}
+
+// Error implements the error interface for FormTagError.
+func (e *FormTagError) Error() string {
+	return fmt.Sprintf("bad %s tag on %s.%s: %s (tag: %q)",
+		FormTag, e.Struct.Name(), e.Field, e.Reason, e.Tag)
+}
+
+var formTagCache sync.Map // reflect.Type -> []*FormSpec
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+var mailAddressType = reflect.TypeOf((*mail.Address)(nil))
+var urlType = reflect.TypeOf((*url.URL)(nil))
+var netipAddrType = reflect.TypeOf(netip.Addr{})
+var netipPrefixType = reflect.TypeOf(netip.Prefix{})
+var decimalType = reflect.TypeOf(Decimal{})
+var moneyType = reflect.TypeOf(Money{})
+
+// FormSpecsFor returns FormSpecs for the struct (or pointer to struct) v,
+// as described by the "vebben" tags of its fields, in field order.  The tag
+// syntax is:
+//
+//   `vebben:"key,type,option,option..."`
+//
+// The key defaults to the name in the field's json tag, if any, or else the
+// field name; if the type is omitted it is derived from the field's type,
+// which works for the string, int, int64, float and bool types and slices
+// thereof, and for *multipart.FileHeader as "file", *mail.Address as
+// "email", *url.URL as "url", netip.Addr as "ip", netip.Prefix as "cidr",
+// Decimal as "decimal" and Money as "money".  Options are:
+//
+//   required       // set Required
+//   sensitive      // set Sensitive
+//   limit=X        // set Limit to X
+//   name=X         // set Name to X (default: the key)
+//   min=N          // set MinItems to N (slice types only)
+//   max=N          // set MaxItems to N (slice types only)
+//   maxsize=N      // set MaxSize to N bytes (file types only)
+//   accept=X,Y     // set Accept to X and Y (file types only)
+//   requiredif=C   // add Condition C to RequiredIf
+//   forbiddenif=C  // add Condition C to ForbiddenIf
+//
+// Conditions are given as the key of the other value, optionally followed
+// by a colon and a list of values separated by "|", and prefixed with "!"
+// to reverse them (cf. Condition.String), e.g.:
+//
+//   `vebben:"reason,string,requiredif=status:cancelled|void"`
+//
+// Because limits, names and accept lists may contain commas, any part of
+// the tag that is not a known option is appended to the preceding limit,
+// name or accept list; so this works as expected:
+//
+//   `vebben:"color,string,required,limit=red,green,blue,name=Color, main"`
+//
+// Fields of the "group" or "[]group" type get their Group specs from the
+// tags of the field's struct type, or the item type of a slice.
+//
+// Fields without a tag, or with the tag "-", are skipped, except for
+// embedded structs, whose fields are included.  Struct types may not
+// contain themselves, directly or indirectly, as groups or embedded
+// structs.
+//
+// The FormSpecs are initialized with Init, and cached per type; they must
+// not be modified.  Syntax errors, as well as the usual Init panics, result
+// in a FormTagError being returned.
+func FormSpecsFor(v interface{}) ([]*FormSpec, error) {
+
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("FormSpecsFor requires a struct, not %T", v)
+	}
+	return formSpecsForType(t, nil)
+}
+
+// formSpecsForType returns the FormSpecs for the struct type t, which is
+// within the struct types being built, if any.
+func formSpecsForType(t reflect.Type, building []reflect.Type) ([]*FormSpec,
+	error) {
+
+	if specs, ok := formTagCache.Load(t); ok {
+		return specs.([]*FormSpec), nil
+	}
+
+	specs := []*FormSpec{}
+	if err := appendTaggedFormSpecs(t, t, &specs,
+		append(building, t)); err != nil {
+		return nil, err
+	}
+	cached, _ := formTagCache.LoadOrStore(t, specs)
+	return cached.([]*FormSpec), nil
+}
+
+// isBuilding returns true if t is one of the struct types being built.
+func isBuilding(t reflect.Type, building []reflect.Type) bool {
+	for _, b := range building {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// MustFormSpecsFor is like FormSpecsFor but panics on error.  It is meant
+// for package-level variables, in the manner of regexp.MustCompile.
+func MustFormSpecsFor(v interface{}) []*FormSpec {
+	specs, err := FormSpecsFor(v)
+	if err != nil {
+		panic(err.Error())
+	}
+	return specs
+}
+
+func appendTaggedFormSpecs(top, t reflect.Type, specs *[]*FormSpec,
+	building []reflect.Type) error {
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup(FormTag)
+		if !tagged && field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if isBuilding(ft, building) {
+					return &FormTagError{top, field.Name, tag,
+						"recursive embedded struct " + ft.String()}
+				}
+				if err := appendTaggedFormSpecs(top, ft, specs,
+					append(building, ft)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !tagged || tag == "-" {
+			continue
+		}
+		spec, reason := parseFormTag(field, tag, building)
+		if reason != "" {
+			return &FormTagError{top, field.Name, tag, reason}
+		}
+		*specs = append(*specs, spec)
+	}
+	return nil
+}
+
+// parseFormTag returns the FormSpec described by tag, or the reason it
+// could not do so, within the struct types being built.
+func parseFormTag(field reflect.StructField, tag string,
+	building []reflect.Type) (spec *FormSpec, reason string) {
+
+	parts := strings.Split(tag, ",")
+	spec = &FormSpec{Key: strings.TrimSpace(parts[0])}
+	if len(parts) > 1 {
+		spec.Type = strings.TrimSpace(parts[1])
+	}
+	if spec.Key == "" {
+		spec.Key = jsonFieldName(field)
+	}
+	if spec.Type == "" {
+		spec.Type = formSpecTypeFor(field.Type)
+		if spec.Type == "" {
+			return nil, "no type given, and none derived from " +
+				field.Type.String()
+		}
+	}
+
+	if len(parts) < 2 {
+		parts = append(parts, "")
+	}
+	accept := ""
+	var cont *string // where to append unknown parts
+	for _, part := range parts[2:] {
+		opt, val, hasVal := strings.Cut(part, "=")
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "required" && !hasVal:
+			spec.Required = true
+			cont = nil
+		case opt == "sensitive" && !hasVal:
+			spec.Sensitive = true
+			cont = nil
+		case opt == "limit" && hasVal:
+			spec.Limit = val
+			cont = &spec.Limit
+		case opt == "name" && hasVal:
+			spec.Name = strings.TrimSpace(val)
+			cont = &spec.Name
+		case (opt == "min" || opt == "max") && hasVal:
+			n, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil {
+				return nil, "bad number for " + opt + ": " + val
+			}
+			if opt == "min" {
+				spec.MinItems = n
+			} else {
+				spec.MaxItems = n
+			}
+			cont = nil
+		case opt == "maxsize" && hasVal:
+			n, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+			if err != nil {
+				return nil, "bad number for " + opt + ": " + val
+			}
+			spec.MaxSize = n
+			cont = nil
+		case (opt == "requiredif" || opt == "forbiddenif") && hasVal:
+			c, ok := parseCondition(val)
+			if !ok {
+				return nil, "bad condition for " + opt + ": " + val
+			}
+			if opt == "requiredif" {
+				spec.RequiredIf = append(spec.RequiredIf, c)
+			} else {
+				spec.ForbiddenIf = append(spec.ForbiddenIf, c)
+			}
+			cont = nil
+		case opt == "accept" && hasVal:
+			accept = val
+			cont = &accept
+		case cont != nil:
+			*cont += "," + part
+		default:
+			return nil, fmt.Sprintf("unknown option %q", part)
+		}
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Key
+	}
+	for _, a := range strings.Split(accept, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			spec.Accept = append(spec.Accept, a)
+		}
+	}
+	if spec.Type == "group" || spec.Type == "[]group" {
+		gt := field.Type
+		for gt.Kind() == reflect.Ptr || gt.Kind() == reflect.Slice {
+			gt = gt.Elem()
+		}
+		if gt.Kind() != reflect.Struct {
+			return nil, "group requires a struct, not " + field.Type.String()
+		}
+		if isBuilding(gt, building) {
+			return nil, "recursive group type " + gt.String()
+		}
+		group, err := formSpecsForType(gt, building)
+		if err != nil {
+			return nil, "in group: " + err.Error()
+		}
+		spec.Group = group
+	}
+
+	// Init panics on bad specs, but here we want an error.
+	defer func() {
+		if r := recover(); r != nil {
+			spec = nil
+			reason = fmt.Sprint(r)
+		}
+	}()
+	spec.Init()
+
+	return spec, ""
+}
+
+// jsonFieldName returns the name encoding/json would use for field.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// formSpecTypeFor returns the standard FormSpec type for t, or an empty
+// string if there is none.
+func formSpecTypeFor(t reflect.Type) string {
+	switch t {
+	case fileHeaderType:
+		return "file"
+	case mailAddressType:
+		return "email"
+	case urlType:
+		return "url"
+	case netipAddrType:
+		return "ip"
+	case netipPrefixType:
+		return "cidr"
+	case decimalType:
+		return "decimal"
+	case moneyType:
+		return "money"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int:
+		return "int"
+	case reflect.Int64:
+		return "int64"
+	case reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice:
+		if item := formSpecTypeFor(t.Elem()); item != "" {
+			return "[]" + item
+		}
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+// formtags_test.go
+// ----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"testing"
+
+	// Helpers:
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+type TaggedBase struct {
+	ID int64 `json:"id" vebben:",,required"`
+}
+
+type TaggedType struct {
+	TaggedBase
+	Variant  string   `json:"variant" vebben:",,required,limit=4,name=The variant"`
+	Size     int      `vebben:"size,int,required,limit=1-4,name=The size (1-4)"`
+	Color    string   `json:"color" vebben:"color,string,limit=red,green,blue"`
+	Tags     []string `json:"tags" vebben:",,min=1,max=3,name=Tags, if any"`
+	Strength float64  `json:"strength" vebben:""`
+	Ignored  string   `json:"ignored"`
+	Skipped  string   `json:"skipped" vebben:"-"`
+}
+
+func Test_FormSpecsFor(t *testing.T) {
+
+	assert := assert.New(t)
+
+	specs, err := vebben.FormSpecsFor(&TaggedType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(6, len(specs), "six specs") {
+		return
+	}
+
+	exp := []struct {
+		Key      string
+		Type     string
+		Required bool
+		Limit    string
+		Name     string
+		MinItems int
+		MaxItems int
+	}{
+		{"id", "int64", true, "", "id", 0, 0},
+		{"variant", "string", true, "4", "The variant", 0, 0},
+		{"size", "int", true, "1-4", "The size (1-4)", 0, 0},
+		{"color", "string", false, "red,green,blue", "color", 0, 0},
+		{"tags", "[]string", false, "", "Tags, if any", 1, 3},
+		{"strength", "float", false, "", "strength", 0, 0},
+	}
+	for idx, e := range exp {
+		s := specs[idx]
+		assert.Equal(e.Key, s.Key, "Key for %d", idx)
+		assert.Equal(e.Type, s.Type, "Type for %d", idx)
+		assert.Equal(e.Required, s.Required, "Required for %d", idx)
+		assert.Equal(e.Limit, s.Limit, "Limit for %d", idx)
+		assert.Equal(e.Name, s.Name, "Name for %d", idx)
+		assert.Equal(e.MinItems, s.MinItems, "MinItems for %d", idx)
+		assert.Equal(e.MaxItems, s.MaxItems, "MaxItems for %d", idx)
+		assert.NotNil(s.Validator, "Validator set for %d", idx)
+	}
+
+	again, err := vebben.FormSpecsFor(TaggedType{})
+	assert.Nil(err, "no error second time")
+	assert.True(&specs[0] == &again[0], "specs are cached")
+
+}
+
+func Test_FormSpecsFor_Decode(t *testing.T) {
+
+	assert := assert.New(t)
+
+	f := vebben.URLValues{
+		"id":      []string{"12"},
+		"variant": []string{"FLUB"},
+		"size":    []string{"3"},
+		"color":   []string{"red"},
+		"tags":    []string{"x", "y"},
+	}
+	target := &TaggedType{}
+	err := vebben.DecodeForm(f, vebben.MustFormSpecsFor(target), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(int64(12), target.ID)
+	assert.Equal("FLUB", target.Variant)
+	assert.Equal(3, target.Size)
+	assert.Equal([]string{"x", "y"}, target.Tags)
+
+}
+
+func Test_FormSpecsFor_NotStruct(t *testing.T) {
+
+	assert := assert.New(t)
+
+	_, err := vebben.FormSpecsFor(8)
+	if assert.Error(err) {
+		assert.Equal("FormSpecsFor requires a struct, not int", err.Error())
+	}
+}
+
+func Test_FormSpecsFor_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	type BadOption struct {
+		Foo string `vebben:"foo,string,requird"`
+	}
+	type BadCount struct {
+		Foo []string `vebben:"foo,,max=x"`
+	}
+	type NoType struct {
+		Foo struct{} `vebben:"foo"`
+	}
+	type BadLimit struct {
+		Foo float64 `vebben:"foo,,limit=1"`
+	}
+	type BadType struct {
+		Foo string `vebben:"foo,nope"`
+	}
+
+	tests := []struct {
+		v   interface{}
+		exp string
+	}{
+		{BadOption{},
+			`bad vebben tag on BadOption.Foo: unknown option "requird" ` +
+				`(tag: "foo,string,requird")`},
+		{BadCount{},
+			`bad vebben tag on BadCount.Foo: bad number for max: x ` +
+				`(tag: "foo,,max=x")`},
+		{NoType{},
+			`bad vebben tag on NoType.Foo: no type given, and none ` +
+				`derived from struct {} (tag: "foo")`},
+		{BadLimit{},
+			`bad vebben tag on BadLimit.Foo: Length limit does not apply ` +
+				`to float (tag: "foo,,limit=1")`},
+		{BadType{},
+			`bad vebben tag on BadType.Foo: Unsupported FormSpec type: ` +
+				`nope (tag: "foo,nope")`},
+	}
+	for _, test := range tests {
+		_, err := vebben.FormSpecsFor(test.v)
+		if assert.Error(err) {
+			assert.IsType(&vebben.FormTagError{}, err)
+			assert.Equal(test.exp, err.Error())
+		}
+		assert.Panics(func() { vebben.MustFormSpecsFor(test.v) })
+	}
+}
+
+type TaggedNode struct {
+	Name string       `vebben:"name,string"`
+	Kids []TaggedNode `vebben:"kids,[]group"`
+}
+
+type TaggedOuter struct {
+	Inner *TaggedInner `vebben:"inner,group"`
+}
+
+type TaggedInner struct {
+	Outers []TaggedOuter `vebben:"outers,[]group"`
+}
+
+type TaggedEmbedder struct {
+	*TaggedEmbedder
+	Name string `vebben:"name,string"`
+}
+
+func Test_FormSpecsFor_Recursive(t *testing.T) {
+
+	assert := assert.New(t)
+
+	tests := []struct {
+		v   interface{}
+		exp string
+	}{
+		{&TaggedNode{},
+			`bad vebben tag on TaggedNode.Kids: recursive group type ` +
+				`vebben_test.TaggedNode (tag: "kids,[]group")`},
+		{&TaggedOuter{},
+			`bad vebben tag on TaggedOuter.Inner: in group: bad vebben tag ` +
+				`on TaggedInner.Outers: recursive group type ` +
+				`vebben_test.TaggedOuter (tag: "outers,[]group") ` +
+				`(tag: "inner,group")`},
+		{&TaggedEmbedder{},
+			`bad vebben tag on TaggedEmbedder.TaggedEmbedder: recursive ` +
+				`embedded struct vebben_test.TaggedEmbedder (tag: "")`},
+	}
+	for _, test := range tests {
+		_, err := vebben.FormSpecsFor(test.v)
+		if assert.Error(err) {
+			assert.IsType(&vebben.FormTagError{}, err)
+			assert.Equal(test.exp, err.Error())
+		}
+	}
+}
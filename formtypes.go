@@ -0,0 +1,181 @@
+// formtypes.go -- registries of FormSpec types.
+// ------------
+
+package vebben
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TypeRegistry is a set of named FormSpec types, which FormSpecs look up by
+// their Type in the registry set as their Types, or in DefaultTypes.  A new
+// registry has all the standard types (see FormSpec), to which a library
+// may add its own without affecting those of any other registry, e.g.:
+//
+//   var Types = vebben.NewTypeRegistry()
+//
+//   func init() {
+//       Types.Add("sku", skuConverter, nil)
+//       Types.Freeze()
+//   }
+//
+//   spec := Types.RequiredFormSpec("sku", "sku")
+//
+// Types may not be added to a frozen registry, but it may be cloned to
+// make an unfrozen one.  A TypeRegistry is safe for concurrent use by
+// multiple goroutines.
+type TypeRegistry struct {
+	mu     sync.RWMutex
+	types  map[string]*formSpecType
+	frozen bool
+
+	// specs are the FormSpecs without Types decoded by Decoders with the
+	// registry, and their copies initialized with it.
+	specs sync.Map // *FormSpec -> *FormSpec
+}
+
+// DefaultTypes is the TypeRegistry of FormSpecs whose Types is nil.
+// AddFormSpecType and SetFormSpecTypeSchema change its types.
+var DefaultTypes = NewTypeRegistry()
+
+// NewTypeRegistry returns a new TypeRegistry with the standard types.
+func NewTypeRegistry() *TypeRegistry {
+	r := &TypeRegistry{types: map[string]*formSpecType{}}
+	for name, t := range standardFormSpecTypes {
+		r.types[name] = t
+	}
+	return r
+}
+
+// Add adds or replaces type t with converter function cf and optional
+// default validator vf, as AddFormSpecType does for DefaultTypes.  It
+// panics if the registry is frozen or t is not a valid type name.
+func (r *TypeRegistry) Add(t string, cf func(string) (interface{}, bool),
+	vf func(*FormSpec, interface{}) error) {
+
+	if t == "" || strings.HasPrefix(t, "[]") {
+		panic("Bad FormSpec type name: " + t)
+	}
+	if cf == nil {
+		panic("FormSpec type requires a converter: " + t)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		panic("TypeRegistry is frozen")
+	}
+	r.types[t] = &formSpecType{
+		converter: cf,
+		validator: vf,
+		custom:    true,
+	}
+}
+
+// SetSchema sets the function returning the JSON Schema for a single value
+// of type t, which must exist, as SetFormSpecTypeSchema does for
+// DefaultTypes.  It panics if the registry is frozen.
+func (r *TypeRegistry) SetSchema(t string,
+	sf func(*FormSpec) map[string]interface{}) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		panic("TypeRegistry is frozen")
+	}
+	ft := r.types[t]
+	if ft == nil {
+		panic("Unsupported FormSpec type: " + t)
+	}
+	// Types may be shared with other registries, so they are replaced.
+	copied := *ft
+	copied.schema = sf
+	r.types[t] = &copied
+}
+
+// Freeze prevents any further changes to the registry.
+func (r *TypeRegistry) Freeze() {
+	r.mu.Lock()
+	r.frozen = true
+	r.mu.Unlock()
+}
+
+// Frozen returns true if the registry is frozen.
+func (r *TypeRegistry) Frozen() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.frozen
+}
+
+// Clone returns a new, unfrozen TypeRegistry with the types of the
+// registry.
+func (r *TypeRegistry) Clone() *TypeRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &TypeRegistry{types: make(map[string]*formSpecType, len(r.types))}
+	for name, t := range r.types {
+		c.types[name] = t
+	}
+	return c
+}
+
+// Has returns true if the registry has type t, or for a slice type such as
+// "[]date", its item type.
+func (r *TypeRegistry) Has(t string) bool {
+	return r.lookup(strings.TrimPrefix(t, "[]")) != nil
+}
+
+// Names returns the sorted names of the types in the registry.
+func (r *TypeRegistry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// specFor returns the copy of fs, which has no Types, initialized with the
+// registry, as decoded by Decoders whose Types it is.  It panics as Init
+// does if fs does not suit the types of the registry.
+func (r *TypeRegistry) specFor(fs *FormSpec) *FormSpec {
+	if c, ok := r.specs.Load(fs); ok {
+		return c.(*FormSpec)
+	}
+	c, _ := r.specs.LoadOrStore(fs, fs.withTypes(r))
+	return c.(*FormSpec)
+}
+
+// lookup returns type t, or nil if there is no such type.
+func (r *TypeRegistry) lookup(t string) *formSpecType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.types[t]
+}
+
+// NewFormSpec returns an initialized FormSpec with the registry as its
+// Types; see the package-level NewFormSpec.
+func (r *TypeRegistry) NewFormSpec(req bool, k, t string,
+	limitAndName ...string) *FormSpec {
+
+	return newFormSpec(r, req, k, t, limitAndName)
+}
+
+// OptionalFormSpec returns an initialized, optional FormSpec with the
+// registry as its Types; see the package-level OptionalFormSpec.
+func (r *TypeRegistry) OptionalFormSpec(k, t string,
+	limitAndName ...string) *FormSpec {
+
+	return newFormSpec(r, false, k, t, limitAndName)
+}
+
+// RequiredFormSpec returns an initialized, required FormSpec with the
+// registry as its Types; see the package-level RequiredFormSpec.
+func (r *TypeRegistry) RequiredFormSpec(k, t string,
+	limitAndName ...string) *FormSpec {
+
+	return newFormSpec(r, true, k, t, limitAndName)
+}
@@ -0,0 +1,150 @@
+// formtypes_test.go
+// -----------------
+
+package vebben_test
+
+import (
+	// Standard:
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	// Helpers:
+	"github.com/biztos/testig"
+	"github.com/stretchr/testify/assert"
+
+	// Under test:
+	"github.com/biztos/vebben"
+)
+
+func upperConverter(s string) (interface{}, bool) {
+	return strings.ToUpper(s), true
+}
+
+func Test_TypeRegistry_Scoped(t *testing.T) {
+
+	assert := assert.New(t)
+
+	reg := vebben.NewTypeRegistry()
+	assert.True(reg.Has("date"))
+	assert.True(reg.Has("[]email"))
+	assert.False(reg.Has("typesupper"))
+	assert.Contains(reg.Names(), "string")
+
+	reg.Add("typesupper", upperConverter, nil)
+	assert.True(reg.Has("typesupper"))
+	assert.True(reg.Has("[]typesupper"))
+	assert.False(vebben.DefaultTypes.Has("typesupper"))
+	testig.AssertPanicsWith(t, func() {
+		vebben.OptionalFormSpec("sku", "typesupper")
+	}, "Unsupported FormSpec type: typesupper", "not in DefaultTypes")
+
+	spec := reg.RequiredFormSpec("sku", "typesupper", "", "SKU")
+	assert.Equal(reg, spec.Types)
+	assert.True(spec.Required)
+	assert.Equal("SKU", spec.Name)
+	assert.Equal(reg, spec.Copy("other", "").Types)
+
+	target := map[string]interface{}{}
+	err := vebben.DecodeForm(vebben.URLValues{"sku": {"abc-1"}},
+		[]*vebben.FormSpec{spec}, &target)
+	if assert.Nil(err) {
+		assert.Equal("ABC-1", target["sku"])
+	}
+
+	// The same name in another registry is another type.
+	other := vebben.NewTypeRegistry()
+	other.Add("typesupper", func(s string) (interface{}, bool) {
+		return s + "!", true
+	}, nil)
+	v, err := other.OptionalFormSpec("x", "typesupper").Convert("abc")
+	if assert.Nil(err) {
+		assert.Equal("abc!", v)
+	}
+
+	// Standard types may be replaced too, as custom types.
+	other.Add("int", upperConverter, nil)
+	v, err = other.NewFormSpec(false, "x", "int", "1-2").Convert("abc")
+	if assert.Nil(err) {
+		assert.Equal("ABC", v)
+	}
+	v, err = vebben.OptionalFormSpec("x", "int").Convert("12")
+	if assert.Nil(err) {
+		assert.Equal(12, v)
+	}
+}
+
+func Test_TypeRegistry_FreezeAndClone(t *testing.T) {
+
+	assert := assert.New(t)
+
+	reg := vebben.NewTypeRegistry()
+	reg.Add("typesfrozen", upperConverter, nil)
+	assert.False(reg.Frozen())
+	reg.Freeze()
+	assert.True(reg.Frozen())
+	testig.AssertPanicsWith(t, func() {
+		reg.Add("typesmore", upperConverter, nil)
+	}, "TypeRegistry is frozen", "add")
+	testig.AssertPanicsWith(t, func() {
+		reg.SetSchema("typesfrozen", nil)
+	}, "TypeRegistry is frozen", "schema")
+
+	clone := reg.Clone()
+	assert.False(clone.Frozen())
+	assert.True(clone.Has("typesfrozen"))
+	clone.Add("typesmore", upperConverter, nil)
+	assert.True(clone.Has("typesmore"))
+	assert.False(reg.Has("typesmore"))
+
+	// Schemas set on a clone do not change the original.
+	clone.SetSchema("string", func(*vebben.FormSpec) map[string]interface{} {
+		return map[string]interface{}{"format": "sku"}
+	})
+	doc := vebben.JSONSchema([]*vebben.FormSpec{
+		reg.OptionalFormSpec("a", "string"),
+		clone.OptionalFormSpec("b", "string"),
+	})
+	props := doc["properties"].(map[string]interface{})
+	assert.NotContains(props["a"], "format")
+	assert.Contains(props["b"], "format")
+}
+
+func Test_TypeRegistry_BadAdd(t *testing.T) {
+
+	reg := vebben.NewTypeRegistry()
+	testig.AssertPanicsWith(t, func() {
+		reg.Add("", upperConverter, nil)
+	}, "Bad FormSpec type name: ", "empty")
+	testig.AssertPanicsWith(t, func() {
+		reg.Add("[]foo", upperConverter, nil)
+	}, "Bad FormSpec type name: []foo", "slice")
+	testig.AssertPanicsWith(t, func() {
+		reg.Add("foo", nil, nil)
+	}, "FormSpec type requires a converter: foo", "no converter")
+}
+
+func Test_TypeRegistry_Concurrent(t *testing.T) {
+
+	assert := assert.New(t)
+
+	reg := vebben.NewTypeRegistry()
+	spec := reg.OptionalFormSpec("x", "string")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			reg.Add(fmt.Sprintf("typeslazy%d", i), upperConverter, nil)
+		}(i)
+		go func() {
+			defer wg.Done()
+			v, err := spec.Convert("abc")
+			assert.Nil(err)
+			assert.Equal("abc", v)
+		}()
+	}
+	wg.Wait()
+	assert.True(reg.Has("typeslazy19"))
+}
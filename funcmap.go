@@ -33,6 +33,13 @@ import (
 //     intrange
 //     pathdepth
 //     indent
+//     forminput
+//     csrffield
+//     value          // FormState methods
+//     haserror
+//     errorfor
+//     checked
+//     selected
 //
 // Functions From Kyoung-chan Lee's Gtf
 //
@@ -79,6 +86,13 @@ func NewFuncMap() template.FuncMap {
 		"intrange":     IntRange,
 		"pathdepth":    PathDepth,
 		"indent":       Indent,
+		"forminput":    FormInput,
+		"csrffield":    CSRFField,
+		"value":        (*FormState).Value,
+		"haserror":     (*FormState).HasError,
+		"errorfor":     (*FormState).ErrorFor,
+		"checked":      (*FormState).Checked,
+		"selected":     (*FormState).Selected,
 
 		// Golang Standard Functions:
 		// TODO (maybe)
@@ -31,6 +31,13 @@ func Test_New(t *testing.T) {
 		"intrange",
 		"pathdepth",
 		"indent",
+		"forminput",
+		"csrffield",
+		"value",
+		"haserror",
+		"errorfor",
+		"checked",
+		"selected",
 
 		// gtf freebies:
 		"replace",
@@ -6,11 +6,12 @@ require (
 	github.com/biztos/testig v0.0.0-20161224131015-f88fca94940a
 	github.com/leekchan/gtf v0.0.0-20190214083521-5fba33c5b00b
 	github.com/stretchr/testify v1.8.4
+	golang.org/x/net v0.11.0
 	golang.org/x/text v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )